@@ -5,6 +5,7 @@ package binvox
 import (
 	"fmt"
 	"log"
+	"math"
 
 	gl "github.com/fogleman/fauxgl"
 )
@@ -27,6 +28,14 @@ type BinVOX struct {
 	TX, TY, TZ float64 // translation (location of origin in world space)
 	Scale      float64 // uniform scale in millimeters
 
+	// Transform, if non-nil, is the matrix that was applied to the
+	// original model before it was voxelized (see VoxelizeTransformed).
+	// Meshes generated from the voxels have its inverse applied so
+	// that they are restored to their original placement. Operations
+	// on the voxel grid itself (such as Rotate90) do not change it.
+	// It is stored beside the binvox file (see TransformFilename).
+	Transform *gl.Matrix
+
 	// Use either WhiteVoxels or ColorVoxels, but not both.
 
 	// WhiteVoxels represents a map of (white) voxels.
@@ -147,3 +156,20 @@ func (b *BinVOX) MBB() *gl.Box {
 	max := gl.V(b.TX+s*float64(b.NX), b.TY+s*float64(b.NY), b.TZ+s*float64(b.NZ))
 	return &gl.Box{Min: min, Max: max}
 }
+
+// VoxelCenter returns the center of the voxel at k in world space (in millimeters).
+func (b *BinVOX) VoxelCenter(k Key) gl.Vector {
+	s := 1.0 / b.VoxelsPerMM()
+	return gl.V(b.TX+s*(float64(k.X)+0.5), b.TY+s*(float64(k.Y)+0.5), b.TZ+s*(float64(k.Z)+0.5))
+}
+
+// VoxelAt returns the key of the voxel containing the world space point p (in millimeters).
+// Note that the returned key may lie outside of the subregion.
+func (b *BinVOX) VoxelAt(p gl.Vector) Key {
+	vpmm := b.VoxelsPerMM()
+	return Key{
+		X: int(math.Floor(vpmm * (p.X - b.TX))),
+		Y: int(math.Floor(vpmm * (p.Y - b.TY))),
+		Z: int(math.Floor(vpmm * (p.Z - b.TZ))),
+	}
+}
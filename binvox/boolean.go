@@ -0,0 +1,52 @@
+package binvox
+
+import (
+	"log"
+)
+
+// Union adds all the voxels of o to b (as WhiteVoxels).
+// Both models must share the same voxel grid. The voxels of o are
+// shifted into the grid of b, but b is not resized, so voxels of o
+// may lie beyond the bounds of b.
+func (b *BinVOX) Union(o *BinVOX) error {
+	dx, dy, dz, err := GridOffset(b, o)
+	if err != nil {
+		return err
+	}
+	log.Printf("Adding %v voxels shifted by [%v,%v,%v]", len(o.WhiteVoxels)+len(o.ColorVoxels), dx, dy, dz)
+	if b.WhiteVoxels == nil {
+		b.WhiteVoxels = WhiteVoxelMap{}
+	}
+	keyFunc := func(k Key) {
+		b.WhiteVoxels[Key{X: k.X + dx, Y: k.Y + dy, Z: k.Z + dz}] = struct{}{}
+	}
+	for k := range o.WhiteVoxels {
+		keyFunc(k)
+	}
+	for k := range o.ColorVoxels {
+		keyFunc(k)
+	}
+	return nil
+}
+
+// Difference removes all the voxels of o from b.
+// Both models must share the same voxel grid.
+func (b *BinVOX) Difference(o *BinVOX) error {
+	dx, dy, dz, err := GridOffset(b, o)
+	if err != nil {
+		return err
+	}
+	log.Printf("Removing %v voxels shifted by [%v,%v,%v]", len(o.WhiteVoxels)+len(o.ColorVoxels), dx, dy, dz)
+	keyFunc := func(k Key) {
+		n := Key{X: k.X + dx, Y: k.Y + dy, Z: k.Z + dz}
+		delete(b.WhiteVoxels, n)
+		delete(b.ColorVoxels, n)
+	}
+	for k := range o.WhiteVoxels {
+		keyFunc(k)
+	}
+	for k := range o.ColorVoxels {
+		keyFunc(k)
+	}
+	return nil
+}
@@ -0,0 +1,435 @@
+package binvox
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// Cavity represents a fully enclosed void within a model that would
+// trap uncured resin or unsintered powder during printing.
+type Cavity struct {
+	// Voxels holds the empty voxels of the cavity within each region,
+	// indexed the same as the regions passed to FindCavities.
+	Voxels [][]Key
+
+	// Gaps holds the boxes (in millimeters) of the parts of the cavity
+	// that lie in the gaps between the regions. No region holds their
+	// voxels, so they are not filled by FillCavities.
+	Gaps []gl.Box
+
+	NumVoxels int       // total number of voxels in the cavity (including Gaps)
+	Volume    float64   // volume of the cavity in cubic millimeters
+	MBB       gl.Box    // minimum bounding box of the cavity in millimeters
+	Lowest    gl.Vector // center of the lowest voxel (in Z, then Y, then X)
+}
+
+// Cavities returns the enclosed cavities of the model, ordered by location.
+// All space beyond the bounds of the model is considered to be outside.
+func (b *BinVOX) Cavities() ([]*Cavity, error) {
+	return FindCavities([]*BinVOX{b})
+}
+
+// FindCavities returns the enclosed cavities of a model that has been
+// diced into the given subregions (such as those written by stldice),
+// ordered by location. All regions must share the same voxel grid.
+//
+// Empty space is flood filled across the faces between neighboring
+// regions, so a cavity may span several regions. Only space beyond the
+// bounding box of all the regions is considered to be outside of the
+// model. Gaps within the bounding box that are not covered by any
+// region (such as entirely empty regions, which are never written by
+// stldice) are treated as empty space that may be part of a cavity.
+func FindCavities(regions []*BinVOX) ([]*Cavity, error) {
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("no regions")
+	}
+	offsets := make([]Key, len(regions))
+	for i, r := range regions {
+		dx, dy, dz, err := GridOffset(regions[0], r)
+		if err != nil {
+			return nil, fmt.Errorf("region #%v: %v", i, err)
+		}
+		offsets[i] = Key{X: dx, Y: dy, Z: dz}
+	}
+	log.Printf("Finding cavities within %v regions...", len(regions))
+
+	// Label the 6-connected empty components within each region.
+	// Solid voxels are labeled -1.
+	labels := make([][]int32, len(regions))
+	var parent []int
+	for i, r := range regions {
+		labels[i] = r.labelEmpty(int32(len(parent)))
+		n := 0
+		for _, l := range labels[i] {
+			if int(l)+1 > n {
+				n = int(l) + 1
+			}
+		}
+		for id := len(parent); id < n; id++ {
+			parent = append(parent, id)
+		}
+	}
+	var find func(id int) int
+	find = func(id int) int {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+
+	// regionAt returns the region containing the global key g, or -1.
+	regionAt := func(g Key) (int, Key) {
+		for j, r := range regions {
+			k := Key{X: g.X - offsets[j].X, Y: g.Y - offsets[j].Y, Z: g.Z - offsets[j].Z}
+			if k.X >= 0 && k.Y >= 0 && k.Z >= 0 && k.X < r.NX && k.Y < r.NY && k.Z < r.NZ {
+				return j, k
+			}
+		}
+		return -1, Key{}
+	}
+
+	// The planes of the region faces cut the bounding box of all the
+	// regions into cells that each lie either within a single region or
+	// within a gap between the regions. Each gap cell is entirely empty,
+	// so it is given a label of its own.
+	var cuts [3][]int
+	for i, r := range regions {
+		o := [3]int{offsets[i].X, offsets[i].Y, offsets[i].Z}
+		n := [3]int{r.NX, r.NY, r.NZ}
+		for axis := range cuts {
+			cuts[axis] = append(cuts[axis], o[axis], o[axis]+n[axis])
+		}
+	}
+	for axis := range cuts {
+		sort.Ints(cuts[axis])
+		c := cuts[axis][:1]
+		for _, v := range cuts[axis][1:] {
+			if v != c[len(c)-1] {
+				c = append(c, v)
+			}
+		}
+		cuts[axis] = c
+	}
+	nc := [3]int{len(cuts[0]) - 1, len(cuts[1]) - 1, len(cuts[2]) - 1}
+	cellIndex := func(c Key) int { return c.X + nc[0]*(c.Y+nc[1]*c.Z) }
+	cellBox := func(c Key) (lo, hi Key) {
+		lo = Key{X: cuts[0][c.X], Y: cuts[1][c.Y], Z: cuts[2][c.Z]}
+		hi = Key{X: cuts[0][c.X+1], Y: cuts[1][c.Y+1], Z: cuts[2][c.Z+1]}
+		return lo, hi
+	}
+	// cellAt returns the cell containing the global key g, or false if g
+	// lies beyond the bounding box.
+	cellAt := func(g Key) (Key, bool) {
+		var c [3]int
+		for axis, v := range [3]int{g.X, g.Y, g.Z} {
+			if v < cuts[axis][0] || v >= cuts[axis][nc[axis]] {
+				return Key{}, false
+			}
+			c[axis] = sort.SearchInts(cuts[axis], v+1) - 1
+		}
+		return Key{X: c[0], Y: c[1], Z: c[2]}, true
+	}
+	gapLabels := make([]int, nc[0]*nc[1]*nc[2])
+	var gaps []Key
+	for z := 0; z < nc[2]; z++ {
+		for y := 0; y < nc[1]; y++ {
+			for x := 0; x < nc[0]; x++ {
+				c := Key{X: x, Y: y, Z: z}
+				gapLabels[cellIndex(c)] = -1
+				lo, _ := cellBox(c)
+				if j, _ := regionAt(lo); j >= 0 {
+					continue
+				}
+				gapLabels[cellIndex(c)] = len(parent)
+				parent = append(parent, len(parent))
+				gaps = append(gaps, c)
+			}
+		}
+	}
+
+	// Join the components across region and gap faces and find those
+	// that reach the outside of the bounding box.
+	open := make([]bool, len(parent))
+	join := func(l int, g Key) {
+		c, ok := cellAt(g)
+		if !ok {
+			open[l] = true
+			return
+		}
+		if gap := gapLabels[cellIndex(c)]; gap >= 0 {
+			parent[find(l)] = find(gap)
+			return
+		}
+		j, nk := regionAt(g)
+		if nl := labels[j][regions[j].index(nk)]; nl >= 0 {
+			parent[find(l)] = find(int(nl))
+		}
+	}
+	for i, r := range regions {
+		r.forEachFaceVoxel(func(k, d Key) {
+			if l := labels[i][r.index(k)]; l >= 0 {
+				join(int(l), Key{X: k.X + d.X + offsets[i].X, Y: k.Y + d.Y + offsets[i].Y, Z: k.Z + d.Z + offsets[i].Z})
+			}
+		})
+	}
+	for _, c := range gaps {
+		l := gapLabels[cellIndex(c)]
+		lo, hi := cellBox(c)
+		for _, d := range neighbors6 {
+			// Any voxel just beyond the face of the cell in direction d
+			// tells which cell lies beyond it.
+			g := Key{X: lo.X + d.X, Y: lo.Y + d.Y, Z: lo.Z + d.Z}
+			if d.X > 0 {
+				g.X = hi.X
+			}
+			if d.Y > 0 {
+				g.Y = hi.Y
+			}
+			if d.Z > 0 {
+				g.Z = hi.Z
+			}
+			if n, ok := cellAt(g); ok && gapLabels[cellIndex(n)] < 0 {
+				continue // joined from the faces of the neighboring region
+			}
+			join(l, g)
+		}
+	}
+	for id, o := range open {
+		if o {
+			open[find(id)] = true
+		}
+	}
+
+	// Gather the voxels of each cavity.
+	s := 1.0 / regions[0].VoxelsPerMM()
+	toVector := func(g Key) gl.Vector {
+		return gl.V(regions[0].TX+s*float64(g.X), regions[0].TY+s*float64(g.Y), regions[0].TZ+s*float64(g.Z))
+	}
+	cavities := map[int]*Cavity{}
+	lowestKeys := map[int]Key{}
+	// add adds the voxels from lo to hi (exclusive) to the cavity of root.
+	add := func(root int, lo, hi Key) *Cavity {
+		box := gl.Box{Min: toVector(lo), Max: toVector(hi)}
+		c, ok := cavities[root]
+		if !ok {
+			c = &Cavity{Voxels: make([][]Key, len(regions)), MBB: box}
+			cavities[root] = c
+			lowestKeys[root] = lo
+		}
+		c.NumVoxels += (hi.X - lo.X) * (hi.Y - lo.Y) * (hi.Z - lo.Z)
+		c.MBB = c.MBB.Extend(box)
+		if lessKey(lo, lowestKeys[root]) {
+			lowestKeys[root] = lo
+		}
+		return c
+	}
+	for i, r := range regions {
+		for idx, l := range labels[i] {
+			if l < 0 {
+				continue
+			}
+			root := find(int(l))
+			if open[root] {
+				continue
+			}
+			k := Key{X: idx % r.NX, Y: (idx / r.NX) % r.NY, Z: idx / (r.NX * r.NY)}
+			g := Key{X: k.X + offsets[i].X, Y: k.Y + offsets[i].Y, Z: k.Z + offsets[i].Z}
+			c := add(root, g, Key{X: g.X + 1, Y: g.Y + 1, Z: g.Z + 1})
+			c.Voxels[i] = append(c.Voxels[i], k)
+		}
+	}
+	for _, cell := range gaps {
+		root := find(gapLabels[cellIndex(cell)])
+		if open[root] {
+			continue
+		}
+		lo, hi := cellBox(cell)
+		c := add(root, lo, hi)
+		c.Gaps = append(c.Gaps, gl.Box{Min: toVector(lo), Max: toVector(hi)})
+	}
+
+	result := make([]*Cavity, 0, len(cavities))
+	for root, c := range cavities {
+		c.Volume = float64(c.NumVoxels) * s * s * s
+		c.Lowest = regions[0].VoxelCenter(lowestKeys[root])
+		result = append(result, c)
+	}
+	sort.Slice(result, func(a, b int) bool {
+		pa, pb := result[a].Lowest, result[b].Lowest
+		if pa.Z != pb.Z {
+			return pa.Z < pb.Z
+		}
+		if pa.Y != pb.Y {
+			return pa.Y < pb.Y
+		}
+		return pa.X < pb.X
+	})
+	log.Printf("Found %v cavities.", len(result))
+	return result, nil
+}
+
+// FillCavities fills the given cavities (found by FindCavities) with
+// solid voxels, adding them to the corresponding regions.
+func FillCavities(regions []*BinVOX, cavities []*Cavity) {
+	for _, c := range cavities {
+		for i, keys := range c.Voxels {
+			r := regions[i]
+			for _, k := range keys {
+				if r.WhiteVoxels == nil && r.ColorVoxels != nil {
+					r.ColorVoxels[k] = White
+					continue
+				}
+				if r.WhiteVoxels == nil {
+					r.WhiteVoxels = WhiteVoxelMap{}
+				}
+				r.WhiteVoxels[k] = struct{}{}
+			}
+		}
+	}
+}
+
+// DrainCavities punches a drain hole of the given diameter (in millimeters)
+// from the lowest point of each of the given cavities (found by
+// FindCavities) down (in -Z) through the wall of the model, removing
+// voxels from the corresponding regions.
+func DrainCavities(regions []*BinVOX, cavities []*Cavity, diameter float64) error {
+	if diameter <= 0 {
+		return fmt.Errorf("drain diameter must be positive")
+	}
+	offsets := make([]Key, len(regions))
+	minZ := math.MaxInt32
+	for i, r := range regions {
+		dx, dy, dz, err := GridOffset(regions[0], r)
+		if err != nil {
+			return fmt.Errorf("region #%v: %v", i, err)
+		}
+		offsets[i] = Key{X: dx, Y: dy, Z: dz}
+		if dz < minZ {
+			minZ = dz
+		}
+	}
+
+	// remove removes the voxel at global key g, reporting whether it was solid.
+	remove := func(g Key) bool {
+		var solid bool
+		for i, r := range regions {
+			k := Key{X: g.X - offsets[i].X, Y: g.Y - offsets[i].Y, Z: g.Z - offsets[i].Z}
+			if _, ok := r.WhiteVoxels[k]; ok {
+				delete(r.WhiteVoxels, k)
+				solid = true
+			}
+			if _, ok := r.ColorVoxels[k]; ok {
+				delete(r.ColorVoxels, k)
+				solid = true
+			}
+		}
+		return solid
+	}
+
+	rad := 0.5 * diameter * regions[0].VoxelsPerMM()
+	ri := int(math.Ceil(rad))
+	for _, c := range cavities {
+		k := regions[0].VoxelAt(c.Lowest)
+		log.Printf("Punching %v mm drain hole at %v", diameter, c.Lowest)
+		for dy := -ri; dy <= ri; dy++ {
+			for dx := -ri; dx <= ri; dx++ {
+				if (dx != 0 || dy != 0) && float64(dx*dx+dy*dy) > rad*rad {
+					continue
+				}
+				var seenWall bool
+				for z := k.Z - 1; z >= minZ; z-- {
+					isWall := remove(Key{X: k.X + dx, Y: k.Y + dy, Z: z})
+					if seenWall && !isWall {
+						break // pierced the wall
+					}
+					if isWall {
+						seenWall = true
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// index returns the index of the in-bounds key k within a dense array
+// (X running fastest, then Y, then Z).
+func (b *BinVOX) index(k Key) int {
+	return k.X + b.NX*(k.Y+b.NY*k.Z)
+}
+
+// labelEmpty labels the 6-connected components of empty voxels within
+// the bounds of b, starting with label first. Solid voxels are labeled -1.
+func (b *BinVOX) labelEmpty(first int32) []int32 {
+	const unset = math.MaxInt32
+	labels := make([]int32, b.NX*b.NY*b.NZ)
+	for i := range labels {
+		labels[i] = unset
+	}
+	inBounds := func(k Key) bool {
+		return k.X >= 0 && k.Y >= 0 && k.Z >= 0 && k.X < b.NX && k.Y < b.NY && k.Z < b.NZ
+	}
+	for k := range b.WhiteVoxels {
+		if inBounds(k) {
+			labels[b.index(k)] = -1
+		}
+	}
+	for k := range b.ColorVoxels {
+		if inBounds(k) {
+			labels[b.index(k)] = -1
+		}
+	}
+
+	next := first
+	var queue []Key
+	for i, l := range labels {
+		if l != unset {
+			continue
+		}
+		labels[i] = next
+		queue = append(queue[:0], Key{X: i % b.NX, Y: (i / b.NX) % b.NY, Z: i / (b.NX * b.NY)})
+		for len(queue) > 0 {
+			k := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			for _, d := range neighbors6 {
+				n := Key{X: k.X + d.X, Y: k.Y + d.Y, Z: k.Z + d.Z}
+				if !inBounds(n) {
+					continue
+				}
+				if j := b.index(n); labels[j] == unset {
+					labels[j] = next
+					queue = append(queue, n)
+				}
+			}
+		}
+		next++
+	}
+	return labels
+}
+
+// forEachFaceVoxel calls f for each voxel k on the boundary of b along
+// with the direction d that leads out of the subregion.
+func (b *BinVOX) forEachFaceVoxel(f func(k, d Key)) {
+	for z := 0; z < b.NZ; z++ {
+		for y := 0; y < b.NY; y++ {
+			f(Key{X: 0, Y: y, Z: z}, Key{X: -1})
+			f(Key{X: b.NX - 1, Y: y, Z: z}, Key{X: 1})
+		}
+	}
+	for z := 0; z < b.NZ; z++ {
+		for x := 0; x < b.NX; x++ {
+			f(Key{X: x, Y: 0, Z: z}, Key{Y: -1})
+			f(Key{X: x, Y: b.NY - 1, Z: z}, Key{Y: 1})
+		}
+	}
+	for y := 0; y < b.NY; y++ {
+		for x := 0; x < b.NX; x++ {
+			f(Key{X: x, Y: y, Z: 0}, Key{Z: -1})
+			f(Key{X: x, Y: y, Z: b.NZ - 1}, Key{Z: 1})
+		}
+	}
+}
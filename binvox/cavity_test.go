@@ -0,0 +1,200 @@
+package binvox
+
+import (
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// cubeWithVoid returns a 10x10x10 cube with a 4x4x4 void from (3,3,3) to (6,6,6).
+func cubeWithVoid() *BinVOX {
+	b := solidCube(10)
+	for z := 3; z < 7; z++ {
+		for y := 3; y < 7; y++ {
+			for x := 3; x < 7; x++ {
+				delete(b.WhiteVoxels, Key{X: x, Y: y, Z: z})
+			}
+		}
+	}
+	return b
+}
+
+// dice splits b in half along X into two regions sharing the same voxel grid.
+func dice(b *BinVOX) []*BinVOX {
+	left := New(5, b.NY, b.NZ, b.TX, b.TY, b.TZ, b.Scale, false)
+	right := New(5, b.NY, b.NZ, b.TX+5/b.VoxelsPerMM(), b.TY, b.TZ, b.Scale, false)
+	for k := range b.WhiteVoxels {
+		if k.X < 5 {
+			left.WhiteVoxels[k] = struct{}{}
+		} else {
+			right.WhiteVoxels[Key{X: k.X - 5, Y: k.Y, Z: k.Z}] = struct{}{}
+		}
+	}
+	return []*BinVOX{left, right}
+}
+
+// diceGrid splits b into regions of size^3 voxels sharing the same voxel
+// grid, omitting the empty regions like stldice does.
+func diceGrid(b *BinVOX, size int) []*BinVOX {
+	var result []*BinVOX
+	vpmm := b.VoxelsPerMM()
+	for z := 0; z < b.NZ; z += size {
+		for y := 0; y < b.NY; y += size {
+			for x := 0; x < b.NX; x += size {
+				r := New(size, size, size, b.TX+float64(x)/vpmm, b.TY+float64(y)/vpmm, b.TZ+float64(z)/vpmm, float64(size)/vpmm, false)
+				for k := range b.WhiteVoxels {
+					if k.X >= x && k.Y >= y && k.Z >= z && k.X < x+size && k.Y < y+size && k.Z < z+size {
+						r.WhiteVoxels[Key{X: k.X - x, Y: k.Y - y, Z: k.Z - z}] = struct{}{}
+					}
+				}
+				if len(r.WhiteVoxels) > 0 {
+					result = append(result, r)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// carve removes the voxels from lo to hi (exclusive) from b.
+func carve(b *BinVOX, lo, hi Key) *BinVOX {
+	for z := lo.Z; z < hi.Z; z++ {
+		for y := lo.Y; y < hi.Y; y++ {
+			for x := lo.X; x < hi.X; x++ {
+				delete(b.WhiteVoxels, Key{X: x, Y: y, Z: z})
+			}
+		}
+	}
+	return b
+}
+
+func TestFindCavities(t *testing.T) {
+	tunnel := cubeWithVoid()
+	for x := 0; x < 3; x++ {
+		delete(tunnel.WhiteVoxels, Key{X: x, Y: 4, Z: 4})
+	}
+	twoVoids := solidCube(10)
+	delete(twoVoids.WhiteVoxels, Key{X: 2, Y: 2, Z: 2})
+	delete(twoVoids.WhiteVoxels, Key{X: 7, Y: 2, Z: 1})
+
+	tests := []struct {
+		name    string
+		regions []*BinVOX
+		want    []int
+		lowest  []gl.Vector
+		gaps    []int
+	}{
+		{name: "solid", regions: []*BinVOX{solidCube(10)}},
+		{
+			name:    "void",
+			regions: []*BinVOX{cubeWithVoid()},
+			want:    []int{64},
+			lowest:  []gl.Vector{{X: 3.5, Y: 3.5, Z: 3.5}},
+		},
+		{name: "tunnel to outside", regions: []*BinVOX{tunnel}},
+		{name: "diced tunnel to outside", regions: dice(tunnel)},
+		{
+			name:    "diced void",
+			regions: dice(cubeWithVoid()),
+			want:    []int{64},
+			lowest:  []gl.Vector{{X: 3.5, Y: 3.5, Z: 3.5}},
+		},
+		{
+			name:    "two voids ordered by location",
+			regions: dice(twoVoids),
+			want:    []int{1, 1},
+			lowest:  []gl.Vector{{X: 7.5, Y: 2.5, Z: 1.5}, {X: 2.5, Y: 2.5, Z: 2.5}},
+		},
+		{
+			name:    "cavity swallows a region",
+			regions: diceGrid(carve(solidCube(12), Key{X: 4, Y: 4, Z: 4}, Key{X: 8, Y: 8, Z: 8}), 4),
+			want:    []int{64},
+			lowest:  []gl.Vector{{X: 4.5, Y: 4.5, Z: 4.5}},
+			gaps:    []int{1},
+		},
+		{
+			name:    "cavity spans a missing region",
+			regions: diceGrid(carve(solidCube(12), Key{X: 3, Y: 3, Z: 3}, Key{X: 9, Y: 9, Z: 9}), 4),
+			want:    []int{216},
+			lowest:  []gl.Vector{{X: 3.5, Y: 3.5, Z: 3.5}},
+			gaps:    []int{1},
+		},
+		{
+			name:    "missing regions open to outside",
+			regions: diceGrid(carve(solidCube(12), Key{X: 0, Y: 4, Z: 4}, Key{X: 8, Y: 8, Z: 8}), 4),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindCavities(tt.regions)
+			if err != nil {
+				t.Fatalf("FindCavities: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("FindCavities = %v cavities, want %v", len(got), len(tt.want))
+			}
+			for i, c := range got {
+				if c.NumVoxels != tt.want[i] {
+					t.Errorf("cavity #%v NumVoxels = %v, want %v", i, c.NumVoxels, tt.want[i])
+				}
+				if c.Volume != float64(tt.want[i]) {
+					t.Errorf("cavity #%v Volume = %v, want %v", i, c.Volume, tt.want[i])
+				}
+				if c.Lowest != tt.lowest[i] {
+					t.Errorf("cavity #%v Lowest = %v, want %v", i, c.Lowest, tt.lowest[i])
+				}
+				var gaps int
+				if tt.gaps != nil {
+					gaps = tt.gaps[i]
+				}
+				if len(c.Gaps) != gaps {
+					t.Errorf("cavity #%v has %v gaps, want %v", i, len(c.Gaps), gaps)
+				}
+				var n int
+				for _, keys := range c.Voxels {
+					n += len(keys)
+				}
+				for _, box := range c.Gaps {
+					size := box.Size()
+					n += int(size.X * size.Y * size.Z)
+				}
+				if n != c.NumVoxels {
+					t.Errorf("cavity #%v has %v voxels in regions and gaps, want %v", i, n, c.NumVoxels)
+				}
+			}
+		})
+	}
+}
+
+func TestFillCavities(t *testing.T) {
+	regions := dice(cubeWithVoid())
+	cavities, err := FindCavities(regions)
+	if err != nil {
+		t.Fatalf("FindCavities: %v", err)
+	}
+	FillCavities(regions, cavities)
+	if got := len(regions[0].WhiteVoxels) + len(regions[1].WhiteVoxels); got != 1000 {
+		t.Errorf("FillCavities = %v voxels, want 1000", got)
+	}
+}
+
+func TestDrainCavities(t *testing.T) {
+	regions := dice(cubeWithVoid())
+	cavities, err := FindCavities(regions)
+	if err != nil {
+		t.Fatalf("FindCavities: %v", err)
+	}
+	if err := DrainCavities(regions, cavities, 0); err == nil {
+		t.Error("DrainCavities with zero diameter: want error")
+	}
+	if err := DrainCavities(regions, cavities, 1); err != nil {
+		t.Fatalf("DrainCavities: %v", err)
+	}
+	if got := len(regions[0].WhiteVoxels) + len(regions[1].WhiteVoxels); got != 1000-64-3 {
+		t.Errorf("DrainCavities = %v voxels, want %v", got, 1000-64-3)
+	}
+	if cavities, err = FindCavities(regions); err != nil || len(cavities) != 0 {
+		t.Errorf("FindCavities after draining = %v cavities, %v; want none", len(cavities), err)
+	}
+}
@@ -0,0 +1,138 @@
+package binvox
+
+import (
+	"log"
+	"math"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// Contour represents a closed polygon in a Z plane.
+//
+// Outer boundaries are oriented counterclockwise (when viewed from +Z)
+// and holes are oriented clockwise, so the solid is always on the left.
+type Contour struct {
+	Points []gl.Vector // vertices in order; the last connects back to the first
+	Hole   bool        // true if the contour is the boundary of a hole
+}
+
+// Layer represents the contours of a mesh at height Z.
+type Layer struct {
+	Z        float64
+	Contours []*Contour
+}
+
+// Area returns the signed area of the contour (in square millimeters),
+// which is positive for outer boundaries and negative for holes.
+func (c *Contour) Area() float64 {
+	var a float64
+	for i, p := range c.Points {
+		q := c.Points[(i+1)%len(c.Points)]
+		a += p.X*q.Y - q.X*p.Y
+	}
+	return 0.5 * a
+}
+
+// Contours returns the closed contours of a (watertight) mesh in the
+// Z plane at z. The intersection segments of the triangles are chained
+// into polygons and oriented using the normals of the triangles.
+// Segments that cannot be chained into closed polygons (due to holes in
+// the mesh) are discarded.
+func Contours(mesh *gl.Mesh, z float64) []*Contour {
+	pairs := contourPairs(mesh, z)
+
+	// Index the segments by their starting points.
+	starts := map[gl.Vector][]int{}
+	for i, p := range pairs {
+		starts[p.v1] = append(starts[p.v1], i)
+	}
+	used := make([]bool, len(pairs))
+	next := func(v gl.Vector) int {
+		for _, i := range starts[v] {
+			if !used[i] {
+				return i
+			}
+		}
+		return -1
+	}
+
+	var result []*Contour
+	var discarded int
+	for i, p := range pairs {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		points := []gl.Vector{p.v1}
+		end := p.v2
+		for end != p.v1 {
+			j := next(end)
+			if j < 0 {
+				break
+			}
+			used[j] = true
+			points = append(points, end)
+			end = pairs[j].v2
+		}
+		if end != p.v1 || len(points) < 3 {
+			discarded += len(points)
+			continue
+		}
+		c := &Contour{Points: points}
+		c.Hole = c.Area() < 0
+		result = append(result, c)
+	}
+	if discarded > 0 {
+		log.Printf("Contours(z=%v): discarded %v segments that do not form closed polygons", z, discarded)
+	}
+	return result
+}
+
+// ContourLayers returns the contours of a (watertight) mesh for each
+// layer of the given height (in millimeters), starting at the bottom of
+// the mesh. Each layer is sliced at its middle.
+func ContourLayers(mesh *gl.Mesh, layerHeight float64) []*Layer {
+	mbb := mesh.BoundingBox()
+	n := int(math.Ceil((mbb.Max.Z-mbb.Min.Z)/layerHeight - epsilon))
+	log.Printf("Finding contours of %v layers...", n)
+	layers := make([]*Layer, n)
+	for i := range layers {
+		z := mbb.Min.Z + (float64(i)+0.5)*layerHeight
+		layers[i] = &Layer{Z: z, Contours: Contours(mesh, z)}
+	}
+	return layers
+}
+
+// contourPairs returns the oriented intersections between the mesh and
+// the Z plane at z. Unlike intersectZPlane, vertices lying exactly on the
+// plane are considered to be above it, so each triangle yields at most
+// one segment, and the intersection with each edge is computed identically
+// for both triangles that share it so that the segments can be chained.
+// Each segment is oriented so that the solid lies on its left.
+func contourPairs(mesh *gl.Mesh, z float64) (result []*intersectionPair) {
+	for _, t := range mesh.Triangles {
+		var points []gl.Vector
+		for _, e := range [][2]gl.Vector{{t.V1.Position, t.V2.Position}, {t.V2.Position, t.V3.Position}, {t.V3.Position, t.V1.Position}} {
+			a, b := e[0], e[1]
+			if (a.Z < z) == (b.Z < z) {
+				continue
+			}
+			if b.Less(a) {
+				a, b = b, a
+			}
+			if v, ok := intersectSegment(a, b, z); ok {
+				points = append(points, gl.V(v.X, v.Y, z))
+			}
+		}
+		if len(points) != 2 || points[0] == points[1] {
+			continue
+		}
+		n := t.V2.Position.Sub(t.V1.Position).Cross(t.V3.Position.Sub(t.V1.Position))
+		d := points[1].Sub(points[0])
+		if d.Y*n.X-d.X*n.Y < 0 {
+			points[0], points[1] = points[1], points[0]
+		}
+		result = append(result, &intersectionPair{v1: points[0], v2: points[1], tri: t})
+	}
+	return result
+}
@@ -0,0 +1,81 @@
+package binvox
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+func TestContours(t *testing.T) {
+	// A 3x3x2 ring of voxels (1 voxel per millimeter) with a hole in the middle.
+	b := New(3, 3, 2, 10, 20, 30, 3, false)
+	for z := 0; z < 2; z++ {
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 3; x++ {
+				if x != 1 || y != 1 {
+					b.Add(x, y, z)
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		mesh *gl.Mesh
+		z    float64
+		want []float64 // signed areas
+	}{
+		{"cube", gl.NewCube(), 0, []float64{1}},
+		{"cube bottom face", gl.NewCube(), -0.5, nil},
+		{"above cube", gl.NewCube(), 0.6, nil},
+		{"ring", b.ManifoldMesh(), 30.5, []float64{-1, 9}},
+		{"ring through vertices", b.ManifoldMesh(), 31, []float64{-1, 9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contours := Contours(tt.mesh, tt.z)
+			var got []float64
+			for _, c := range contours {
+				a := c.Area()
+				if c.Hole != (a < 0) {
+					t.Errorf("contour with area %v has Hole=%v", a, c.Hole)
+				}
+				for _, p := range c.Points {
+					if p.Z != tt.z {
+						t.Errorf("point %v not at z=%v", p, tt.z)
+					}
+				}
+				got = append(got, math.Round(a*1e6)/1e6)
+			}
+			sort.Float64s(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Contours areas = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Contours areas = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestContourLayers(t *testing.T) {
+	mesh := gl.NewCube()
+	mesh.Transform(gl.Translate(gl.V(0, 0, 0.5))) // z=0..1
+	layers := ContourLayers(mesh, 0.25)
+	if len(layers) != 4 {
+		t.Fatalf("ContourLayers = %v layers, want 4", len(layers))
+	}
+	for i, layer := range layers {
+		if want := 0.25*float64(i) + 0.125; math.Abs(layer.Z-want) > 1e-9 {
+			t.Errorf("layer %v Z = %v, want %v", i, layer.Z, want)
+		}
+		if len(layer.Contours) != 1 {
+			t.Errorf("layer %v has %v contours, want 1", i, len(layer.Contours))
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package binvox
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// DistanceField represents a signed distance field sampled at voxel centers.
+// Distances are in millimeters and are negative inside the model.
+//
+// The field covers its originating BinVOX plus Halo extra voxels on every
+// side, so NX = b.NX + 2*Halo (and likewise for NY and NZ) and (TX,TY,TZ)
+// is the world space location of the corner of the first halo voxel.
+type DistanceField struct {
+	NX, NY, NZ int     // number of samples in each dimension
+	TX, TY, TZ float64 // translation (location of origin in world space)
+	VoxelSize  float64 // size of each voxel in millimeters
+	Halo       int     // number of halo voxels on each side
+
+	// Data holds the samples with X running fastest, then Y, then Z.
+	Data []float32
+}
+
+// SignedDistanceField computes the exact Euclidean signed distance field of
+// the model in linear time (using the Felzenszwalb-Huttenlocher algorithm).
+//
+// Each sample is the distance from the voxel center to the center of the
+// nearest voxel of the opposite kind, less half a voxel to approximate the
+// distance to the surface.
+//
+// halo extra voxels are processed on every side of the subregion. Voxels
+// present in the halo (see WithHalo) are taken into account, so a diced
+// subregion produces exact distances (up to halo voxels away) that match
+// those of the complete model. All space beyond the halo is treated as
+// exterior, so interior samples are always finite. Exterior samples are
+// set to +Inf when the field has no solid voxels.
+func (b *BinVOX) SignedDistanceField(halo int) (*DistanceField, error) {
+	if b.NX <= 0 || b.NY <= 0 || b.NZ <= 0 {
+		return nil, fmt.Errorf("model dimensions must be positive (%v,%v,%v)", b.NX, b.NY, b.NZ)
+	}
+	if halo < 0 {
+		return nil, fmt.Errorf("halo must not be negative: %v", halo)
+	}
+
+	s := 1.0 / b.VoxelsPerMM()
+	d := &DistanceField{
+		NX: b.NX + 2*halo, NY: b.NY + 2*halo, NZ: b.NZ + 2*halo,
+		TX: b.TX - float64(halo)*s, TY: b.TY - float64(halo)*s, TZ: b.TZ - float64(halo)*s,
+		VoxelSize: s,
+		Halo:      halo,
+	}
+	log.Printf("Computing signed distance field (n=[%v,%v,%v]) for %v...", d.NX, d.NY, d.NZ, b)
+
+	// The transform runs over the field padded by one empty voxel on
+	// every side, so the space beyond the halo is exterior.
+	p := &DistanceField{NX: d.NX + 2, NY: d.NY + 2, NZ: d.NZ + 2}
+	n := p.NX * p.NY * p.NZ
+	solid := make([]bool, n)
+	keyFunc := func(k Key) {
+		x, y, z := k.X+halo, k.Y+halo, k.Z+halo
+		if x < 0 || y < 0 || z < 0 || x >= d.NX || y >= d.NY || z >= d.NZ {
+			return
+		}
+		solid[x+1+p.NX*(y+1+p.NY*(z+1))] = true
+	}
+	for k := range b.WhiteVoxels {
+		keyFunc(k)
+	}
+	for k := range b.ColorVoxels {
+		keyFunc(k)
+	}
+
+	outside := make([]float64, n) // squared distance to nearest solid voxel
+	inside := make([]float64, n)  // squared distance to nearest empty voxel
+	for i, v := range solid {
+		if v {
+			inside[i] = math.Inf(1)
+		} else {
+			outside[i] = math.Inf(1)
+		}
+	}
+	p.edt(outside)
+	p.edt(inside)
+
+	d.Data = make([]float32, d.NX*d.NY*d.NZ)
+	for z := 0; z < d.NZ; z++ {
+		for y := 0; y < d.NY; y++ {
+			for x := 0; x < d.NX; x++ {
+				i := x + 1 + p.NX*(y+1+p.NY*(z+1))
+				if solid[i] {
+					d.Data[x+d.NX*(y+d.NY*z)] = float32(-(math.Sqrt(inside[i]) - 0.5) * s)
+				} else {
+					d.Data[x+d.NX*(y+d.NY*z)] = float32((math.Sqrt(outside[i]) - 0.5) * s)
+				}
+			}
+		}
+	}
+
+	log.Printf("Done computing signed distance field.")
+	return d, nil
+}
+
+// At returns the signed distance (in millimeters) at the voxel (x,y,z)
+// of the originating BinVOX. Halo voxels are addressed with negative
+// indices or indices beyond the subregion. ok is false beyond the halo.
+func (d *DistanceField) At(x, y, z int) (dist float64, ok bool) {
+	x, y, z = x+d.Halo, y+d.Halo, z+d.Halo
+	if x < 0 || y < 0 || z < 0 || x >= d.NX || y >= d.NY || z >= d.NZ {
+		return 0, false
+	}
+	return float64(d.Data[x+d.NX*(y+d.NY*z)]), true
+}
+
+// edt performs an in-place squared Euclidean distance transform of f
+// (in voxel units) by transforming along X, then Y, then Z.
+func (d *DistanceField) edt(f []float64) {
+	line := func(start, stride, n int) {
+		g := make([]float64, n)
+		for i := range g {
+			g[i] = f[start+i*stride]
+		}
+		out := make([]float64, n)
+		edt1D(g, out)
+		for i, v := range out {
+			f[start+i*stride] = v
+		}
+	}
+
+	var wg sync.WaitGroup
+	for z := 0; z < d.NZ; z++ {
+		wg.Add(1)
+		go func(z int) {
+			for y := 0; y < d.NY; y++ {
+				line(d.NX*(y+d.NY*z), 1, d.NX)
+			}
+			wg.Done()
+		}(z)
+	}
+	wg.Wait()
+	for z := 0; z < d.NZ; z++ {
+		wg.Add(1)
+		go func(z int) {
+			for x := 0; x < d.NX; x++ {
+				line(x+d.NX*d.NY*z, d.NX, d.NY)
+			}
+			wg.Done()
+		}(z)
+	}
+	wg.Wait()
+	for y := 0; y < d.NY; y++ {
+		wg.Add(1)
+		go func(y int) {
+			for x := 0; x < d.NX; x++ {
+				line(x+d.NX*y, d.NX*d.NY, d.NZ)
+			}
+			wg.Done()
+		}(y)
+	}
+	wg.Wait()
+}
+
+// edt1D computes the one-dimensional squared distance transform of the
+// sampled function f using the lower envelope of parabolas.
+// See: Felzenszwalb and Huttenlocher, "Distance Transforms of Sampled Functions".
+func edt1D(f, out []float64) {
+	n := len(f)
+	v := make([]int, n)       // locations of parabolas in the lower envelope
+	z := make([]float64, n+1) // boundaries between parabolas
+	k := -1
+	for q := 0; q < n; q++ {
+		if math.IsInf(f[q], 1) {
+			continue
+		}
+		for {
+			if k < 0 {
+				k = 0
+				v[0] = q
+				z[0] = math.Inf(-1)
+				z[1] = math.Inf(1)
+				break
+			}
+			p := v[k]
+			s := ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*q-2*p)
+			if s <= z[k] {
+				k--
+				continue
+			}
+			k++
+			v[k] = q
+			z[k] = s
+			z[k+1] = math.Inf(1)
+			break
+		}
+	}
+	if k < 0 { // no features at all
+		for q := range out {
+			out[q] = math.Inf(1)
+		}
+		return
+	}
+	k = 0
+	for q := 0; q < n; q++ {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		dq := float64(q - v[k])
+		out[q] = dq*dq + f[v[k]]
+	}
+}
+
+// WithHalo returns a copy of the subregion b with the voxels of the
+// neighboring subregions that lie within halo voxels of it added as white
+// voxels. All subregions must share the same voxel grid. The added voxels
+// have keys beyond the bounds of b, which is understood by
+// SignedDistanceField and other analyses. b itself is not modified.
+func (b *BinVOX) WithHalo(halo int, neighbors ...*BinVOX) (*BinVOX, error) {
+	result := *b
+	result.WhiteVoxels = make(WhiteVoxelMap, len(b.WhiteVoxels))
+	for k := range b.WhiteVoxels {
+		result.WhiteVoxels[k] = struct{}{}
+	}
+	if b.ColorVoxels != nil {
+		result.ColorVoxels = make(ColorVoxelMap, len(b.ColorVoxels))
+		for k, c := range b.ColorVoxels {
+			result.ColorVoxels[k] = c
+		}
+	}
+	for i, n := range neighbors {
+		dx, dy, dz, err := GridOffset(b, n)
+		if err != nil {
+			return nil, fmt.Errorf("neighbor #%v: %v", i, err)
+		}
+		keyFunc := func(k Key) {
+			x, y, z := k.X+dx, k.Y+dy, k.Z+dz
+			if x < -halo || y < -halo || z < -halo || x >= b.NX+halo || y >= b.NY+halo || z >= b.NZ+halo {
+				return
+			}
+			result.WhiteVoxels[Key{X: x, Y: y, Z: z}] = struct{}{}
+		}
+		for k := range n.WhiteVoxels {
+			keyFunc(k)
+		}
+		for k := range n.ColorVoxels {
+			keyFunc(k)
+		}
+	}
+	return &result, nil
+}
+
+var voxelSizeRE = regexp.MustCompile(`^voxelsize (\S+)\s*$`)
+
+// maxDistanceFieldSamples is the maximum number of samples accepted by
+// ReadDistanceField (4GB of data).
+const maxDistanceFieldSamples = 1 << 30
+
+const sdfHeaderFMT = `#sdf 1
+dim %v %v %v
+translate %g %g %g
+voxelsize %g
+data
+`
+
+// Write writes the distance field as a raw volume of little-endian
+// float32 samples (X running fastest, then Y, then Z) preceded by a
+// small text header in the style of binvox files.
+func (d *DistanceField) Write(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("unable to create file %q: %v", filename, err)
+	}
+	w := bufio.NewWriter(f)
+	if err := d.write(w); err != nil {
+		f.Close()
+		return fmt.Errorf("Write(%q): %v", filename, err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("Write(%q): %v", filename, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close %q: %v", filename, err)
+	}
+	log.Printf("Done writing %v samples to file %q.", len(d.Data), filename)
+	return nil
+}
+
+func (d *DistanceField) write(w io.Writer) error {
+	if len(d.Data) != d.NX*d.NY*d.NZ {
+		return fmt.Errorf("got %v samples, want %v", len(d.Data), d.NX*d.NY*d.NZ)
+	}
+	if _, err := fmt.Fprintf(w, sdfHeaderFMT, d.NX, d.NY, d.NZ, d.TX, d.TY, d.TZ, d.VoxelSize); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, d.Data)
+}
+
+// ReadDistanceField reads a distance field written by Write.
+// Since the halo is not recorded in the file, Halo is always zero.
+func ReadDistanceField(filename string) (*DistanceField, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %q: %v", filename, err)
+	}
+	defer f.Close()
+	d, err := readDistanceField(f)
+	if err != nil {
+		return nil, fmt.Errorf("ReadDistanceField(%q): %v", filename, err)
+	}
+	return d, nil
+}
+
+func readDistanceField(r io.Reader) (*DistanceField, error) {
+	b := bufio.NewReader(r)
+	var lines [5]string
+	for i := range lines {
+		line, err := b.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("could not read header: %v", err)
+		}
+		lines[i] = line
+	}
+	if lines[0] != "#sdf 1\n" {
+		return nil, fmt.Errorf("not a distance field file: %v", lines[0])
+	}
+	if lines[4] != "data\n" {
+		return nil, fmt.Errorf("could not find data section: %v", lines[4])
+	}
+
+	d := &DistanceField{}
+	parts := dimRE.FindStringSubmatch(lines[1])
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("unable to parse dimensions: %v", lines[1])
+	}
+	var dims [3]int
+	for i := range dims {
+		v, err := strconv.Atoi(parts[i+1])
+		if err != nil || v <= 0 || v > maxDistanceFieldSamples {
+			return nil, fmt.Errorf("invalid dimensions: %v", lines[1])
+		}
+		dims[i] = v
+	}
+	d.NX, d.NY, d.NZ = dims[0], dims[1], dims[2]
+	if d.NX*d.NY > maxDistanceFieldSamples || d.NX*d.NY*d.NZ > maxDistanceFieldSamples {
+		return nil, fmt.Errorf("too many samples: %v", lines[1])
+	}
+
+	parts = translateRE.FindStringSubmatch(lines[2])
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("unable to parse translation: %v", lines[2])
+	}
+	var err error
+	if d.TX, err = strconv.ParseFloat(parts[1], 64); err != nil {
+		return nil, fmt.Errorf("unable to parse translation: %v", lines[2])
+	}
+	if d.TY, err = strconv.ParseFloat(parts[2], 64); err != nil {
+		return nil, fmt.Errorf("unable to parse translation: %v", lines[2])
+	}
+	if d.TZ, err = strconv.ParseFloat(parts[3], 64); err != nil {
+		return nil, fmt.Errorf("unable to parse translation: %v", lines[2])
+	}
+
+	parts = voxelSizeRE.FindStringSubmatch(lines[3])
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("unable to parse voxel size: %v", lines[3])
+	}
+	if d.VoxelSize, err = strconv.ParseFloat(parts[1], 64); err != nil {
+		return nil, fmt.Errorf("unable to parse voxel size: %v", lines[3])
+	}
+
+	// The data is read in chunks so that a truncated file with large
+	// dimensions fails without allocating all of its samples up front.
+	n := d.NX * d.NY * d.NZ
+	for len(d.Data) < n {
+		size := n - len(d.Data)
+		if size > 1<<16 {
+			size = 1 << 16
+		}
+		chunk := make([]float32, size)
+		if err := binary.Read(b, binary.LittleEndian, chunk); err != nil {
+			return nil, fmt.Errorf("error reading data: got %v of %v samples: %v", len(d.Data), n, err)
+		}
+		d.Data = append(d.Data, chunk...)
+	}
+	if _, err := b.ReadByte(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after %v samples", n)
+	}
+	return d, nil
+}
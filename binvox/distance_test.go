@@ -0,0 +1,187 @@
+package binvox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSignedDistanceField(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	b := New(7, 5, 6, 1, 2, 3, 14, false) // 0.5 voxels per millimeter
+	for i := 0; i < 40; i++ {
+		b.Add(r.Intn(b.NX), r.Intn(b.NY), r.Intn(b.NZ))
+	}
+
+	d, err := b.SignedDistanceField(0)
+	if err != nil {
+		t.Fatalf("SignedDistanceField: %v", err)
+	}
+
+	for z := 0; z < b.NZ; z++ {
+		for y := 0; y < b.NY; y++ {
+			for x := 0; x < b.NX; x++ {
+				_, solid := b.Get(x, y, z)
+				best := math.Inf(1)
+				for k := range allKeys(b) {
+					if _, ok := b.Get(k.X, k.Y, k.Z); ok == solid {
+						continue
+					}
+					dx, dy, dz := float64(k.X-x), float64(k.Y-y), float64(k.Z-z)
+					best = math.Min(best, math.Sqrt(dx*dx+dy*dy+dz*dz))
+				}
+				want := (best - 0.5) * 2
+				if solid {
+					want = -want
+				}
+				got, ok := d.At(x, y, z)
+				if !ok || math.Abs(got-want) > 1e-5 {
+					t.Errorf("At(%v,%v,%v) = (%v,%v), want %v", x, y, z, got, ok, want)
+				}
+			}
+		}
+	}
+}
+
+// allKeys returns the keys of b plus a layer of (empty) exterior voxels
+// on every side.
+func allKeys(b *BinVOX) map[Key]struct{} {
+	result := map[Key]struct{}{}
+	for z := -1; z <= b.NZ; z++ {
+		for y := -1; y <= b.NY; y++ {
+			for x := -1; x <= b.NX; x++ {
+				result[Key{X: x, Y: y, Z: z}] = struct{}{}
+			}
+		}
+	}
+	return result
+}
+
+func TestSignedDistanceFieldHalo(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	whole := New(12, 6, 6, 0, 0, 0, 12, false)
+	for i := 0; i < 200; i++ {
+		whole.Add(r.Intn(whole.NX), r.Intn(whole.NY), r.Intn(whole.NZ))
+	}
+
+	left := New(6, 6, 6, 0, 0, 0, 6, false)
+	right := New(6, 6, 6, 6, 0, 0, 6, false)
+	for k := range whole.WhiteVoxels {
+		if k.X < 6 {
+			left.Add(k.X, k.Y, k.Z)
+		} else {
+			right.Add(k.X-6, k.Y, k.Z)
+		}
+	}
+	const halo = 3
+	n := len(left.WhiteVoxels)
+	orig := left
+	left, err := left.WithHalo(halo, right)
+	if err != nil {
+		t.Fatalf("WithHalo: %v", err)
+	}
+	if len(left.WhiteVoxels) <= n {
+		t.Error("WithHalo added no voxels")
+	}
+	if len(orig.WhiteVoxels) != n {
+		t.Errorf("WithHalo modified the subregion: %v voxels, want %v", len(orig.WhiteVoxels), n)
+	}
+
+	want, err := whole.SignedDistanceField(halo)
+	if err != nil {
+		t.Fatalf("SignedDistanceField: %v", err)
+	}
+	got, err := left.SignedDistanceField(halo)
+	if err != nil {
+		t.Fatalf("SignedDistanceField: %v", err)
+	}
+	for z := 0; z < left.NZ; z++ {
+		for y := 0; y < left.NY; y++ {
+			for x := 0; x < left.NX; x++ {
+				w, _ := want.At(x, y, z)
+				if math.Abs(w) > halo-0.5 {
+					continue // beyond the reach of the halo
+				}
+				if g, _ := got.At(x, y, z); g != w {
+					t.Errorf("At(%v,%v,%v) = %v, want %v", x, y, z, g, w)
+				}
+			}
+		}
+	}
+}
+
+func TestDistanceFieldReadWrite(t *testing.T) {
+	want := &DistanceField{
+		NX: 2, NY: 1, NZ: 2,
+		TX: -1, TY: 2.5, TZ: 3,
+		VoxelSize: 0.25,
+		Data:      []float32{-0.5, 0.5, 1.5, float32(math.Inf(1))},
+	}
+	var buf bytes.Buffer
+	if err := want.write(&buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got, err := readDistanceField(&buf)
+	if err != nil {
+		t.Fatalf("readDistanceField: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readDistanceField = %#v, want %#v", got, want)
+	}
+}
+
+func TestSignedDistanceFieldFilled(t *testing.T) {
+	// A model that fills its grid has its exterior beyond the bounds.
+	b := New(3, 3, 3, 0, 0, 0, 3, false)
+	for k := range allKeys(b) {
+		if k.X >= 0 && k.Y >= 0 && k.Z >= 0 && k.X < 3 && k.Y < 3 && k.Z < 3 {
+			b.Add(k.X, k.Y, k.Z)
+		}
+	}
+	d, err := b.SignedDistanceField(0)
+	if err != nil {
+		t.Fatalf("SignedDistanceField: %v", err)
+	}
+	for _, tt := range []struct {
+		k    Key
+		want float64
+	}{
+		{k: Key{X: 1, Y: 1, Z: 1}, want: -1.5},
+		{k: Key{X: 0, Y: 1, Z: 1}, want: -0.5},
+		{k: Key{X: 2, Y: 2, Z: 2}, want: -0.5},
+	} {
+		if got, _ := d.At(tt.k.X, tt.k.Y, tt.k.Z); got != tt.want {
+			t.Errorf("At(%v) = %v, want %v", tt.k, got, tt.want)
+		}
+	}
+}
+
+func TestReadDistanceFieldMalformed(t *testing.T) {
+	header := "#sdf 1\ndim %v\ntranslate 0 0 0\nvoxelsize 1\ndata\n"
+	tests := []struct {
+		name    string
+		dim     string
+		samples int
+	}{
+		{name: "zero dimension", dim: "2 0 2"},
+		{name: "too many samples", dim: "65536 65536 65536", samples: 1},
+		{name: "truncated data", dim: "2 2 2", samples: 7},
+		{name: "extra data", dim: "2 2 2", samples: 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			fmt.Fprintf(&buf, header, tt.dim)
+			if err := binary.Write(&buf, binary.LittleEndian, make([]float32, tt.samples)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := readDistanceField(&buf); err == nil {
+				t.Error("readDistanceField = nil error, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package binvox
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+const (
+	// ScaleTolerance is the relative tolerance within which the voxels
+	// per millimeter of two models are considered equal.
+	ScaleTolerance = 1e-6
+
+	// AlignTolerance is the tolerance (as a fraction of a voxel) within
+	// which the offset between two models is considered to be a whole
+	// number of voxels.
+	AlignTolerance = 1e-3
+)
+
+// ScaleMismatchError is returned when two models do not have the same
+// number of voxels per millimeter.
+type ScaleMismatchError struct {
+	VPMM, OtherVPMM float64
+}
+
+func (e *ScaleMismatchError) Error() string {
+	return fmt.Sprintf("voxels per millimeter mismatch: %v != %v", e.VPMM, e.OtherVPMM)
+}
+
+// MisalignedError is returned when the translations of two models with
+// the same number of voxels per millimeter are not offset by a whole
+// number of voxels.
+type MisalignedError struct {
+	// Offset is the offset between the models in voxels.
+	Offset gl.Vector
+
+	// Residual is the distance (in millimeters) that the second model
+	// would need to be moved to align with the grid of the first.
+	Residual gl.Vector
+}
+
+func (e *MisalignedError) Error() string {
+	return fmt.Sprintf("voxel grids are misaligned by (%v,%v,%v) mm (offset=(%v,%v,%v) voxels)",
+		e.Residual.X, e.Residual.Y, e.Residual.Z, e.Offset.X, e.Offset.Y, e.Offset.Z)
+}
+
+// CheckGrid returns nil if b and o share the same voxel grid, meaning
+// that they have the same voxels per millimeter (within ScaleTolerance)
+// and their translations differ by a whole number of voxels (within
+// AlignTolerance). Otherwise it returns a *ScaleMismatchError or a
+// *MisalignedError.
+func CheckGrid(b, o *BinVOX) error {
+	_, _, _, err := GridOffset(b, o)
+	return err
+}
+
+// GridOffset returns the offset (in voxels) that maps keys in o onto
+// the voxel grid of b. The two models must share the same voxel grid
+// (see CheckGrid).
+func GridOffset(b, o *BinVOX) (dx, dy, dz int, err error) {
+	dx, dy, dz, residual, err := SnapOffset(b, o)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	vpmm := b.VoxelsPerMM()
+	if residual.Abs().MaxComponent()*vpmm > AlignTolerance {
+		offset := gl.V(o.TX-b.TX, o.TY-b.TY, o.TZ-b.TZ).MulScalar(vpmm)
+		return 0, 0, 0, &MisalignedError{Offset: offset, Residual: residual}
+	}
+	return dx, dy, dz, nil
+}
+
+// SnapOffset is like GridOffset but rounds the offset between the
+// models to the nearest whole number of voxels instead of requiring
+// them to be aligned. It returns the residual distance (in millimeters)
+// that o would need to be moved to align with the grid of b. The two
+// models must have the same voxels per millimeter.
+func SnapOffset(b, o *BinVOX) (dx, dy, dz int, residual gl.Vector, err error) {
+	vpmm := b.VoxelsPerMM()
+	if ovpmm := o.VoxelsPerMM(); math.Abs(vpmm-ovpmm) > ScaleTolerance*vpmm {
+		return 0, 0, 0, residual, &ScaleMismatchError{VPMM: vpmm, OtherVPMM: ovpmm}
+	}
+	fx, fy, fz := (o.TX-b.TX)*vpmm, (o.TY-b.TY)*vpmm, (o.TZ-b.TZ)*vpmm
+	dx, dy, dz = int(math.Round(fx)), int(math.Round(fy)), int(math.Round(fz))
+	residual = gl.V(float64(dx)-fx, float64(dy)-fy, float64(dz)-fz).DivScalar(vpmm)
+	return dx, dy, dz, residual, nil
+}
+
+// SnapToGrid moves o by less than half a voxel so that it shares the
+// voxel grid of b, and returns the distance (in millimeters) that it
+// was moved. The two models must have the same voxels per millimeter.
+func SnapToGrid(b, o *BinVOX) (residual gl.Vector, err error) {
+	_, _, _, residual, err = SnapOffset(b, o)
+	if err != nil {
+		return residual, err
+	}
+	if residual != (gl.Vector{}) {
+		log.Printf("Snapping %v onto the voxel grid by (%v,%v,%v) mm", o, residual.X, residual.Y, residual.Z)
+	}
+	o.TX += residual.X
+	o.TY += residual.Y
+	o.TZ += residual.Z
+	return residual, nil
+}
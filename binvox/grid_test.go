@@ -0,0 +1,77 @@
+package binvox
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+func TestGridOffset(t *testing.T) {
+	base := New(10, 10, 10, 0, 0, 0, 5, false) // 2 vpmm
+
+	tests := []struct {
+		name       string
+		o          *BinVOX
+		dx, dy, dz int
+		misaligned bool
+		mismatch   bool
+	}{
+		{name: "same", o: New(10, 10, 10, 0, 0, 0, 5, false)},
+		{name: "whole voxels", o: New(4, 4, 4, 1.5, -2, 0.5, 2, false), dx: 3, dy: -4, dz: 1},
+		{name: "within tolerance", o: New(4, 4, 4, 1.5+1e-9, 0, 0, 2, false), dx: 3},
+		{name: "sub-voxel", o: New(4, 4, 4, 1.2, 0, 0, 2, false), misaligned: true},
+		{name: "scale mismatch", o: New(4, 4, 4, 0, 0, 0, 4, false), mismatch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dx, dy, dz, err := GridOffset(base, tt.o)
+			var me *MisalignedError
+			if got := errors.As(err, &me); got != tt.misaligned {
+				t.Errorf("GridOffset err = %v, want MisalignedError=%v", err, tt.misaligned)
+			}
+			var se *ScaleMismatchError
+			if got := errors.As(err, &se); got != tt.mismatch {
+				t.Errorf("GridOffset err = %v, want ScaleMismatchError=%v", err, tt.mismatch)
+			}
+			if err != nil {
+				if cerr := CheckGrid(base, tt.o); cerr == nil {
+					t.Error("CheckGrid = nil, want error")
+				}
+				return
+			}
+			if dx != tt.dx || dy != tt.dy || dz != tt.dz {
+				t.Errorf("GridOffset = (%v,%v,%v), want (%v,%v,%v)", dx, dy, dz, tt.dx, tt.dy, tt.dz)
+			}
+		})
+	}
+}
+
+func TestSnapToGrid(t *testing.T) {
+	base := New(10, 10, 10, 0, 0, 0, 5, false) // 2 vpmm
+	o := New(4, 4, 4, 1.2, 0.9, -0.1, 2, false)
+
+	residual, err := SnapToGrid(base, o)
+	if err != nil {
+		t.Fatalf("SnapToGrid: %v", err)
+	}
+	if want := gl.V(-0.2, 0.1, 0.1); residual.Sub(want).Length() > 1e-9 {
+		t.Errorf("SnapToGrid residual = %v, want %v", residual, want)
+	}
+	dx, dy, dz, err := GridOffset(base, o)
+	if err != nil {
+		t.Fatalf("GridOffset after SnapToGrid: %v", err)
+	}
+	if dx != 2 || dy != 2 || dz != 0 {
+		t.Errorf("GridOffset after SnapToGrid = (%v,%v,%v), want (2,2,0)", dx, dy, dz)
+	}
+	if math.Abs(o.TX-1) > 1e-9 {
+		t.Errorf("SnapToGrid TX = %v, want 1", o.TX)
+	}
+
+	if _, err := SnapToGrid(base, New(4, 4, 4, 0, 0, 0, 4, false)); err == nil {
+		t.Error("SnapToGrid with scale mismatch: want error")
+	}
+}
@@ -0,0 +1,263 @@
+package binvox
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// LatticeFunc reports whether the world space point p (in millimeters)
+// lies within a lattice infill. Since lattices are evaluated in world
+// space, diced subregions of a model tile seamlessly.
+type LatticeFunc func(p gl.Vector) bool
+
+// CubicLattice returns a LatticeFunc representing a simple cubic lattice
+// of axis-aligned square beams. cellSize is the distance between beams
+// and beamWidth is the width of each beam (both in millimeters).
+func CubicLattice(cellSize, beamWidth float64) LatticeFunc {
+	h := 0.5 * beamWidth
+	onBeam := func(v float64) bool {
+		d := math.Abs(v - cellSize*math.Floor(v/cellSize+0.5))
+		return d <= h
+	}
+	return func(p gl.Vector) bool {
+		x, y, z := onBeam(p.X), onBeam(p.Y), onBeam(p.Z)
+		return (x && y) || (y && z) || (z && x)
+	}
+}
+
+// HollowOptions controls how a model is hollowed.
+type HollowOptions struct {
+	// WallThickness is the minimum thickness (in millimeters) of the
+	// wall that is retained around the cavity.
+	WallThickness float64
+
+	// Lattice, if non-nil, fills the cavity with the given lattice
+	// (e.g. CubicLattice or GyroidLattice), which is then joined to the wall.
+	Lattice LatticeFunc
+
+	// DrainDiameter is the diameter (in millimeters) of each drain hole.
+	// Zero means that no drain holes are punched.
+	DrainDiameter float64
+
+	// DrainHoles are world space points (in millimeters) within the cavity
+	// from which drain holes are punched down (in -Z) through the wall.
+	// If empty (and DrainDiameter is non-zero), one drain hole is punched
+	// at the lowest point of each disconnected cavity.
+	DrainHoles []gl.Vector
+}
+
+// Hollow hollows out the solid model, keeping a wall of (at least)
+// opts.WallThickness millimeters. Voxels not present in the model
+// (including those beyond the subregion) are considered to be outside
+// of the model, so diced subregions should be hollowed with enough
+// neighboring voxels present to avoid introducing walls at the seams.
+//
+// The wall is found by peeling off layers of 26-connected voxels, so
+// the retained wall is never thinner than requested.
+//
+// Hollow overwrites the WhiteVoxels map in b.
+func (b *BinVOX) Hollow(opts *HollowOptions) error {
+	if opts == nil || opts.WallThickness <= 0 {
+		return fmt.Errorf("wall thickness must be positive")
+	}
+	if opts.DrainDiameter < 0 {
+		return fmt.Errorf("drain diameter must not be negative")
+	}
+
+	vpmm := b.VoxelsPerMM()
+	layers := int(math.Ceil(opts.WallThickness * vpmm))
+	log.Printf("Hollowing %v with a %v voxel wall...", b, layers)
+
+	solid := WhiteVoxelMap{}
+	for k := range b.WhiteVoxels {
+		solid[k] = struct{}{}
+	}
+	for k := range b.ColorVoxels {
+		solid[k] = struct{}{}
+	}
+
+	// Peel off the wall, one layer at a time.
+	wall := WhiteVoxelMap{}
+	var front []Key
+	for k := range solid {
+		if !solid.surrounded(k) {
+			wall[k] = struct{}{}
+			front = append(front, k)
+		}
+	}
+	for i := 1; i < layers; i++ {
+		var next []Key
+		for _, k := range front {
+			forEachNeighbor26(k, func(n Key) {
+				if _, ok := solid[n]; !ok {
+					return
+				}
+				if _, ok := wall[n]; ok {
+					return
+				}
+				wall[n] = struct{}{}
+				next = append(next, n)
+			})
+		}
+		front = next
+	}
+
+	cavity := WhiteVoxelMap{}
+	for k := range solid {
+		if _, ok := wall[k]; !ok {
+			cavity[k] = struct{}{}
+		}
+	}
+	log.Printf("Wall has %v voxels; cavity has %v voxels.", len(wall), len(cavity))
+
+	voxels := WhiteVoxelMap{}
+	for k := range wall {
+		voxels[k] = struct{}{}
+	}
+	if opts.Lattice != nil {
+		for k := range cavity {
+			if opts.Lattice(b.VoxelCenter(k)) {
+				voxels[k] = struct{}{}
+			}
+		}
+	}
+
+	if opts.DrainDiameter > 0 {
+		holes := make([]Key, 0, len(opts.DrainHoles))
+		for _, p := range opts.DrainHoles {
+			holes = append(holes, b.VoxelAt(p))
+		}
+		if len(holes) == 0 {
+			for _, c := range cavity.components() {
+				holes = append(holes, lowest(c))
+			}
+		}
+		r := 0.5 * opts.DrainDiameter * vpmm
+		for _, k := range holes {
+			log.Printf("Punching %v mm drain hole at %v", opts.DrainDiameter, b.VoxelCenter(k))
+			punchDrain(voxels, wall, k, r)
+		}
+	}
+
+	b.WhiteVoxels = voxels
+	b.ColorVoxels = nil
+	log.Printf("Done hollowing; %v voxels remain.", len(b.WhiteVoxels))
+	return nil
+}
+
+// punchDrain removes all voxels within radius r (in voxels) of the vertical
+// line passing through k, starting at k and moving down (in -Z) until the
+// wall has been completely pierced.
+func punchDrain(voxels, wall WhiteVoxelMap, k Key, r float64) {
+	minZ := k.Z
+	for v := range voxels {
+		if v.Z < minZ {
+			minZ = v.Z
+		}
+	}
+
+	ri := int(math.Ceil(r))
+	for dy := -ri; dy <= ri; dy++ {
+		for dx := -ri; dx <= ri; dx++ {
+			if dx != 0 || dy != 0 {
+				if float64(dx*dx+dy*dy) > r*r {
+					continue
+				}
+			}
+			var seenWall bool
+			for z := k.Z; z >= minZ; z-- {
+				v := Key{X: k.X + dx, Y: k.Y + dy, Z: z}
+				_, isWall := wall[v]
+				if seenWall && !isWall {
+					break // pierced the wall
+				}
+				if isWall {
+					seenWall = true
+				}
+				delete(voxels, v)
+			}
+		}
+	}
+}
+
+// surrounded reports whether all 26 neighbors of k are present.
+func (m WhiteVoxelMap) surrounded(k Key) bool {
+	for z := -1; z <= 1; z++ {
+		for y := -1; y <= 1; y++ {
+			for x := -1; x <= 1; x++ {
+				if _, ok := m[Key{X: k.X + x, Y: k.Y + y, Z: k.Z + z}]; !ok {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// forEachNeighbor26 calls f for each of the 26 neighbors of k.
+func forEachNeighbor26(k Key, f func(n Key)) {
+	for z := -1; z <= 1; z++ {
+		for y := -1; y <= 1; y++ {
+			for x := -1; x <= 1; x++ {
+				if x != 0 || y != 0 || z != 0 {
+					f(Key{X: k.X + x, Y: k.Y + y, Z: k.Z + z})
+				}
+			}
+		}
+	}
+}
+
+// neighbors6 are the offsets of the 6 face-adjacent neighbors of a voxel.
+var neighbors6 = []Key{{X: -1}, {X: 1}, {Y: -1}, {Y: 1}, {Z: -1}, {Z: 1}}
+
+// components returns the 6-connected components of the voxels in m.
+func (m WhiteVoxelMap) components() [][]Key {
+	seen := make(map[Key]bool, len(m))
+	var result [][]Key
+	for k := range m {
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		c := []Key{k}
+		for i := 0; i < len(c); i++ {
+			for _, d := range neighbors6 {
+				n := Key{X: c[i].X + d.X, Y: c[i].Y + d.Y, Z: c[i].Z + d.Z}
+				if _, ok := m[n]; ok && !seen[n] {
+					seen[n] = true
+					c = append(c, n)
+				}
+			}
+		}
+		result = append(result, c)
+	}
+	// Keep the results stable for any given model.
+	sort.Slice(result, func(a, b int) bool { return lessKey(lowest(result[a]), lowest(result[b])) })
+	return result
+}
+
+// lowest returns the lowest key (in Z, then Y, then X) in keys.
+func lowest(keys []Key) Key {
+	result := keys[0]
+	for _, k := range keys[1:] {
+		if lessKey(k, result) {
+			result = k
+		}
+	}
+	return result
+}
+
+// lessKey orders keys by Z, then Y, then X.
+func lessKey(a, b Key) bool {
+	if a.Z != b.Z {
+		return a.Z < b.Z
+	}
+	if a.Y != b.Y {
+		return a.Y < b.Y
+	}
+	return a.X < b.X
+}
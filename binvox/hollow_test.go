@@ -0,0 +1,84 @@
+package binvox
+
+import (
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+func solidCube(n int) *BinVOX {
+	b := New(n, n, n, 0, 0, 0, float64(n), false)
+	for z := 0; z < n; z++ {
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				b.Add(x, y, z)
+			}
+		}
+	}
+	return b
+}
+
+func TestHollow(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *HollowOptions
+		want    int
+		gone    []Key
+		wantErr bool
+	}{
+		{name: "no options", wantErr: true},
+		{name: "zero wall", opts: &HollowOptions{}, wantErr: true},
+		{
+			name: "2mm wall",
+			opts: &HollowOptions{WallThickness: 2},
+			want: 1000 - 6*6*6,
+			gone: []Key{{X: 2, Y: 2, Z: 2}, {X: 7, Y: 7, Z: 7}},
+		},
+		{
+			name: "fractional wall rounds up",
+			opts: &HollowOptions{WallThickness: 1.5},
+			want: 1000 - 6*6*6,
+		},
+		{
+			name: "auto drain hole",
+			opts: &HollowOptions{WallThickness: 2, DrainDiameter: 1},
+			want: 1000 - 6*6*6 - 2,
+			gone: []Key{{X: 2, Y: 2, Z: 1}, {X: 2, Y: 2, Z: 0}},
+		},
+		{
+			name: "explicit drain hole",
+			opts: &HollowOptions{WallThickness: 2, DrainDiameter: 1, DrainHoles: []gl.Vector{{X: 5.5, Y: 4.5, Z: 3.5}}},
+			want: 1000 - 6*6*6 - 2,
+			gone: []Key{{X: 5, Y: 4, Z: 1}, {X: 5, Y: 4, Z: 0}},
+		},
+		{
+			name: "lattice infill",
+			opts: &HollowOptions{WallThickness: 2, Lattice: func(p gl.Vector) bool { return p.X == 4.5 }},
+			want: 1000 - 6*6*6 + 6*6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := solidCube(10)
+			err := b.Hollow(tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Hollow = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Hollow: %v", err)
+			}
+			if got := len(b.WhiteVoxels); got != tt.want {
+				t.Errorf("Hollow left %v voxels, want %v", got, tt.want)
+			}
+			for _, k := range tt.gone {
+				if _, ok := b.WhiteVoxels[k]; ok {
+					t.Errorf("voxel %v still present, want removed", k)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package binvox
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// Lattice represents a kind of lattice infill.
+type Lattice int
+
+const (
+	// Cubic is a simple cubic lattice of axis-aligned square beams.
+	// See CubicLattice.
+	Cubic Lattice = iota
+
+	// Gyroid is a gyroid triply periodic minimal surface.
+	Gyroid
+
+	// SchwarzP is a Schwarz primitive triply periodic minimal surface.
+	SchwarzP
+
+	// Diamond is a Schwarz diamond triply periodic minimal surface.
+	Diamond
+)
+
+var latticeNames = []string{"cubic", "gyroid", "schwarzp", "diamond"}
+
+// String returns the name of the lattice, e.g. "gyroid".
+func (l Lattice) String() string {
+	if l < 0 || int(l) >= len(latticeNames) {
+		return fmt.Sprintf("Lattice(%d)", int(l))
+	}
+	return latticeNames[l]
+}
+
+// ParseLattice parses a lattice name such as "cubic" or "gyroid".
+func ParseLattice(s string) (Lattice, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, name := range latticeNames {
+		if s == name {
+			return Lattice(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown lattice %q", s)
+}
+
+// Func returns a LatticeFunc for the lattice with the given cell size
+// (the period of the lattice) and thickness (the beam width for Cubic
+// or the wall thickness of the surface for the others), both in
+// millimeters.
+func (l Lattice) Func(cellSize, thickness float64) (LatticeFunc, error) {
+	if cellSize <= 0 {
+		return nil, fmt.Errorf("lattice cell size must be positive: %v", cellSize)
+	}
+	if thickness <= 0 {
+		return nil, fmt.Errorf("lattice thickness must be positive: %v", thickness)
+	}
+	switch l {
+	case Cubic:
+		return CubicLattice(cellSize, thickness), nil
+	case Gyroid:
+		return GyroidLattice(cellSize, thickness), nil
+	case SchwarzP:
+		return SchwarzPLattice(cellSize, thickness), nil
+	case Diamond:
+		return DiamondLattice(cellSize, thickness), nil
+	}
+	return nil, fmt.Errorf("unknown lattice: %v", l)
+}
+
+// GyroidLattice returns a LatticeFunc representing a gyroid sheet with
+// the given cell size and wall thickness (both in millimeters).
+func GyroidLattice(cellSize, wallThickness float64) LatticeFunc {
+	return tpmsLattice(cellSize, wallThickness, func(x, y, z float64) (float64, gl.Vector) {
+		sx, cx := math.Sincos(x)
+		sy, cy := math.Sincos(y)
+		sz, cz := math.Sincos(z)
+		v := sx*cy + sy*cz + sz*cx
+		g := gl.V(cx*cy-sz*sx, cy*cz-sx*sy, cz*cx-sy*sz)
+		return v, g
+	})
+}
+
+// SchwarzPLattice returns a LatticeFunc representing a Schwarz primitive
+// sheet with the given cell size and wall thickness (both in millimeters).
+func SchwarzPLattice(cellSize, wallThickness float64) LatticeFunc {
+	return tpmsLattice(cellSize, wallThickness, func(x, y, z float64) (float64, gl.Vector) {
+		sx, cx := math.Sincos(x)
+		sy, cy := math.Sincos(y)
+		sz, cz := math.Sincos(z)
+		return cx + cy + cz, gl.V(-sx, -sy, -sz)
+	})
+}
+
+// DiamondLattice returns a LatticeFunc representing a Schwarz diamond
+// sheet with the given cell size and wall thickness (both in millimeters).
+func DiamondLattice(cellSize, wallThickness float64) LatticeFunc {
+	return tpmsLattice(cellSize, wallThickness, func(x, y, z float64) (float64, gl.Vector) {
+		sx, cx := math.Sincos(x)
+		sy, cy := math.Sincos(y)
+		sz, cz := math.Sincos(z)
+		v := sx*sy*sz + sx*cy*cz + cx*sy*cz + cx*cy*sz
+		g := gl.V(
+			cx*sy*sz+cx*cy*cz-sx*sy*cz-sx*cy*sz,
+			sx*cy*sz-sx*sy*cz+cx*cy*cz-cx*sy*sz,
+			sx*sy*cz-sx*cy*sz-cx*sy*sz+cx*cy*cz)
+		return v, g
+	})
+}
+
+// tpmsLattice returns a LatticeFunc representing a sheet of the given
+// wall thickness (in millimeters) centered on the zero level set of the
+// implicit function f, which has a period of 2*pi and returns its value
+// and gradient at (x,y,z).
+//
+// The distance to the surface is estimated by dividing the value of f
+// by the magnitude of its gradient, which keeps the wall thickness
+// uniform across the surface.
+func tpmsLattice(cellSize, wallThickness float64, f func(x, y, z float64) (float64, gl.Vector)) LatticeFunc {
+	k := 2 * math.Pi / cellSize
+	h := 0.5 * wallThickness
+	return func(p gl.Vector) bool {
+		v, g := f(k*p.X, k*p.Y, k*p.Z)
+		return math.Abs(v) <= h*k*g.Length()
+	}
+}
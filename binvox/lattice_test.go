@@ -0,0 +1,73 @@
+package binvox
+
+import (
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+func TestLattice(t *testing.T) {
+	const cell, thickness = 10.0, 1.0
+	tests := []struct {
+		lattice Lattice
+		on, off gl.Vector // points on the surface and far from it
+		minFill float64   // expected range of the volume fraction
+		maxFill float64
+	}{
+		{Gyroid, gl.V(0, 0, 0), gl.V(2.5, 0, 5), 0.25, 0.37},
+		{SchwarzP, gl.V(2.5, 2.5, 2.5), gl.V(0, 0, 0), 0.18, 0.29},
+		{Diamond, gl.V(0, 0, 0), gl.V(0, 0, 2.5), 0.32, 0.46},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lattice.String(), func(t *testing.T) {
+			f, err := tt.lattice.Func(cell, thickness)
+			if err != nil {
+				t.Fatalf("Func: %v", err)
+			}
+			if !f(tt.on) {
+				t.Errorf("f(%v) = false, want true", tt.on)
+			}
+			if f(tt.off) {
+				t.Errorf("f(%v) = true, want false", tt.off)
+			}
+
+			// The lattice is periodic in world space, so diced regions tile seamlessly.
+			var n, filled int
+			const steps = 20
+			for z := 0; z < steps; z++ {
+				for y := 0; y < steps; y++ {
+					for x := 0; x < steps; x++ {
+						p := gl.V(float64(x)+0.5, float64(y)+0.5, float64(z)+0.5).MulScalar(cell / steps)
+						got := f(p)
+						if shifted := f(p.Add(gl.V(-cell, 2*cell, 3*cell))); shifted != got {
+							t.Fatalf("f(%v) = %v, but shifted by whole cells = %v", p, got, shifted)
+						}
+						n++
+						if got {
+							filled++
+						}
+					}
+				}
+			}
+			if fill := float64(filled) / float64(n); fill < tt.minFill || fill > tt.maxFill {
+				t.Errorf("volume fraction = %v, want %v-%v", fill, tt.minFill, tt.maxFill)
+			}
+		})
+	}
+}
+
+func TestParseLattice(t *testing.T) {
+	for i, name := range latticeNames {
+		got, err := ParseLattice(name)
+		if err != nil || got != Lattice(i) {
+			t.Errorf("ParseLattice(%q) = %v, %v, want %v", name, got, err, Lattice(i))
+		}
+	}
+	if _, err := ParseLattice("octet"); err == nil {
+		t.Error("ParseLattice(octet) = nil error, want error")
+	}
+	if _, err := Gyroid.Func(0, 1); err == nil {
+		t.Error("Func(0, 1) = nil error, want error")
+	}
+}
@@ -64,7 +64,9 @@ func (b *BinVOX) ManifoldMesh() *gl.Mesh {
 		tris = append(tris, grid2tris(k, v, voxelToVector)...)
 	}
 
-	return gl.NewMesh(tris, nil)
+	mesh := gl.NewMesh(tris, nil)
+	b.untransform(mesh)
+	return mesh
 }
 
 func vlog(fmts string, args ...interface{}) {
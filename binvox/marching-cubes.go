@@ -60,7 +60,9 @@ func (b *BinVOX) MarchingCubes() *gl.Mesh {
 		tris = append(tris, polygonize(grid)...)
 	}
 
-	return gl.NewMesh(tris, nil)
+	mesh := gl.NewMesh(tris, nil)
+	b.untransform(mesh)
+	return mesh
 }
 
 func polygonize(grid *gridCell) (tris []*gl.Triangle) {
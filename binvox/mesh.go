@@ -7,17 +7,18 @@ import (
 )
 
 // ToMesh converts a BinVOX to a mesh.
+// The vertices of the mesh are colored by their voxels.
 func (b *BinVOX) ToMesh() *gl.Mesh {
 	log.Printf("Generating mesh for %v voxels...", len(b.WhiteVoxels)+len(b.ColorVoxels))
 	voxels := []gl.Voxel{}
-	keyFunc := func(v Key) {
-		voxels = append(voxels, gl.Voxel{X: v.X, Y: v.Y, Z: v.Z})
+	keyFunc := func(v Key, c Color) {
+		voxels = append(voxels, gl.Voxel{X: v.X, Y: v.Y, Z: v.Z, Color: gl.Color{R: c.R, G: c.G, B: c.B, A: c.A}})
 	}
 	for v := range b.WhiteVoxels {
-		keyFunc(v)
+		keyFunc(v, White)
 	}
-	for v := range b.ColorVoxels {
-		keyFunc(v)
+	for v, c := range b.ColorVoxels {
+		keyFunc(v, c)
 	}
 
 	mesh := gl.NewVoxelMesh(voxels)
@@ -42,8 +43,19 @@ func (b *BinVOX) ToMesh() *gl.Mesh {
 
 	m := gl.Identity().Translate(t1).Scale(s).Translate(t2)
 	mesh.Transform(m)
+	b.untransform(mesh)
 	mbb = mesh.BoundingBox()
 	log.Printf("Done moving mesh to MBB=%v", mbb)
 
 	return mesh
 }
+
+// untransform restores a mesh generated from the voxels to the original
+// placement of the model by applying the inverse of b.Transform (if any).
+func (b *BinVOX) untransform(mesh *gl.Mesh) {
+	if b.Transform == nil {
+		return
+	}
+	log.Println("Restoring the mesh to its original placement...")
+	mesh.Transform(b.Transform.Inverse())
+}
@@ -0,0 +1,88 @@
+package binvox
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// SavePLY writes the mesh as a binary PLY file, retaining the vertex colors.
+func SavePLY(filename string, mesh *gl.Mesh) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("unable to create file %q: %v", filename, err)
+	}
+	w := bufio.NewWriter(f)
+	if err := writePLY(w, mesh); err != nil {
+		f.Close()
+		return fmt.Errorf("SavePLY(%q): %v", filename, err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("SavePLY(%q): %v", filename, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close %q: %v", filename, err)
+	}
+	log.Printf("Done writing %v triangles to file %q.", len(mesh.Triangles), filename)
+	return nil
+}
+
+const plyHeaderFMT = `ply
+format binary_little_endian 1.0
+element vertex %v
+property float x
+property float y
+property float z
+property uchar red
+property uchar green
+property uchar blue
+element face %v
+property list uchar int vertex_indices
+end_header
+`
+
+// plyVertex is the binary representation of a colored PLY vertex.
+type plyVertex struct {
+	X, Y, Z float32
+	R, G, B uint8
+}
+
+// plyFace is the binary representation of a PLY triangle.
+type plyFace struct {
+	N          uint8
+	V1, V2, V3 int32
+}
+
+func writePLY(w io.Writer, mesh *gl.Mesh) error {
+	n := len(mesh.Triangles)
+	if _, err := fmt.Fprintf(w, plyHeaderFMT, 3*n, n); err != nil {
+		return err
+	}
+
+	toByte := func(v float64) uint8 { return uint8(math.Round(255 * gl.Clamp(v, 0, 1))) }
+	for _, t := range mesh.Triangles {
+		for _, v := range []gl.Vertex{t.V1, t.V2, t.V3} {
+			pv := plyVertex{
+				X: float32(v.Position.X), Y: float32(v.Position.Y), Z: float32(v.Position.Z),
+				R: toByte(v.Color.R), G: toByte(v.Color.G), B: toByte(v.Color.B),
+			}
+			if err := binary.Write(w, binary.LittleEndian, &pv); err != nil {
+				return err
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		f := plyFace{N: 3, V1: int32(3 * i), V2: int32(3*i + 1), V3: int32(3*i + 2)}
+		if err := binary.Write(w, binary.LittleEndian, &f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,10 +4,14 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
+
+	gl "github.com/fogleman/fauxgl"
 )
 
 var (
@@ -30,6 +34,9 @@ func Read(filename string, sx, sy, sz, nx, ny, nz int) (*BinVOX, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Read(%q): %v", filename, err)
 	}
+	if binVOX.Transform, err = readTransform(TransformFilename(filename)); err != nil {
+		return nil, fmt.Errorf("Read(%q): %v", filename, err)
+	}
 	log.Printf("Done loading %v voxels from file %q.", len(binVOX.WhiteVoxels), filename)
 	return binVOX, nil
 }
@@ -174,3 +181,43 @@ func read(r io.Reader, sx, sy, sz, cx, cy, cz int) (*BinVOX, error) {
 		WhiteVoxels: voxels,
 	}, nil
 }
+
+// readTransform reads the transform written by writeTransform, if any.
+func readTransform(filename string) (*gl.Matrix, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseMatrix(string(buf))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse transform %q: %v", filename, err)
+	}
+	return m, nil
+}
+
+// ParseMatrix parses 16 numbers (in row-major order) separated by
+// whitespace or commas as a matrix, such as the value of the stldice
+// -transform flag or the contents of a transform file (see
+// TransformFilename).
+func ParseMatrix(s string) (*gl.Matrix, error) {
+	fields := strings.Fields(strings.Replace(s, ",", " ", -1))
+	if len(fields) != 16 {
+		return nil, fmt.Errorf("expected 16 values, got %v", len(fields))
+	}
+	var v [16]float64
+	for i, f := range fields {
+		var err error
+		if v[i], err = strconv.ParseFloat(f, 64); err != nil {
+			return nil, fmt.Errorf("invalid number %v: %v", f, err)
+		}
+	}
+	return &gl.Matrix{
+		X00: v[0], X01: v[1], X02: v[2], X03: v[3],
+		X10: v[4], X11: v[5], X12: v[6], X13: v[7],
+		X20: v[8], X21: v[9], X22: v[10], X23: v[11],
+		X30: v[12], X31: v[13], X32: v[14], X33: v[15],
+	}, nil
+}
@@ -0,0 +1,257 @@
+package binvox
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// Filter selects how voxels are combined (when downsampling) or
+// interpolated (when upsampling) during resampling.
+type Filter int
+
+const (
+	// Majority keeps a voxel when more than half of the source voxels
+	// that it covers are solid. It can only be used for downsampling.
+	Majority Filter = iota
+
+	// Average keeps a voxel when any of the source voxels that it covers
+	// are solid, and records the fraction that are solid as the alpha
+	// (density) of a ColorVoxel. It can only be used for downsampling.
+	Average
+
+	// Nearest copies the source voxel containing each voxel center.
+	Nearest
+
+	// Trilinear interpolates the occupancy of the eight source voxels
+	// nearest to each voxel center, keeping the voxel when the result
+	// is at least one half. This rounds off the blocky corners of
+	// low resolution models when upsampling.
+	Trilinear
+)
+
+var filterNames = []string{"majority", "average", "nearest", "trilinear"}
+
+// String returns the name of the filter, e.g. "majority".
+func (f Filter) String() string {
+	if f < 0 || int(f) >= len(filterNames) {
+		return fmt.Sprintf("Filter(%d)", int(f))
+	}
+	return filterNames[f]
+}
+
+// ParseFilter parses a filter name such as "majority" or "trilinear".
+func ParseFilter(s string) (Filter, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, name := range filterNames {
+		if s == name {
+			return Filter(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown filter %q", s)
+}
+
+// Resample returns a copy of the model resampled to vpmm voxels per
+// millimeter using the given filter. The resampled model has the same
+// translation and covers (at least) the same region as b.
+func (b *BinVOX) Resample(vpmm float64, filter Filter) (*BinVOX, error) {
+	if vpmm <= 0 {
+		return nil, fmt.Errorf("voxels per millimeter must be positive: %v", vpmm)
+	}
+	r := vpmm / b.VoxelsPerMM()
+	nx := int(math.Ceil(float64(b.NX)*r - epsilon))
+	ny := int(math.Ceil(float64(b.NY)*r - epsilon))
+	nz := int(math.Ceil(float64(b.NZ)*r - epsilon))
+	dst := New(nx, ny, nz, b.TX, b.TY, b.TZ, 0, false)
+	dst.Scale = float64(dst.Dim()) / vpmm
+	dst.Transform = b.Transform
+	if err := b.resampleInto(dst, filter); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// ResampleDim returns a copy of the model resampled so that its largest
+// dimension has dim voxels. See Resample.
+func (b *BinVOX) ResampleDim(dim int, filter Filter) (*BinVOX, error) {
+	if dim <= 0 {
+		return nil, fmt.Errorf("dimension must be positive: %v", dim)
+	}
+	return b.Resample(float64(dim)/b.Scale, filter)
+}
+
+// ResampleOnto returns a copy of the model resampled onto the voxel grid
+// of another model, so that the result has the same dimensions,
+// translation and scale as grid and its keys can be used directly with
+// those of grid. Voxels of b that lie beyond the bounds of grid are
+// retained with keys beyond those bounds.
+func (b *BinVOX) ResampleOnto(grid *BinVOX, filter Filter) (*BinVOX, error) {
+	dst := New(grid.NX, grid.NY, grid.NZ, grid.TX, grid.TY, grid.TZ, grid.Scale, false)
+	dst.Transform = grid.Transform
+	if err := b.resampleInto(dst, filter); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// resampleInto resamples the voxels of b into the (empty) model dst.
+func (b *BinVOX) resampleInto(dst *BinVOX, filter Filter) error {
+	sv, dv := b.VoxelsPerMM(), dst.VoxelsPerMM()
+	log.Printf("Resampling %v from %v to %v voxels per millimeter using the %v filter...", b, sv, dv, filter)
+
+	color := b.WhiteVoxels == nil && b.ColorVoxels != nil
+	get := func(k Key) (Color, bool) {
+		if _, ok := b.WhiteVoxels[k]; ok {
+			return White, true
+		}
+		c, ok := b.ColorVoxels[k]
+		return c, ok
+	}
+	forEach := func(f func(k Key)) {
+		for k := range b.WhiteVoxels {
+			f(k)
+		}
+		for k := range b.ColorVoxels {
+			f(k)
+		}
+	}
+
+	// span returns the range of destination indices whose centers lie
+	// within [lo,hi) where lo and hi are source indices along one axis.
+	span := func(lo, hi, st, dt float64) (int, int) {
+		a := (st + lo/sv - dt) * dv
+		z := (st + hi/sv - dt) * dv
+		return int(math.Ceil(a - 0.5 - epsilon)), int(math.Ceil(z-0.5-epsilon)) - 1
+	}
+	// srcIndex returns the (fractional) source index of the center of
+	// destination index j along one axis.
+	srcIndex := func(j int, st, dt float64) float64 {
+		return (dt + (float64(j)+0.5)/dv - st) * sv
+	}
+
+	var result map[Key]Color
+	switch filter {
+	case Majority, Average:
+		if sv < dv-epsilon {
+			return fmt.Errorf("the %v filter can only be used for downsampling", filter)
+		}
+		type cell struct {
+			count   int
+			r, g, b float64
+		}
+		cells := map[Key]*cell{}
+		forEach(func(k Key) {
+			c, _ := get(k)
+			p := b.VoxelCenter(k)
+			dk := dst.VoxelAt(p)
+			v, ok := cells[dk]
+			if !ok {
+				v = &cell{}
+				cells[dk] = v
+			}
+			v.count++
+			v.r += c.R
+			v.g += c.G
+			v.b += c.B
+		})
+		full := math.Pow(sv/dv, 3) // number of source voxels covered by each voxel
+		result = map[Key]Color{}
+		for k, v := range cells {
+			n := float64(v.count)
+			c := Color{R: v.r / n, G: v.g / n, B: v.b / n, A: 1}
+			if filter == Average {
+				c.A = math.Min(1, n/full)
+				result[k] = c
+				continue
+			}
+			if n > 0.5*full {
+				result[k] = c
+			}
+		}
+		if filter == Average {
+			color = true
+		}
+
+	case Nearest:
+		result = map[Key]Color{}
+		forEach(func(k Key) {
+			c, _ := get(k)
+			x0, x1 := span(float64(k.X), float64(k.X+1), b.TX, dst.TX)
+			y0, y1 := span(float64(k.Y), float64(k.Y+1), b.TY, dst.TY)
+			z0, z1 := span(float64(k.Z), float64(k.Z+1), b.TZ, dst.TZ)
+			for z := z0; z <= z1; z++ {
+				for y := y0; y <= y1; y++ {
+					for x := x0; x <= x1; x++ {
+						result[Key{X: x, Y: y, Z: z}] = c
+					}
+				}
+			}
+		})
+
+	case Trilinear:
+		// Each source voxel influences the destination voxels whose
+		// centers lie within one source voxel of its center.
+		candidates := WhiteVoxelMap{}
+		forEach(func(k Key) {
+			x0, x1 := span(float64(k.X)-0.5, float64(k.X)+1.5, b.TX, dst.TX)
+			y0, y1 := span(float64(k.Y)-0.5, float64(k.Y)+1.5, b.TY, dst.TY)
+			z0, z1 := span(float64(k.Z)-0.5, float64(k.Z)+1.5, b.TZ, dst.TZ)
+			for z := z0; z <= z1; z++ {
+				for y := y0; y <= y1; y++ {
+					for x := x0; x <= x1; x++ {
+						candidates[Key{X: x, Y: y, Z: z}] = struct{}{}
+					}
+				}
+			}
+		})
+		result = map[Key]Color{}
+		for dk := range candidates {
+			u := gl.V(srcIndex(dk.X, b.TX, dst.TX)-0.5, srcIndex(dk.Y, b.TY, dst.TY)-0.5, srcIndex(dk.Z, b.TZ, dst.TZ)-0.5)
+			i0 := Key{X: int(math.Floor(u.X)), Y: int(math.Floor(u.Y)), Z: int(math.Floor(u.Z))}
+			f := gl.V(u.X-float64(i0.X), u.Y-float64(i0.Y), u.Z-float64(i0.Z))
+			var occupancy float64
+			var sum Color
+			for i := 0; i < 8; i++ {
+				k := Key{X: i0.X + i&1, Y: i0.Y + (i>>1)&1, Z: i0.Z + (i>>2)&1}
+				c, ok := get(k)
+				if !ok {
+					continue
+				}
+				w := weight(f.X, i&1) * weight(f.Y, (i>>1)&1) * weight(f.Z, (i>>2)&1)
+				occupancy += w
+				sum = Color{R: sum.R + w*c.R, G: sum.G + w*c.G, B: sum.B + w*c.B}
+			}
+			if occupancy >= 0.5-epsilon {
+				result[dk] = Color{R: sum.R / occupancy, G: sum.G / occupancy, B: sum.B / occupancy, A: 1}
+			}
+		}
+
+	default:
+		return fmt.Errorf("unknown filter: %v", filter)
+	}
+
+	if color {
+		dst.WhiteVoxels = nil
+		dst.ColorVoxels = ColorVoxelMap(result)
+	} else {
+		dst.WhiteVoxels = WhiteVoxelMap{}
+		dst.ColorVoxels = nil
+		for k := range result {
+			dst.WhiteVoxels[k] = struct{}{}
+		}
+	}
+	log.Printf("Done resampling; %v voxels.", len(result))
+	return nil
+}
+
+// weight returns the trilinear interpolation weight of the lower (i=0)
+// or upper (i=1) sample given the fractional offset f.
+func weight(f float64, i int) float64 {
+	if i == 0 {
+		return 1 - f
+	}
+	return f
+}
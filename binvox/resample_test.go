@@ -0,0 +1,94 @@
+package binvox
+
+import (
+	"testing"
+)
+
+func TestResample(t *testing.T) {
+	single := New(8, 8, 8, 0, 0, 0, 8, false)
+	single.Add(0, 0, 0)
+
+	tests := []struct {
+		name    string
+		b       *BinVOX
+		vpmm    float64
+		filter  Filter
+		wantN   int
+		want    int
+		alpha   float64
+		wantErr bool
+	}{
+		{name: "majority down", b: solidCube(8), vpmm: 0.5, filter: Majority, wantN: 4, want: 64},
+		{name: "majority drops lone voxel", b: single, vpmm: 0.5, filter: Majority, wantN: 4, want: 0},
+		{name: "majority up", b: solidCube(8), vpmm: 2, filter: Majority, wantErr: true},
+		{name: "average down", b: solidCube(8), vpmm: 0.5, filter: Average, wantN: 4, want: 64, alpha: 1},
+		{name: "average keeps lone voxel", b: single, vpmm: 0.5, filter: Average, wantN: 4, want: 1, alpha: 0.125},
+		{name: "average up", b: solidCube(8), vpmm: 2, filter: Average, wantErr: true},
+		{name: "nearest down", b: solidCube(8), vpmm: 0.5, filter: Nearest, wantN: 4, want: 64},
+		{name: "nearest up", b: solidCube(8), vpmm: 2, filter: Nearest, wantN: 16, want: 16 * 16 * 16},
+		{name: "trilinear up rounds corners", b: solidCube(8), vpmm: 2, filter: Trilinear, wantN: 16, want: 16*16*16 - 8},
+		{name: "bad vpmm", b: solidCube(8), vpmm: 0, filter: Nearest, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.b.Resample(tt.vpmm, tt.filter)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Resample: want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resample: %v", err)
+			}
+			if got.NX != tt.wantN || got.NY != tt.wantN || got.NZ != tt.wantN {
+				t.Errorf("Resample N = [%v,%v,%v], want %v", got.NX, got.NY, got.NZ, tt.wantN)
+			}
+			if v := got.VoxelsPerMM(); v != tt.vpmm {
+				t.Errorf("Resample VoxelsPerMM = %v, want %v", v, tt.vpmm)
+			}
+			if n := len(got.WhiteVoxels) + len(got.ColorVoxels); n != tt.want {
+				t.Errorf("Resample = %v voxels, want %v", n, tt.want)
+			}
+			for k, c := range got.ColorVoxels {
+				if c.A != tt.alpha {
+					t.Errorf("Resample voxel %v alpha = %v, want %v", k, c.A, tt.alpha)
+				}
+			}
+		})
+	}
+}
+
+func TestResampleOnto(t *testing.T) {
+	cut := solidCube(4)
+	grid := New(4, 4, 4, 1, 1, 1, 2, false)
+
+	got, err := cut.ResampleOnto(grid, Nearest)
+	if err != nil {
+		t.Fatalf("ResampleOnto: %v", err)
+	}
+	if got.NX != grid.NX || got.TX != grid.TX || got.Scale != grid.Scale {
+		t.Errorf("ResampleOnto = %v, want grid of %v", got, grid)
+	}
+	if len(got.WhiteVoxels) != 8*8*8 {
+		t.Errorf("ResampleOnto = %v voxels, want %v", len(got.WhiteVoxels), 8*8*8)
+	}
+	for _, k := range []Key{{X: -2, Y: -2, Z: -2}, {X: 5, Y: 5, Z: 5}} {
+		if _, ok := got.WhiteVoxels[k]; !ok {
+			t.Errorf("ResampleOnto missing voxel %v", k)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	for _, f := range []Filter{Majority, Average, Nearest, Trilinear} {
+		got, err := ParseFilter(f.String())
+		if err != nil || got != f {
+			t.Errorf("ParseFilter(%q) = %v, %v; want %v", f.String(), got, err, f)
+		}
+	}
+	if _, err := ParseFilter("bogus"); err == nil {
+		t.Error("ParseFilter(bogus): want error")
+	}
+}
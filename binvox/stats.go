@@ -0,0 +1,181 @@
+package binvox
+
+import (
+	"fmt"
+	"math"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// Stats represents the mass properties of a voxel model.
+//
+// Stats retains raw sums rather than final results so that the
+// statistics of the subregions of a diced model can be combined with
+// Add. Faces shared between neighboring subregions are cancelled when
+// combined, so the surface area of the combined model is correct.
+type Stats struct {
+	Voxels    int     // number of solid voxels
+	VoxelSize float64 // edge length of each voxel in millimeters
+	Density   float64 // density in grams per cubic centimeter
+	MBB       gl.Box  // minimum bounding box of the solid voxels in millimeters
+
+	faces int              // number of exposed voxel faces
+	seams map[Key]struct{} // exposed faces on the subregion boundary, in doubled world grid coordinates
+	sum   gl.Vector        // sum of voxel centers
+	sum2  [3][3]float64
+}
+
+// Stats returns the mass properties of the model for the given density
+// (in grams per cubic centimeter). Only voxels within the bounds of the
+// subregion are considered.
+func (b *BinVOX) Stats(density float64) *Stats {
+	vpmm := b.VoxelsPerMM()
+	s := &Stats{VoxelSize: 1 / vpmm, Density: density, seams: map[Key]struct{}{}}
+	// Location of the voxel grid origin in world grid coordinates.
+	ox := int(math.Round(b.TX * vpmm))
+	oy := int(math.Round(b.TY * vpmm))
+	oz := int(math.Round(b.TZ * vpmm))
+
+	inBounds := func(k Key) bool {
+		return k.X >= 0 && k.Y >= 0 && k.Z >= 0 && k.X < b.NX && k.Y < b.NY && k.Z < b.NZ
+	}
+	solid := func(k Key) bool {
+		if _, ok := b.WhiteVoxels[k]; ok {
+			return true
+		}
+		_, ok := b.ColorVoxels[k]
+		return ok
+	}
+
+	keyFunc := func(k Key) {
+		if !inBounds(k) {
+			return
+		}
+		c := b.VoxelCenter(k)
+		box := gl.Box{Min: c.SubScalar(0.5 * s.VoxelSize), Max: c.AddScalar(0.5 * s.VoxelSize)}
+		if s.Voxels == 0 {
+			s.MBB = box
+		} else {
+			s.MBB = s.MBB.Extend(box)
+		}
+		s.Voxels++
+		s.sum = s.sum.Add(c)
+		v := [3]float64{c.X, c.Y, c.Z}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				s.sum2[i][j] += v[i] * v[j]
+			}
+		}
+		for _, d := range neighbors6 {
+			n := Key{X: k.X + d.X, Y: k.Y + d.Y, Z: k.Z + d.Z}
+			if solid(n) && inBounds(n) {
+				continue
+			}
+			s.faces++
+			if !inBounds(n) {
+				face := Key{X: 2*(k.X+ox) + d.X, Y: 2*(k.Y+oy) + d.Y, Z: 2*(k.Z+oz) + d.Z}
+				s.seams[face] = struct{}{}
+			}
+		}
+	}
+	for k := range b.WhiteVoxels {
+		keyFunc(k)
+	}
+	for k := range b.ColorVoxels {
+		keyFunc(k)
+	}
+	return s
+}
+
+// Add combines the statistics of a neighboring subregion (sharing the
+// same voxel grid and density) into s.
+func (s *Stats) Add(o *Stats) error {
+	if math.Abs(s.VoxelSize-o.VoxelSize) > 1e-6*s.VoxelSize {
+		return fmt.Errorf("voxel size mismatch: %v != %v", s.VoxelSize, o.VoxelSize)
+	}
+	if s.Density != o.Density {
+		return fmt.Errorf("density mismatch: %v != %v", s.Density, o.Density)
+	}
+	if o.Voxels == 0 {
+		return nil
+	}
+	if s.Voxels == 0 {
+		s.MBB = o.MBB
+	} else {
+		s.MBB = s.MBB.Extend(o.MBB)
+	}
+	s.Voxels += o.Voxels
+	s.faces += o.faces
+	s.sum = s.sum.Add(o.sum)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			s.sum2[i][j] += o.sum2[i][j]
+		}
+	}
+	if s.seams == nil {
+		s.seams = map[Key]struct{}{}
+	}
+	for face := range o.seams {
+		if _, ok := s.seams[face]; ok {
+			// Both sides of this face are solid, so neither is exposed.
+			s.faces -= 2
+			delete(s.seams, face)
+			continue
+		}
+		s.seams[face] = struct{}{}
+	}
+	return nil
+}
+
+// Volume returns the volume of the model in cubic millimeters.
+func (s *Stats) Volume() float64 {
+	return float64(s.Voxels) * s.VoxelSize * s.VoxelSize * s.VoxelSize
+}
+
+// SurfaceArea returns the exposed surface area of the model in square millimeters.
+func (s *Stats) SurfaceArea() float64 {
+	return float64(s.faces) * s.VoxelSize * s.VoxelSize
+}
+
+// Mass returns the mass of the model in grams.
+func (s *Stats) Mass() float64 {
+	return s.Density * s.Volume() / 1000
+}
+
+// Centroid returns the center of mass of the model in millimeters.
+func (s *Stats) Centroid() gl.Vector {
+	if s.Voxels == 0 {
+		return gl.Vector{}
+	}
+	return s.sum.DivScalar(float64(s.Voxels))
+}
+
+// Inertia returns the inertia tensor of the model about its centroid
+// (in gram square millimeters). Each voxel is treated as a solid cube.
+func (s *Stats) Inertia() [3][3]float64 {
+	var result [3][3]float64
+	if s.Voxels == 0 {
+		return result
+	}
+	n := float64(s.Voxels)
+	m := s.Mass() / n // mass of each voxel
+	c := s.Centroid()
+	cv := [3]float64{c.X, c.Y, c.Z}
+
+	// Second moments about the centroid.
+	var cov [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			cov[i][j] = s.sum2[i][j] - n*cv[i]*cv[j]
+		}
+	}
+	trace := cov[0][0] + cov[1][1] + cov[2][2]
+	cube := s.VoxelSize * s.VoxelSize / 6 // inertia of a unit mass cube about its center
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			result[i][j] = -m * cov[i][j]
+		}
+		result[i][i] += m*trace + s.Mass()*cube
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package binvox
+
+import (
+	"math"
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+func TestStats(t *testing.T) {
+	approx := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	tests := []struct {
+		name    string
+		regions []*BinVOX
+	}{
+		{name: "whole", regions: []*BinVOX{solidCube(10)}},
+		{name: "diced", regions: dice(solidCube(10))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.regions[0].Stats(1.25)
+			for _, r := range tt.regions[1:] {
+				if err := got.Add(r.Stats(1.25)); err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+			}
+
+			if got.Voxels != 1000 {
+				t.Errorf("Voxels = %v, want 1000", got.Voxels)
+			}
+			if v := got.Volume(); !approx(v, 1000) {
+				t.Errorf("Volume = %v, want 1000", v)
+			}
+			if v := got.SurfaceArea(); !approx(v, 600) {
+				t.Errorf("SurfaceArea = %v, want 600", v)
+			}
+			if v := got.Mass(); !approx(v, 1.25) {
+				t.Errorf("Mass = %v, want 1.25", v)
+			}
+			if v, want := got.Centroid(), gl.V(5, 5, 5); v.Sub(want).Length() > 1e-9 {
+				t.Errorf("Centroid = %v, want %v", v, want)
+			}
+			if want := (gl.Box{Max: gl.V(10, 10, 10)}); got.MBB != want {
+				t.Errorf("MBB = %v, want %v", got.MBB, want)
+			}
+
+			// A solid cube of mass m and side a has I = m*a*a/6 about each axis.
+			inertia := got.Inertia()
+			for i := 0; i < 3; i++ {
+				for j := 0; j < 3; j++ {
+					want := 0.0
+					if i == j {
+						want = 1.25 * 100 / 6
+					}
+					if !approx(inertia[i][j], want) {
+						t.Errorf("Inertia[%v][%v] = %v, want %v", i, j, inertia[i][j], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStatsAddMismatch(t *testing.T) {
+	s := solidCube(10).Stats(1)
+	if err := s.Add(solidCube(10).Stats(2)); err == nil {
+		t.Error("Add with different density: want error")
+	}
+	if err := s.Add(solidCube(5).Stats(1)); err != nil {
+		t.Errorf("Add with same voxel size: %v", err)
+	}
+}
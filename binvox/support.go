@@ -0,0 +1,295 @@
+package binvox
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Axis represents a build direction along one of the voxel grid axes.
+type Axis int
+
+const (
+	PosZ Axis = iota // +Z is up (the default)
+	NegZ             // -Z is up
+	PosX             // +X is up
+	NegX             // -X is up
+	PosY             // +Y is up
+	NegY             // -Y is up
+)
+
+var axisNames = []string{"+z", "-z", "+x", "-x", "+y", "-y"}
+
+// String returns the name of the axis, e.g. "+z".
+func (a Axis) String() string {
+	if a < 0 || int(a) >= len(axisNames) {
+		return fmt.Sprintf("Axis(%d)", int(a))
+	}
+	return axisNames[a]
+}
+
+// ParseAxis parses an axis name such as "+z", "z" or "-X".
+func ParseAxis(s string) (Axis, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) == 1 {
+		s = "+" + s
+	}
+	for i, name := range axisNames {
+		if s == name {
+			return Axis(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown axis %q", s)
+}
+
+// toBuild maps a key into the build frame where the build direction is +Z.
+func (a Axis) toBuild(k Key) Key {
+	switch a {
+	case NegZ:
+		return Key{X: k.X, Y: -k.Y, Z: -k.Z}
+	case PosX:
+		return Key{X: k.Y, Y: k.Z, Z: k.X}
+	case NegX:
+		return Key{X: k.Y, Y: -k.Z, Z: -k.X}
+	case PosY:
+		return Key{X: k.Z, Y: k.X, Z: k.Y}
+	case NegY:
+		return Key{X: k.Z, Y: -k.X, Z: -k.Y}
+	}
+	return k
+}
+
+// fromBuild maps a key from the build frame back into the model frame.
+func (a Axis) fromBuild(k Key) Key {
+	switch a {
+	case NegZ:
+		return Key{X: k.X, Y: -k.Y, Z: -k.Z}
+	case PosX:
+		return Key{X: k.Z, Y: k.X, Z: k.Y}
+	case NegX:
+		return Key{X: -k.Z, Y: k.X, Z: -k.Y}
+	case PosY:
+		return Key{X: k.Y, Y: k.Z, Z: k.X}
+	case NegY:
+		return Key{X: -k.Y, Y: -k.Z, Z: k.X}
+	}
+	return k
+}
+
+// layers holds the voxels of a model in the build frame, one map per layer.
+type layers map[int]map[[2]int]struct{}
+
+func (b *BinVOX) layers(axis Axis) (result layers, minZ, maxZ int) {
+	result = layers{}
+	minZ, maxZ = math.MaxInt32, math.MinInt32
+	keyFunc := func(k Key) {
+		k = axis.toBuild(k)
+		layer, ok := result[k.Z]
+		if !ok {
+			layer = map[[2]int]struct{}{}
+			result[k.Z] = layer
+		}
+		layer[[2]int{k.X, k.Y}] = struct{}{}
+		if k.Z < minZ {
+			minZ = k.Z
+		}
+		if k.Z > maxZ {
+			maxZ = k.Z
+		}
+	}
+	for k := range b.WhiteVoxels {
+		keyFunc(k)
+	}
+	for k := range b.ColorVoxels {
+		keyFunc(k)
+	}
+	return result, minZ, maxZ
+}
+
+// Overhangs returns the voxels of the model that need support when the
+// model is built in the given direction. A voxel needs support when the
+// layer below it has no voxels within the cone of maxAngle degrees
+// (measured from the build direction) beneath it. Voxels in the lowest
+// layer of the model rest on the build plate and never need support.
+func (b *BinVOX) Overhangs(axis Axis, maxAngle float64) (WhiteVoxelMap, error) {
+	if maxAngle < 0 || maxAngle >= 90 {
+		return nil, fmt.Errorf("overhang angle must be in the range [0,90): %v", maxAngle)
+	}
+	r := math.Tan(maxAngle * math.Pi / 180)
+	ri := int(math.Floor(r + epsilon))
+
+	model, minZ, maxZ := b.layers(axis)
+	log.Printf("Finding overhangs of %v built in the %v direction with a %v degree cone...", b, axis, maxAngle)
+
+	var mu sync.Mutex // protects result
+	result := WhiteVoxelMap{}
+	var wg sync.WaitGroup
+	for z := minZ + 1; z <= maxZ; z++ {
+		wg.Add(1)
+		go func(z int) {
+			below := model[z-1]
+			for p := range model[z] {
+				supported := false
+				for dy := -ri; dy <= ri && !supported; dy++ {
+					for dx := -ri; dx <= ri; dx++ {
+						if float64(dx*dx+dy*dy) > r*r+epsilon {
+							continue
+						}
+						if _, ok := below[[2]int{p[0] + dx, p[1] + dy}]; ok {
+							supported = true
+							break
+						}
+					}
+				}
+				if !supported {
+					mu.Lock()
+					result[axis.fromBuild(Key{X: p[0], Y: p[1], Z: z})] = struct{}{}
+					mu.Unlock()
+				}
+			}
+			wg.Done()
+		}(z)
+	}
+	wg.Wait()
+
+	log.Printf("Found %v overhanging voxels.", len(result))
+	return result, nil
+}
+
+// SupportOptions controls the generation of support structures.
+type SupportOptions struct {
+	// Axis is the build direction.
+	Axis Axis
+
+	// MaxAngle is the maximum self-supporting overhang angle in degrees,
+	// measured from the build direction.
+	MaxAngle float64
+
+	// Spacing is the distance between support pillars in voxels.
+	// Each overhanging voxel is supported by the nearest pillar on a
+	// grid with this spacing. Zero or one supports every voxel.
+	Spacing int
+
+	// PillarWidth is the width of each square pillar in voxels (default 1).
+	PillarWidth int
+}
+
+// Supports returns a support volume for the model made of simple vertical
+// pillars that run from beneath each overhang down to either the build
+// plate or to the model itself. A column with several overhangs stacked
+// above each other gets a pillar beneath each of them. A pillar whose
+// top lies within the model (which can happen when the overhang is
+// snapped to a column of the Spacing grid) starts beneath the model
+// instead. Pillars at the edges of the voxel grid are moved inward so
+// that they lie entirely within it. The result shares the voxel grid of
+// b and can be combined with it by using Union.
+func (b *BinVOX) Supports(opts *SupportOptions) (*BinVOX, error) {
+	if opts == nil {
+		opts = &SupportOptions{MaxAngle: 45}
+	}
+	overhangs, err := b.Overhangs(opts.Axis, opts.MaxAngle)
+	if err != nil {
+		return nil, err
+	}
+	spacing := opts.Spacing
+	if spacing < 1 {
+		spacing = 1
+	}
+	width := opts.PillarWidth
+	if width < 1 {
+		width = 1
+	}
+
+	// The bounds of the voxel grid in the build frame.
+	gridMin, gridMax := opts.Axis.toBuild(Key{}), opts.Axis.toBuild(Key{X: b.NX - 1, Y: b.NY - 1, Z: b.NZ - 1})
+	if gridMin.X > gridMax.X {
+		gridMin.X, gridMax.X = gridMax.X, gridMin.X
+	}
+	if gridMin.Y > gridMax.Y {
+		gridMin.Y, gridMax.Y = gridMax.Y, gridMin.Y
+	}
+	clamp := func(v, min, max int) int {
+		if v > max {
+			v = max
+		}
+		if v < min {
+			v = min
+		}
+		return v
+	}
+	snap := func(v, min, max int) int {
+		return clamp(spacing*int(math.Floor(float64(v)/float64(spacing)+0.5)), min, max)
+	}
+	// footprint returns the first and last rows (or columns) of a pillar
+	// centered on v, moved inward to lie within [min,max].
+	lo := -(width - 1) / 2
+	footprint := func(v, min, max int) (int, int) {
+		first := clamp(v+lo, min, max-width+1)
+		last := first + width - 1
+		if first < min {
+			first = min
+		}
+		if last > max {
+			last = max
+		}
+		return first, last
+	}
+
+	// Find the tops of the pillars of each column.
+	type column [2]int
+	tops := map[column]map[int]struct{}{}
+	highest := map[column]int{}
+	for k := range overhangs {
+		k = opts.Axis.toBuild(k)
+		c := column{snap(k.X, gridMin.X, gridMax.X), snap(k.Y, gridMin.Y, gridMax.Y)}
+		if _, ok := tops[c]; !ok {
+			tops[c] = map[int]struct{}{}
+			highest[c] = k.Z - 1
+		}
+		tops[c][k.Z-1] = struct{}{}
+		if k.Z-1 > highest[c] {
+			highest[c] = k.Z - 1
+		}
+	}
+
+	model, minZ, _ := b.layers(opts.Axis)
+	result := New(b.NX, b.NY, b.NZ, b.TX, b.TY, b.TZ, b.Scale, false)
+	result.Transform = b.Transform
+	var pillars int
+	for c, top := range highest {
+		x0, x1 := footprint(c[0], gridMin.X, gridMax.X)
+		y0, y1 := footprint(c[1], gridMin.Y, gridMax.Y)
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				p := [2]int{x, y}
+				// Walk down the column, starting a pillar at each top
+				// and ending it when it rests on the model.
+				var needed, running bool
+				for z := top; z >= minZ; z-- {
+					if _, ok := tops[c][z]; ok {
+						needed = true
+					}
+					if _, ok := model[z][p]; ok {
+						if running {
+							needed, running = false, false // resting on the model
+						}
+						continue
+					}
+					if !needed {
+						continue
+					}
+					if !running && p == c {
+						pillars++
+					}
+					running = true
+					result.WhiteVoxels[opts.Axis.fromBuild(Key{X: p[0], Y: p[1], Z: z})] = struct{}{}
+				}
+			}
+		}
+	}
+
+	log.Printf("Generated %v pillars with %v support voxels.", pillars, len(result.WhiteVoxels))
+	return result, nil
+}
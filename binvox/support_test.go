@@ -0,0 +1,203 @@
+package binvox
+
+import (
+	"reflect"
+	"testing"
+)
+
+// table returns an "L" shaped model: a post at x=0 with an arm along +X on top.
+func table() *BinVOX {
+	b := New(5, 1, 5, 0, 0, 0, 5, false)
+	for z := 0; z < 5; z++ {
+		b.Add(0, 0, z)
+	}
+	for x := 1; x < 5; x++ {
+		b.Add(x, 0, 4)
+	}
+	return b
+}
+
+func TestOverhangs(t *testing.T) {
+	tests := []struct {
+		name  string
+		axis  Axis
+		angle float64
+		want  WhiteVoxelMap
+	}{
+		{
+			name: "vertical",
+			want: WhiteVoxelMap{{X: 1, Z: 4}: {}, {X: 2, Z: 4}: {}, {X: 3, Z: 4}: {}, {X: 4, Z: 4}: {}},
+		},
+		{
+			name:  "45 degrees",
+			angle: 45,
+			want:  WhiteVoxelMap{{X: 2, Z: 4}: {}, {X: 3, Z: 4}: {}, {X: 4, Z: 4}: {}},
+		},
+		{
+			name: "upside down",
+			axis: NegZ,
+			want: WhiteVoxelMap{},
+		},
+		{
+			name: "sideways",
+			axis: NegX,
+			want: WhiteVoxelMap{{Z: 0}: {}, {Z: 1}: {}, {Z: 2}: {}, {Z: 3}: {}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := table().Overhangs(tt.axis, tt.angle)
+			if err != nil {
+				t.Fatalf("Overhangs: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Overhangs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSupports(t *testing.T) {
+	b := table()
+	got, err := b.Supports(&SupportOptions{})
+	if err != nil {
+		t.Fatalf("Supports: %v", err)
+	}
+	want := WhiteVoxelMap{}
+	for x := 1; x < 5; x++ {
+		for z := 0; z < 4; z++ {
+			want[Key{X: x, Z: z}] = struct{}{}
+		}
+	}
+	if !reflect.DeepEqual(got.WhiteVoxels, want) {
+		t.Errorf("Supports = %v, want %v", got.WhiteVoxels, want)
+	}
+
+	if err := b.Union(got); err != nil {
+		t.Fatalf("Union: %v", err)
+	}
+	if overhangs, _ := b.Overhangs(PosZ, 0); len(overhangs) != 0 {
+		t.Errorf("supported model still has overhangs: %v", overhangs)
+	}
+}
+
+// shelves returns a post at x=0 with an arm along +X on top and a
+// shorter shelf halfway up, so the arm overhangs the shelf.
+func shelves() *BinVOX {
+	b := New(5, 1, 9, 0, 0, 0, 9, false)
+	for z := 0; z < 9; z++ {
+		b.Add(0, 0, z)
+	}
+	for x := 1; x < 5; x++ {
+		b.Add(x, 0, 8)
+	}
+	for x := 1; x < 3; x++ {
+		b.Add(x, 0, 4)
+	}
+	return b
+}
+
+// pillars returns the voxels of the pillars at y=0 with the given x
+// from z0 down to z1 (inclusive).
+func pillars(m WhiteVoxelMap, z0, z1 int, xs ...int) WhiteVoxelMap {
+	for _, x := range xs {
+		for z := z1; z <= z0; z++ {
+			m[Key{X: x, Z: z}] = struct{}{}
+		}
+	}
+	return m
+}
+
+func TestSupportsColumns(t *testing.T) {
+	// A block hangs beneath the middle of an arm, so the column at x=2
+	// (which the arm at x=1 snaps to) starts within the block.
+	hanging := table()
+	hanging.Add(2, 0, 3)
+	hanging.Add(2, 0, 2)
+
+	// An arm reaches the edge of the grid at x=6, which snaps to x=8.
+	edge := New(7, 1, 4, 0, 0, 0, 7, false)
+	for z := 0; z < 4; z++ {
+		edge.Add(0, 0, z)
+	}
+	for x := 1; x < 7; x++ {
+		edge.Add(x, 0, 3)
+	}
+
+	tests := []struct {
+		name    string
+		b       *BinVOX
+		spacing int
+		width   int
+		want    WhiteVoxelMap
+	}{
+		{
+			name: "stacked overhangs",
+			b:    shelves(),
+			want: pillars(pillars(pillars(WhiteVoxelMap{}, 7, 5, 1, 2), 3, 0, 1, 2), 7, 0, 3, 4),
+		},
+		{
+			name:    "snapped into model",
+			b:       hanging,
+			spacing: 2,
+			want:    pillars(pillars(WhiteVoxelMap{}, 1, 0, 2), 3, 0, 4),
+		},
+		{
+			name:    "snapped beyond the edge",
+			b:       edge,
+			spacing: 4,
+			want:    pillars(WhiteVoxelMap{}, 2, 0, 4, 6),
+		},
+		{
+			name:    "wide pillar at the edge",
+			b:       edge,
+			spacing: 4,
+			width:   3,
+			want:    pillars(WhiteVoxelMap{}, 2, 0, 1, 2, 3, 4, 5, 6),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.b.Supports(&SupportOptions{Spacing: tt.spacing, PillarWidth: tt.width})
+			if err != nil {
+				t.Fatalf("Supports: %v", err)
+			}
+			if !reflect.DeepEqual(got.WhiteVoxels, tt.want) {
+				t.Errorf("Supports = %v, want %v", got.WhiteVoxels, tt.want)
+			}
+		})
+	}
+
+	// The pillars support both overhangs.
+	b := shelves()
+	got, err := b.Supports(&SupportOptions{})
+	if err != nil {
+		t.Fatalf("Supports: %v", err)
+	}
+	if err := b.Union(got); err != nil {
+		t.Fatalf("Union: %v", err)
+	}
+	if overhangs, _ := b.Overhangs(PosZ, 0); len(overhangs) != 0 {
+		t.Errorf("supported model still has overhangs: %v", overhangs)
+	}
+}
+
+func TestAxis(t *testing.T) {
+	k := Key{X: 1, Y: 2, Z: 3}
+	for _, name := range []string{"+z", "-z", "+x", "-x", "+y", "-y"} {
+		a, err := ParseAxis(name)
+		if err != nil {
+			t.Fatalf("ParseAxis(%q): %v", name, err)
+		}
+		if a.String() != name {
+			t.Errorf("ParseAxis(%q).String() = %q", name, a)
+		}
+		if got := a.fromBuild(a.toBuild(k)); got != k {
+			t.Errorf("%v: fromBuild(toBuild(%v)) = %v", a, k, got)
+		}
+	}
+	if _, err := ParseAxis("w"); err == nil {
+		t.Error("ParseAxis(\"w\") = nil, want error")
+	}
+}
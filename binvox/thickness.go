@@ -0,0 +1,184 @@
+package binvox
+
+import (
+	"log"
+	"math"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// ThicknessMap maps each voxel of a model to its local wall thickness (in millimeters).
+type ThicknessMap map[Key]float64
+
+// Thickness computes the local wall thickness of every voxel in the model.
+// The thickness of a voxel is the diameter of the largest sphere that
+// contains the voxel and is inscribed within the model (see Hildebrand
+// and Rüegsegger, "A new method for the model-independent assessment of
+// thickness in three-dimensional images").
+//
+// Since spheres are centered on voxel centers, the thickness is accurate
+// to within one voxel: walls that are an even number of voxels thick are
+// reported as one voxel thinner, erring on the side of caution.
+//
+// Voxels present beyond the bounds of the subregion (see WithHalo) are
+// taken into account but are not included in the results. All space
+// beyond the farthest of them is treated as exterior, so the halo of a
+// diced subregion should be at least as wide as its thickest walls.
+func (b *BinVOX) Thickness() (ThicknessMap, error) {
+	halo := b.haloWidth()
+	d, err := b.SignedDistanceField(halo)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Computing wall thickness of %v...", b)
+
+	// r2 holds the squared distance (in voxels) from each solid voxel
+	// center to the nearest empty voxel center.
+	r2 := make([]float64, len(d.Data))
+	for i, v := range d.Data {
+		if v < 0 {
+			r := -float64(v)/d.VoxelSize + 0.5
+			r2[i] = r * r
+		}
+	}
+	index := func(x, y, z int) int { return x + d.NX*(y+d.NY*z) }
+
+	// Only the spheres centered on the medial axis need to be considered;
+	// all others are contained within the sphere of a neighbor.
+	var centers []int
+	for z := 0; z < d.NZ; z++ {
+		for y := 0; y < d.NY; y++ {
+			for x := 0; x < d.NX; x++ {
+				i := index(x, y, z)
+				if r2[i] == 0 {
+					continue
+				}
+				r := math.Sqrt(r2[i])
+				contained := false
+				forEachNeighbor26(Key{X: x, Y: y, Z: z}, func(n Key) {
+					if contained || n.X < 0 || n.Y < 0 || n.Z < 0 || n.X >= d.NX || n.Y >= d.NY || n.Z >= d.NZ {
+						return
+					}
+					dx, dy, dz := float64(n.X-x), float64(n.Y-y), float64(n.Z-z)
+					if math.Sqrt(r2[index(n.X, n.Y, n.Z)]) >= r+math.Sqrt(dx*dx+dy*dy+dz*dz) {
+						contained = true
+					}
+				})
+				if !contained {
+					centers = append(centers, i)
+				}
+			}
+		}
+	}
+	thickness := make([]float64, len(d.Data))
+	for _, i := range centers {
+		cx, cy, cz := i%d.NX, (i/d.NX)%d.NY, i/(d.NX*d.NY)
+		diameter := 2 * (math.Sqrt(r2[i]) - 0.5) * d.VoxelSize
+		ri := int(math.Ceil(math.Sqrt(r2[i])))
+		for z := cz - ri; z <= cz+ri; z++ {
+			for y := cy - ri; y <= cy+ri; y++ {
+				for x := cx - ri; x <= cx+ri; x++ {
+					if x < 0 || y < 0 || z < 0 || x >= d.NX || y >= d.NY || z >= d.NZ {
+						continue
+					}
+					dx, dy, dz := float64(x-cx), float64(y-cy), float64(z-cz)
+					if dx*dx+dy*dy+dz*dz >= r2[i] {
+						continue
+					}
+					if j := index(x, y, z); thickness[j] < diameter {
+						thickness[j] = diameter
+					}
+				}
+			}
+		}
+	}
+
+	result := ThicknessMap{}
+	keyFunc := func(k Key) {
+		if k.X < 0 || k.Y < 0 || k.Z < 0 || k.X >= b.NX || k.Y >= b.NY || k.Z >= b.NZ {
+			return
+		}
+		result[k] = thickness[index(k.X+halo, k.Y+halo, k.Z+halo)]
+	}
+	for k := range b.WhiteVoxels {
+		keyFunc(k)
+	}
+	for k := range b.ColorVoxels {
+		keyFunc(k)
+	}
+	log.Printf("Done computing wall thickness of %v voxels.", len(result))
+	return result, nil
+}
+
+// haloWidth returns the number of voxels by which the voxels of b extend
+// beyond its bounds (see WithHalo).
+func (b *BinVOX) haloWidth() int {
+	var halo int
+	keyFunc := func(k Key) {
+		for _, beyond := range []int{-k.X, -k.Y, -k.Z, k.X - b.NX + 1, k.Y - b.NY + 1, k.Z - b.NZ + 1} {
+			if beyond > halo {
+				halo = beyond
+			}
+		}
+	}
+	for k := range b.WhiteVoxels {
+		keyFunc(k)
+	}
+	for k := range b.ColorVoxels {
+		keyFunc(k)
+	}
+	return halo
+}
+
+// ThinRegion represents a connected region of the model whose walls are
+// thinner than the requested minimum.
+type ThinRegion struct {
+	Voxels       []Key   // voxels within the region
+	MinThickness float64 // thinnest wall (in millimeters) within the region
+	MBB          gl.Box  // minimum bounding box of the region in millimeters
+}
+
+// ThinRegions returns the connected regions of voxels whose thickness
+// is less than minThickness millimeters, ordered by location.
+func (b *BinVOX) ThinRegions(t ThicknessMap, minThickness float64) []*ThinRegion {
+	thin := WhiteVoxelMap{}
+	for k, v := range t {
+		if v < minThickness {
+			thin[k] = struct{}{}
+		}
+	}
+
+	var result []*ThinRegion
+	s := 1.0 / b.VoxelsPerMM()
+	for _, c := range thin.components() {
+		r := &ThinRegion{Voxels: c, MinThickness: math.Inf(1)}
+		for i, k := range c {
+			r.MinThickness = math.Min(r.MinThickness, t[k])
+			min := gl.V(b.TX+s*float64(k.X), b.TY+s*float64(k.Y), b.TZ+s*float64(k.Z))
+			box := gl.Box{Min: min, Max: min.AddScalar(s)}
+			if i == 0 {
+				r.MBB = box
+			} else {
+				r.MBB = r.MBB.Extend(box)
+			}
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// Highlight returns a copy of the model using ColorVoxels where every
+// voxel thinner than minThickness millimeters is colored thin and all
+// other voxels are White.
+func (b *BinVOX) Highlight(t ThicknessMap, minThickness float64, thin Color) *BinVOX {
+	result := New(b.NX, b.NY, b.NZ, b.TX, b.TY, b.TZ, b.Scale, true)
+	result.Transform = b.Transform
+	for k, v := range t {
+		c := White
+		if v < minThickness {
+			c = thin
+		}
+		result.ColorVoxels[k] = c
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package binvox
+
+import (
+	"testing"
+)
+
+func TestThickness(t *testing.T) {
+	// A 3mm thick slab with a 1mm thick fin standing on top of it.
+	b := New(10, 10, 8, 0, 0, 0, 10, false)
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			for z := 0; z < 3; z++ {
+				b.Add(x, y, z)
+			}
+			if x == 4 {
+				for z := 3; z < 8; z++ {
+					b.Add(x, y, z)
+				}
+			}
+		}
+	}
+
+	got, err := b.Thickness()
+	if err != nil {
+		t.Fatalf("Thickness: %v", err)
+	}
+	if len(got) != len(b.WhiteVoxels) {
+		t.Fatalf("Thickness returned %v voxels, want %v", len(got), len(b.WhiteVoxels))
+	}
+	for k, v := range got {
+		want := 3.0
+		switch {
+		case k.Z == 3: // where the fin meets the slab
+			continue
+		case k.Z > 3:
+			want = 1
+		}
+		if v != want {
+			t.Errorf("thickness at %v = %v, want %v", k, v, want)
+		}
+	}
+
+	regions := b.ThinRegions(got, 2)
+	if len(regions) != 1 {
+		t.Fatalf("ThinRegions returned %v regions, want 1", len(regions))
+	}
+	r := regions[0]
+	if len(r.Voxels) != 10*5 || r.MinThickness != 1 {
+		t.Errorf("ThinRegions = {%v voxels, min %v}, want {50 voxels, min 1}", len(r.Voxels), r.MinThickness)
+	}
+	if r.MBB.Min.X != 4 || r.MBB.Max.X != 5 || r.MBB.Min.Z != 3 || r.MBB.Max.Z != 8 {
+		t.Errorf("ThinRegions MBB = %v, want (4,0,3)-(5,10,8)", r.MBB)
+	}
+
+	h := b.Highlight(got, 2, Color{R: 1, A: 1})
+	if c, _ := h.Get(4, 0, 7); c != (Color{R: 1, A: 1}) {
+		t.Errorf("Highlight color of fin = %v, want red", c)
+	}
+	if c, _ := h.Get(0, 0, 0); c != White {
+		t.Errorf("Highlight color of slab = %v, want white", c)
+	}
+}
+
+func TestThicknessHalo(t *testing.T) {
+	// A 6mm thick slab diced into two 3mm thick subregions.
+	whole := New(10, 10, 6, 0, 0, 0, 10, false)
+	lower := New(10, 10, 3, 0, 0, 0, 10, false)
+	upper := New(10, 10, 3, 0, 0, 3, 10, false)
+	for z := 0; z < 6; z++ {
+		for y := 0; y < 10; y++ {
+			for x := 0; x < 10; x++ {
+				whole.Add(x, y, z)
+				if z < 3 {
+					lower.Add(x, y, z)
+				} else {
+					upper.Add(x, y, z-3)
+				}
+			}
+		}
+	}
+	want, err := whole.Thickness()
+	if err != nil {
+		t.Fatalf("Thickness: %v", err)
+	}
+	lower, err = lower.WithHalo(3, upper)
+	if err != nil {
+		t.Fatalf("WithHalo: %v", err)
+	}
+	got, err := lower.Thickness()
+	if err != nil {
+		t.Fatalf("Thickness: %v", err)
+	}
+	if len(got) != 10*10*3 {
+		t.Fatalf("Thickness returned %v voxels, want %v", len(got), 10*10*3)
+	}
+	for k, v := range got {
+		if v != want[k] {
+			t.Errorf("thickness of %v = %v, want %v", k, v, want[k])
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package binvox
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// All transforms are performed about the world space origin (rather than
+// about the subregion) so that the subregions of a diced model that are
+// transformed identically still tile seamlessly.
+
+// intMatrix is a signed permutation matrix used for lossless transforms.
+type intMatrix [3][3]int
+
+// posAxes are the positive axes indexed by axisVector.
+var posAxes = []Axis{PosX, PosY, PosZ}
+
+// axisVector returns the index (0=X, 1=Y, 2=Z) and sign of the axis.
+func (a Axis) axisVector() (index, sign int) {
+	switch a {
+	case PosX:
+		return 0, 1
+	case NegX:
+		return 0, -1
+	case PosY:
+		return 1, 1
+	case NegY:
+		return 1, -1
+	case NegZ:
+		return 2, -1
+	}
+	return 2, 1
+}
+
+// Rotate90 rotates the model by turns quarter turns about the given axis
+// using the right-hand rule (i.e. counterclockwise when looking down the
+// axis towards the origin). Negative turns rotate clockwise.
+func (b *BinVOX) Rotate90(axis Axis, turns int) {
+	index, sign := axis.axisVector()
+	turns = ((sign*turns)%4 + 4) % 4
+	if turns == 0 {
+		return
+	}
+	// One counterclockwise quarter turn about +X, +Y, or +Z.
+	quarter := []intMatrix{
+		{{1, 0, 0}, {0, 0, -1}, {0, 1, 0}},
+		{{0, 0, 1}, {0, 1, 0}, {-1, 0, 0}},
+		{{0, -1, 0}, {1, 0, 0}, {0, 0, 1}},
+	}[index]
+	m := intMatrix{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	for i := 0; i < turns; i++ {
+		m = quarter.mul(m)
+	}
+	log.Printf("Rotating %v by %v degrees about the %v axis...", b, 90*turns, posAxes[index])
+	b.transform(m)
+}
+
+// Mirror mirrors the model across the plane through the origin that is
+// perpendicular to the given axis. The sign of the axis is ignored.
+func (b *BinVOX) Mirror(axis Axis) {
+	index, _ := axis.axisVector()
+	m := intMatrix{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	m[index][index] = -1
+	log.Printf("Mirroring %v across the %v axis...", b, posAxes[index])
+	b.transform(m)
+}
+
+// Translate moves the model by a whole number of voxels in each direction.
+func (b *BinVOX) Translate(dx, dy, dz int) {
+	s := 1.0 / b.VoxelsPerMM()
+	b.TX += s * float64(dx)
+	b.TY += s * float64(dy)
+	b.TZ += s * float64(dz)
+}
+
+// ScaleInt scales the model up by the integer factor n, keeping the size
+// of each voxel the same, so that each voxel is replaced by n*n*n voxels.
+func (b *BinVOX) ScaleInt(n int) error {
+	if n < 1 {
+		return fmt.Errorf("scale factor must be positive: %v", n)
+	}
+	if n == 1 {
+		return nil
+	}
+	log.Printf("Scaling %v by %v...", b, n)
+	scale := func(k Key, f func(k Key)) {
+		for z := 0; z < n; z++ {
+			for y := 0; y < n; y++ {
+				for x := 0; x < n; x++ {
+					f(Key{X: n*k.X + x, Y: n*k.Y + y, Z: n*k.Z + z})
+				}
+			}
+		}
+	}
+	if b.WhiteVoxels != nil {
+		voxels := make(WhiteVoxelMap, n*n*n*len(b.WhiteVoxels))
+		for k := range b.WhiteVoxels {
+			scale(k, func(k Key) { voxels[k] = struct{}{} })
+		}
+		b.WhiteVoxels = voxels
+	}
+	if b.ColorVoxels != nil {
+		voxels := make(ColorVoxelMap, n*n*n*len(b.ColorVoxels))
+		for k, c := range b.ColorVoxels {
+			scale(k, func(k Key) { voxels[k] = c })
+		}
+		b.ColorVoxels = voxels
+	}
+	b.NX, b.NY, b.NZ = n*b.NX, n*b.NY, n*b.NZ
+	b.TX, b.TY, b.TZ = float64(n)*b.TX, float64(n)*b.TY, float64(n)*b.TZ
+	b.Scale *= float64(n)
+	return nil
+}
+
+// transform applies the signed permutation matrix m to the model.
+func (b *BinVOX) transform(m intMatrix) {
+	n := [3]int{b.NX, b.NY, b.NZ}
+	s := 1.0 / b.VoxelsPerMM()
+	min := [3]float64{b.TX, b.TY, b.TZ}
+	max := [3]float64{b.TX + s*float64(b.NX), b.TY + s*float64(b.NY), b.TZ + s*float64(b.NZ)}
+
+	var nn [3]int
+	var offset [3]int
+	var t [3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			switch m[i][j] {
+			case 1:
+				nn[i] = n[j]
+				t[i] = min[j]
+			case -1:
+				nn[i] = n[j]
+				offset[i] = n[j] - 1
+				t[i] = -max[j]
+			}
+		}
+	}
+	mapKey := func(k Key) Key {
+		v := [3]int{k.X, k.Y, k.Z}
+		var r [3]int
+		for i := 0; i < 3; i++ {
+			r[i] = offset[i] + m[i][0]*v[0] + m[i][1]*v[1] + m[i][2]*v[2]
+		}
+		return Key{X: r[0], Y: r[1], Z: r[2]}
+	}
+
+	if b.WhiteVoxels != nil {
+		voxels := make(WhiteVoxelMap, len(b.WhiteVoxels))
+		for k := range b.WhiteVoxels {
+			voxels[mapKey(k)] = struct{}{}
+		}
+		b.WhiteVoxels = voxels
+	}
+	if b.ColorVoxels != nil {
+		voxels := make(ColorVoxelMap, len(b.ColorVoxels))
+		for k, c := range b.ColorVoxels {
+			voxels[mapKey(k)] = c
+		}
+		b.ColorVoxels = voxels
+	}
+	b.NX, b.NY, b.NZ = nn[0], nn[1], nn[2]
+	b.TX, b.TY, b.TZ = t[0], t[1], t[2]
+}
+
+// mul returns the matrix product a*b.
+func (a intMatrix) mul(b intMatrix) intMatrix {
+	var r intMatrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return r
+}
+
+// Rotate returns a copy of the model rotated by degrees about the given
+// axis (through the origin) using the right-hand rule (as in Rotate90)
+// by resampling with the Nearest or Trilinear filter. The result has the
+// same voxels per millimeter as b and covers the rotated subregion.
+// As with Rotate90, b.Transform is kept unchanged.
+func (b *BinVOX) Rotate(axis gl.Vector, degrees float64, filter Filter) (*BinVOX, error) {
+	if filter != Nearest && filter != Trilinear {
+		return nil, fmt.Errorf("rotation requires the nearest or trilinear filter, not %v", filter)
+	}
+	if axis.Length() == 0 {
+		return nil, fmt.Errorf("rotation axis must not be zero")
+	}
+	// gl.Rotate turns clockwise, so negate the angle for the right-hand rule.
+	rot := gl.Rotate(axis.Normalize(), -gl.Radians(degrees))
+	inv := rot.Inverse()
+	vpmm := b.VoxelsPerMM()
+	box := b.MBB().Transform(rot)
+	nx := int(math.Ceil((box.Max.X-box.Min.X)*vpmm - epsilon))
+	ny := int(math.Ceil((box.Max.Y-box.Min.Y)*vpmm - epsilon))
+	nz := int(math.Ceil((box.Max.Z-box.Min.Z)*vpmm - epsilon))
+	dst := New(nx, ny, nz, box.Min.X, box.Min.Y, box.Min.Z, 0, false)
+	dst.Scale = float64(dst.Dim()) / vpmm
+	dst.Transform = b.Transform
+	log.Printf("Rotating %v by %v degrees about %v using the %v filter...", b, degrees, axis, filter)
+
+	get := func(k Key) (Color, bool) {
+		if _, ok := b.WhiteVoxels[k]; ok {
+			return White, true
+		}
+		c, ok := b.ColorVoxels[k]
+		return c, ok
+	}
+
+	// sample returns the color and occupancy of b at world point p.
+	sample := func(p gl.Vector) (Color, float64) {
+		if filter == Nearest {
+			if c, ok := get(b.VoxelAt(p)); ok {
+				return c, 1
+			}
+			return Color{}, 0
+		}
+		u := gl.V((p.X-b.TX)*vpmm-0.5, (p.Y-b.TY)*vpmm-0.5, (p.Z-b.TZ)*vpmm-0.5)
+		i0 := Key{X: int(math.Floor(u.X)), Y: int(math.Floor(u.Y)), Z: int(math.Floor(u.Z))}
+		f := gl.V(u.X-float64(i0.X), u.Y-float64(i0.Y), u.Z-float64(i0.Z))
+		var occupancy float64
+		var sum Color
+		for i := 0; i < 8; i++ {
+			c, ok := get(Key{X: i0.X + i&1, Y: i0.Y + (i>>1)&1, Z: i0.Z + (i>>2)&1})
+			if !ok {
+				continue
+			}
+			w := weight(f.X, i&1) * weight(f.Y, (i>>1)&1) * weight(f.Z, (i>>2)&1)
+			occupancy += w
+			sum = Color{R: sum.R + w*c.R, G: sum.G + w*c.G, B: sum.B + w*c.B}
+		}
+		if occupancy == 0 {
+			return Color{}, 0
+		}
+		return Color{R: sum.R / occupancy, G: sum.G / occupancy, B: sum.B / occupancy, A: 1}, occupancy
+	}
+
+	// Only destination voxels near a rotated source voxel need to be sampled.
+	reach := 1
+	if filter == Trilinear {
+		reach = 2
+	}
+	candidates := WhiteVoxelMap{}
+	addCandidates := func(k Key) {
+		c := dst.VoxelAt(rot.MulPosition(b.VoxelCenter(k)))
+		for z := -reach; z <= reach; z++ {
+			for y := -reach; y <= reach; y++ {
+				for x := -reach; x <= reach; x++ {
+					candidates[Key{X: c.X + x, Y: c.Y + y, Z: c.Z + z}] = struct{}{}
+				}
+			}
+		}
+	}
+	for k := range b.WhiteVoxels {
+		addCandidates(k)
+	}
+	for k := range b.ColorVoxels {
+		addCandidates(k)
+	}
+
+	color := b.WhiteVoxels == nil && b.ColorVoxels != nil
+	if color {
+		dst.WhiteVoxels, dst.ColorVoxels = nil, ColorVoxelMap{}
+	}
+	for k := range candidates {
+		c, occupancy := sample(inv.MulPosition(dst.VoxelCenter(k)))
+		if occupancy < 0.5-epsilon {
+			continue
+		}
+		if color {
+			dst.ColorVoxels[k] = c
+		} else {
+			dst.WhiteVoxels[k] = struct{}{}
+		}
+	}
+	log.Printf("Done rotating; %v voxels.", len(dst.WhiteVoxels)+len(dst.ColorVoxels))
+	return dst, nil
+}
@@ -0,0 +1,177 @@
+package binvox
+
+import (
+	"math"
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// lShape returns an asymmetric model offset from the origin.
+func lShape() *BinVOX {
+	b := New(4, 3, 2, 1, 2, 3, 2, false) // 2 vpmm
+	for _, k := range []Key{{0, 0, 0}, {1, 0, 0}, {2, 0, 0}, {3, 0, 0}, {0, 1, 0}, {0, 2, 0}, {0, 0, 1}} {
+		b.WhiteVoxels[k] = struct{}{}
+	}
+	return b
+}
+
+// centers returns the world space voxel centers of b, rounded to avoid
+// floating point noise.
+func centers(b *BinVOX) map[gl.Vector]bool {
+	result := map[gl.Vector]bool{}
+	round := func(v float64) float64 { return math.Round(v*1e6) / 1e6 }
+	for k := range b.WhiteVoxels {
+		c := b.VoxelCenter(k)
+		result[gl.V(round(c.X), round(c.Y), round(c.Z))] = true
+	}
+	return result
+}
+
+// inBounds reports whether all voxels of b lie within its bounds.
+func inBounds(b *BinVOX) bool {
+	for k := range b.WhiteVoxels {
+		if k.X < 0 || k.Y < 0 || k.Z < 0 || k.X >= b.NX || k.Y >= b.NY || k.Z >= b.NZ {
+			return false
+		}
+	}
+	return true
+}
+
+// ccw returns a counterclockwise (right-hand rule) rotation matrix.
+func ccw(axis gl.Vector, radians float64) gl.Matrix {
+	return gl.Rotate(axis, -radians)
+}
+
+func TestRotate90(t *testing.T) {
+	tests := []struct {
+		axis   Axis
+		turns  int
+		matrix gl.Matrix
+	}{
+		{axis: PosZ, turns: 1, matrix: ccw(gl.V(0, 0, 1), math.Pi/2)},
+		{axis: PosZ, turns: -1, matrix: ccw(gl.V(0, 0, 1), -math.Pi/2)},
+		{axis: NegZ, turns: 1, matrix: ccw(gl.V(0, 0, 1), -math.Pi/2)},
+		{axis: PosX, turns: 1, matrix: ccw(gl.V(1, 0, 0), math.Pi/2)},
+		{axis: PosY, turns: 2, matrix: ccw(gl.V(0, 1, 0), math.Pi)},
+		{axis: NegY, turns: 3, matrix: ccw(gl.V(0, 1, 0), -3*math.Pi/2)},
+		{axis: PosX, turns: 4, matrix: gl.Identity()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.axis.String(), func(t *testing.T) {
+			b := lShape()
+			want := map[gl.Vector]bool{}
+			for c := range centers(b) {
+				r := tt.matrix.MulPosition(c)
+				round := func(v float64) float64 { return math.Round(v*1e6) / 1e6 }
+				want[gl.V(round(r.X), round(r.Y), round(r.Z))] = true
+			}
+
+			b.Rotate90(tt.axis, tt.turns)
+			if got := centers(b); len(got) != len(want) {
+				t.Fatalf("Rotate90 = %v voxels, want %v", len(got), len(want))
+			} else {
+				for c := range want {
+					if !got[c] {
+						t.Errorf("Rotate90 missing voxel centered at %v", c)
+					}
+				}
+			}
+			if !inBounds(b) {
+				t.Errorf("Rotate90 left voxels out of bounds of %v", b)
+			}
+		})
+	}
+}
+
+func TestMirror(t *testing.T) {
+	b := lShape()
+	want := map[gl.Vector]bool{}
+	for c := range centers(b) {
+		want[gl.V(c.X, -c.Y, c.Z)] = true
+	}
+	b.Mirror(NegY)
+	got := centers(b)
+	for c := range want {
+		if !got[c] {
+			t.Errorf("Mirror missing voxel centered at %v", c)
+		}
+	}
+	if !inBounds(b) {
+		t.Errorf("Mirror left voxels out of bounds of %v", b)
+	}
+	if b.TY != -3.5 {
+		t.Errorf("Mirror TY = %v, want -3.5", b.TY)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	b := lShape()
+	b.Translate(2, -4, 1)
+	if b.TX != 2 || b.TY != 0 || b.TZ != 3.5 {
+		t.Errorf("Translate = (%v,%v,%v), want (2,0,3.5)", b.TX, b.TY, b.TZ)
+	}
+}
+
+func TestScaleInt(t *testing.T) {
+	b := lShape()
+	if err := b.ScaleInt(0); err == nil {
+		t.Error("ScaleInt(0): want error")
+	}
+	if err := b.ScaleInt(3); err != nil {
+		t.Fatalf("ScaleInt: %v", err)
+	}
+	if got, want := len(b.WhiteVoxels), 7*27; got != want {
+		t.Errorf("ScaleInt = %v voxels, want %v", got, want)
+	}
+	if got := b.VoxelsPerMM(); got != 2 {
+		t.Errorf("ScaleInt VoxelsPerMM = %v, want 2", got)
+	}
+	if want := (gl.Box{Min: gl.V(3, 6, 9), Max: gl.V(9, 10.5, 12)}); *b.MBB() != want {
+		t.Errorf("ScaleInt MBB = %v, want %v", *b.MBB(), want)
+	}
+	if !inBounds(b) {
+		t.Errorf("ScaleInt left voxels out of bounds of %v", b)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	want := lShape()
+	want.Rotate90(PosZ, 1)
+
+	got, err := lShape().Rotate(gl.V(0, 0, 1), 90, Nearest)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	wc, gc := centers(want), centers(got)
+	if len(gc) != len(wc) {
+		t.Fatalf("Rotate = %v voxels, want %v", len(gc), len(wc))
+	}
+	for c := range wc {
+		if !gc[c] {
+			t.Errorf("Rotate missing voxel centered at %v", c)
+		}
+	}
+
+	cube, err := solidCube(10).Rotate(gl.V(1, 1, 0), 45, Trilinear)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if n := len(cube.WhiteVoxels); math.Abs(float64(n)-1000) > 100 {
+		t.Errorf("Rotate 45 degrees = %v voxels, want about 1000", n)
+	}
+
+	transformed := solidCube(2)
+	m := gl.Translate(gl.V(1, 2, 3))
+	transformed.Transform = &m
+	if got, err := transformed.Rotate(gl.V(0, 0, 1), 30, Nearest); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	} else if got.Transform == nil || *got.Transform != m {
+		t.Errorf("Rotate Transform = %v, want %v", got.Transform, m)
+	}
+
+	if _, err := solidCube(2).Rotate(gl.V(0, 0, 1), 45, Majority); err == nil {
+		t.Error("Rotate with majority filter: want error")
+	}
+}
@@ -76,6 +76,23 @@ func (b *BinVOX) Voxelize(mesh *gl.Mesh) error {
 	return nil
 }
 
+// VoxelizeTransformed voxelizes the mesh after applying the matrix m to it
+// (without modifying mesh), which allows a model to be voxelized in any
+// orientation. The matrix is recorded in b.Transform so that meshes
+// generated from the voxels are restored to the original placement.
+//
+// Note that (b.TX,b.TY,b.TZ) and the grid dimensions refer to the
+// transformed mesh.
+func (b *BinVOX) VoxelizeTransformed(mesh *gl.Mesh, m gl.Matrix) error {
+	transformed := mesh.Copy()
+	transformed.Transform(m)
+	if err := b.Voxelize(transformed); err != nil {
+		return err
+	}
+	b.Transform = &m
+	return nil
+}
+
 func (b *BinVOX) VoxelizeZ(mesh *gl.Mesh, zi int) error {
 	if b.NX == 0 || b.NY == 0 || b.NZ == 0 {
 		return fmt.Errorf("mesh dimensions must be non-zero (%v,%v,%v)", b.NX, b.NY, b.NZ)
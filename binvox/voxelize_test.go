@@ -1,6 +1,11 @@
 package binvox
 
 import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"testing"
@@ -70,3 +75,65 @@ func sortVoxels(voxels WhiteVoxelMap) []gl.Voxel {
 
 	return result
 }
+
+func TestVoxelizeTransformed(t *testing.T) {
+	mesh := gl.NewCube()                          // (-0.5,-0.5,-0.5)-(0.5,0.5,0.5)
+	mesh.Transform(gl.Scale(gl.V(3.9, 1.9, 1.9))) // keep the faces off the voxel boundaries
+	m := gl.Rotate(gl.V(0, 0, 1), math.Pi/2)      // swaps the X and Y extents
+
+	bv := &BinVOX{NX: 4, NY: 8, NZ: 4, TX: -1, TY: -2, TZ: -1, Scale: 4}
+	if err := bv.VoxelizeTransformed(mesh, m); err != nil {
+		t.Fatalf("VoxelizeTransformed: %v", err)
+	}
+	if got, want := len(bv.WhiteVoxels), 4*8*4; got != want {
+		t.Errorf("VoxelizeTransformed = %v voxels, want %v", got, want)
+	}
+	if box := mesh.BoundingBox(); box.Max.X != 1.95 {
+		t.Errorf("VoxelizeTransformed modified the mesh: %v", box)
+	}
+
+	want := gl.Box{Min: gl.V(-2, -1, -1), Max: gl.V(2, 1, 1)}
+	for name, mesh := range map[string]*gl.Mesh{"ToMesh": bv.ToMesh(), "ManifoldMesh": bv.ManifoldMesh()} {
+		box := mesh.BoundingBox()
+		if box.Min.Sub(want.Min).Length() > 1e-9 || box.Max.Sub(want.Max).Length() > 1e-9 {
+			t.Errorf("%v MBB = %v, want %v", name, box, want)
+		}
+	}
+
+	filename := filepath.Join(t.TempDir(), "model.binvox")
+	if err := bv.Write(filename, 0, 0, 0, 0, 0, 0); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf, []byte("transform")) {
+		t.Errorf("Write wrote the transform into the binvox header")
+	}
+	got, err := Read(filename, 0, 0, 0, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if got.Transform == nil || *got.Transform != m {
+		t.Errorf("Read Transform = %v, want %v", got.Transform, m)
+	}
+	if len(got.WhiteVoxels) != len(bv.WhiteVoxels) {
+		t.Errorf("Read = %v voxels, want %v", len(got.WhiteVoxels), len(bv.WhiteVoxels))
+	}
+
+	// Overwriting the file with an untransformed model removes the transform.
+	bv.Transform = nil
+	if err := bv.Write(filename, 0, 0, 0, 0, 0, 0); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := os.Stat(TransformFilename(filename)); !os.IsNotExist(err) {
+		t.Errorf("Stat(%q) = %v, want not-exist error", TransformFilename(filename), err)
+	}
+	if got, err = Read(filename, 0, 0, 0, 0, 0, 0); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if got.Transform != nil {
+		t.Errorf("Read Transform = %v, want nil", got.Transform)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 
@@ -13,6 +14,11 @@ import (
 // Write writes a binvox file.
 // sx, sy, sz are the starting indices of the model.
 // nx, ny, nz are the number of voxels to write in each direction (0=all).
+//
+// The binvox format has no place for b.Transform, so it is written to a
+// separate file (see TransformFilename) that is read back by Read. Any
+// such file left over from a previous model is removed when b.Transform
+// is nil.
 func (b *BinVOX) Write(filename string, sx, sy, sz, nx, ny, nz int) error {
 	f, err := os.Create(filename)
 	if err != nil {
@@ -26,20 +32,54 @@ func (b *BinVOX) Write(filename string, sx, sy, sz, nx, ny, nz int) error {
 	if err := f.Close(); err != nil {
 		return fmt.Errorf("unable to close %q: %v", filename, err)
 	}
+	if err := b.writeTransform(TransformFilename(filename)); err != nil {
+		return err
+	}
 	log.Printf("Done writing %v white voxels to file %q.", n, filename)
 	return nil
 }
 
+// TransformFilename returns the name of the file that holds the Transform
+// of the model in the binvox file filename.
+func TransformFilename(filename string) string {
+	return filename + ".transform"
+}
+
+// writeTransform writes b.Transform to the file as four rows of four
+// values, or removes the file if b.Transform is nil.
+func (b *BinVOX) writeTransform(filename string) error {
+	m := b.Transform
+	if m == nil {
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("unable to remove %q: %v", filename, err)
+		}
+		return nil
+	}
+	s := fmt.Sprintf("%g %g %g %g\n%g %g %g %g\n%g %g %g %g\n%g %g %g %g\n",
+		m.X00, m.X01, m.X02, m.X03,
+		m.X10, m.X11, m.X12, m.X13,
+		m.X20, m.X21, m.X22, m.X23,
+		m.X30, m.X31, m.X32, m.X33)
+	if err := ioutil.WriteFile(filename, []byte(s), 0644); err != nil {
+		return fmt.Errorf("unable to write transform: %v", err)
+	}
+	return nil
+}
+
 const headerFMT = `#binvox 1
 dim %v %v %v
 translate %g %g %g
 scale %g
-data
 `
 
+// header returns the binvox header for a model with the given dimensions.
+func (b *BinVOX) header(nx, ny, nz int) string {
+	return fmt.Sprintf(headerFMT, nx, ny, nz, b.TX, b.TY, b.TZ, b.Scale) + "data\n"
+}
+
 func (b *BinVOX) write(w io.Writer, sx, sy, sz, nx, ny, nz int) (int64, error) {
 	if len(b.WhiteVoxels) == 0 {
-		fmt.Fprintf(w, headerFMT, 0, 0, 0, b.TX, b.TY, b.TZ, b.Scale)
+		fmt.Fprint(w, b.header(0, 0, 0))
 		return 0, nil
 	}
 
@@ -53,7 +93,7 @@ func (b *BinVOX) write(w io.Writer, sx, sy, sz, nx, ny, nz int) (int64, error) {
 		nz = b.NZ
 	}
 
-	header := fmt.Sprintf(headerFMT, nx, ny, nz, b.TX, b.TY, b.TZ, b.Scale)
+	header := b.header(nx, ny, nz)
 	log.Printf("New header: %v", header)
 	fmt.Fprint(w, header)
 
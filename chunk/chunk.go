@@ -0,0 +1,120 @@
+// Package chunk splits files into checksummed chunks for gRPC streams and
+// reassembles them. It is shared by the stldice and stl2svx protocols,
+// whose Chunk messages have the same fields.
+//
+// SendFrom and ReceiveTo stream files to and from an io.Reader or
+// io.Writer (such as a file on disk) without holding them in memory.
+// Send and Receive are for data that must be in memory anyway, such as
+// STL files and requests that are parsed as a whole.
+package chunk
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	// Size is the maximum number of bytes of data in each chunk.
+	Size = 1 << 20 // 1MB
+
+	// MaxFileSize is the largest file that Receive accepts.
+	MaxFileSize = 1 << 32 // 4GB
+)
+
+// Message is a chunk of a file, as implemented by the generated Chunk
+// message of each protocol.
+type Message interface {
+	GetOffset() int64
+	GetSize() int64
+	GetData() []byte
+	GetCrc32() uint32
+}
+
+// Send splits data into chunks (with checksums) and sends them in order
+// with send. Empty data is sent as a single empty chunk.
+func Send(data []byte, send func(offset, size int64, data []byte, crc uint32) error) error {
+	return SendFrom(bytes.NewReader(data), int64(len(data)), send)
+}
+
+// SendFrom is Send for the size bytes read from r, which are read one
+// chunk at a time.
+func SendFrom(r io.Reader, size int64, send func(offset, size int64, data []byte, crc uint32) error) error {
+	for offset := int64(0); offset == 0 || offset < size; offset += Size {
+		n := size - offset
+		if n > Size {
+			n = Size
+		}
+		data := make([]byte, n) // send may keep it
+		if _, err := io.ReadFull(r, data); err != nil {
+			return fmt.Errorf("unable to read chunk at offset %v: %v", offset, err)
+		}
+		if err := send(offset, size, data, crc32.ChecksumIEEE(data)); err != nil {
+			return fmt.Errorf("unable to send chunk at offset %v: %v", offset, err)
+		}
+	}
+	return nil
+}
+
+// Receive receives chunks with recv until it returns io.EOF and returns
+// the reassembled data. It returns an error if a chunk is missing, out of
+// order or fails its checksum, if the data is incomplete, or if the file
+// is larger than MaxFileSize. The data grows as chunks arrive, so a
+// sender cannot make Receive allocate more than it actually sends.
+func Receive(recv func() (Message, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := ReceiveTo(&buf, recv); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReceiveTo is Receive that writes each chunk to w as it arrives and
+// returns the size of the file. Nothing is written past a bad chunk, but
+// the chunks before it have already been written.
+func ReceiveTo(w io.Writer, recv func() (Message, error)) (int64, error) {
+	var offset int64
+	size := int64(-1)
+	for {
+		c, err := recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if c == nil {
+			return 0, fmt.Errorf("missing chunk at offset %v", offset)
+		}
+		if size < 0 {
+			if c.GetSize() < 0 || c.GetSize() > MaxFileSize {
+				return 0, fmt.Errorf("invalid file size %v (maximum %v)", c.GetSize(), int64(MaxFileSize))
+			}
+			size = c.GetSize()
+		}
+		if c.GetSize() != size {
+			return 0, fmt.Errorf("chunk at offset %v has file size %v, want %v", c.GetOffset(), c.GetSize(), size)
+		}
+		if c.GetOffset() != offset {
+			return 0, fmt.Errorf("got chunk at offset %v, want offset %v", c.GetOffset(), offset)
+		}
+		if offset+int64(len(c.GetData())) > size {
+			return 0, fmt.Errorf("chunk at offset %v extends beyond the file size %v", c.GetOffset(), size)
+		}
+		if got, want := crc32.ChecksumIEEE(c.GetData()), c.GetCrc32(); got != want {
+			return 0, fmt.Errorf("chunk at offset %v has checksum %08x, want %08x", c.GetOffset(), got, want)
+		}
+		if _, err := w.Write(c.GetData()); err != nil {
+			return 0, fmt.Errorf("unable to write chunk at offset %v: %v", offset, err)
+		}
+		offset += int64(len(c.GetData()))
+	}
+	if size < 0 {
+		return 0, fmt.Errorf("no chunks received")
+	}
+	if offset != size {
+		return 0, fmt.Errorf("received %v bytes, want %v", offset, size)
+	}
+	return size, nil
+}
@@ -0,0 +1,111 @@
+package chunk
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// message is a Message for testing.
+type message struct {
+	offset, size int64
+	data         []byte
+	crc          uint32
+}
+
+func (m *message) GetOffset() int64 { return m.offset }
+func (m *message) GetSize() int64   { return m.size }
+func (m *message) GetData() []byte  { return m.data }
+func (m *message) GetCrc32() uint32 { return m.crc }
+
+// send returns the chunks of data.
+func send(t *testing.T, data []byte) []*message {
+	t.Helper()
+	var chunks []*message
+	err := Send(data, func(offset, size int64, data []byte, crc uint32) error {
+		chunks = append(chunks, &message{offset: offset, size: size, data: data, crc: crc})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Send(%v bytes): %v", len(data), err)
+	}
+	return chunks
+}
+
+// replay returns a recv function that returns the chunks in order and then io.EOF.
+func replay(chunks []*message) func() (Message, error) {
+	return func() (Message, error) {
+		if len(chunks) == 0 {
+			return nil, io.EOF
+		}
+		c := chunks[0]
+		chunks = chunks[1:]
+		return c, nil
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := make([]byte, 3*Size+5)
+	for i := range data {
+		data[i] = byte(i * 13)
+	}
+	chunks := send(t, data)
+	if len(chunks) != 4 {
+		t.Errorf("Send sent %v chunks, want 4", len(chunks))
+	}
+	got, err := Receive(replay(chunks))
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("Receive returned different data")
+	}
+}
+
+func TestReceiveSize(t *testing.T) {
+	// A huge (or negative) size is rejected before anything is allocated.
+	for _, size := range []int64{1 << 62, MaxFileSize + 1, -1} {
+		if _, err := Receive(replay([]*message{{size: size}})); err == nil {
+			t.Errorf("Receive of a %v byte file = nil error, want error", size)
+		}
+	}
+
+	// A file up to MaxFileSize is accepted, but only what is sent is
+	// received.
+	chunks := send(t, []byte("hello"))
+	for _, c := range chunks {
+		c.size = MaxFileSize
+	}
+	if _, err := Receive(replay(chunks)); err == nil {
+		t.Error("Receive of a truncated file = nil error, want error")
+	}
+}
+
+func TestStream(t *testing.T) {
+	data := make([]byte, 2*Size+7)
+	for i := range data {
+		data[i] = byte(i * 7 / 3)
+	}
+	var chunks []*message
+	err := SendFrom(bytes.NewReader(data), int64(len(data)), func(offset, size int64, data []byte, crc uint32) error {
+		chunks = append(chunks, &message{offset: offset, size: size, data: data, crc: crc})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SendFrom: %v", err)
+	}
+	var buf bytes.Buffer
+	size, err := ReceiveTo(&buf, replay(chunks))
+	if err != nil {
+		t.Fatalf("ReceiveTo: %v", err)
+	}
+	if size != int64(len(data)) || !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("ReceiveTo = %v bytes, want %v identical bytes", size, len(data))
+	}
+
+	// A reader that is shorter than the size is an error.
+	err = SendFrom(bytes.NewReader(data[:10]), 20, func(offset, size int64, data []byte, crc uint32) error { return nil })
+	if err == nil {
+		t.Error("SendFrom of a short reader = nil error, want error")
+	}
+}
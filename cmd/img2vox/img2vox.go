@@ -0,0 +1,135 @@
+// img2vox creates 'binvox' files from PNG or TIFF images.
+//
+// With -heightmap, a single grayscale image is converted into a relief
+// (e.g. for embossing or lithophanes) whose height is proportional to
+// the brightness of each pixel. Otherwise, the images are treated as a
+// stack of slices (ordered from the bottom of the model to the top),
+// like the slices within SVX files.
+//
+// The resulting binvox files can be used directly with voxcut (see its
+// -align flag for models that are not on the voxel grid of the base).
+//
+// Usage:
+//
+//	img2vox -heightmap -mmpp 0.1 -height 3 -base 0.8 -invert -obinvox litho.binvox photo.png
+//	img2vox -voxel 0.05 -obinvox model.binvox slice-*.png
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+	"github.com/gmlewis/stldice/v4/raster"
+)
+
+var (
+	binVOXFile    = flag.String("obinvox", "", "The output binvox filename to create")
+	stlFile       = flag.String("ostl", "", "The output stl filename to create")
+	heightmap     = flag.Bool("heightmap", false, "Convert a single heightmap image (instead of a stack of slices)")
+	mmPerPixel    = flag.Float64("mmpp", 0.1, "Heightmap millimeters per pixel")
+	maxHeight     = flag.Float64("height", 2, "Heightmap height of a white pixel above the base in millimeters")
+	baseHeight    = flag.Float64("base", 0, "Heightmap base thickness in millimeters")
+	invert        = flag.Bool("invert", false, "Heightmap dark pixels are tall (for lithophanes)")
+	voxelSize     = flag.Float64("voxel", 0.1, "Slice stack voxel size (and slice spacing) in millimeters")
+	threshold     = flag.Float64("threshold", 0.5, "Slice stack brightness (0-1) at or above which a pixel is solid")
+	origin        = flag.String("origin", "0,0,0", "World space location of the model origin in millimeters")
+	smoothDegrees = flag.Float64("smooth", 0, "Degrees used for smoothing normals (0=no smoothing)")
+	manifold      = flag.Bool("manifold", false, "Output manifold mesh - useful for low-res models")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\t%v [options] heightmap.png\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\t%v [options] slice0.png [slice1.tif ...]\n\nOptions:\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatal("Must supply at least one filename")
+	}
+	if *heightmap && flag.NArg() != 1 {
+		log.Fatal("Must supply exactly one heightmap filename")
+	}
+	if *binVOXFile == "" && *stlFile == "" {
+		log.Fatal("Must specify at least one of -obinvox or -ostl")
+	}
+
+	o, err := parsePoint(*origin)
+	if err != nil {
+		log.Fatalf("Unable to parse -origin: %v", err)
+	}
+
+	var model *binvox.BinVOX
+	if *heightmap {
+		model, err = raster.ReadHeightmap(flag.Arg(0), &raster.HeightmapOptions{
+			MMPerPixel: *mmPerPixel,
+			MaxHeight:  *maxHeight,
+			BaseHeight: *baseHeight,
+			Invert:     *invert,
+			Origin:     o,
+		})
+	} else {
+		model, err = raster.ReadStack(flag.Args(), &raster.StackOptions{
+			VoxelSize: *voxelSize,
+			Threshold: *threshold,
+			Origin:    o,
+		})
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *binVOXFile != "" {
+		log.Printf("Writing file %q...", *binVOXFile)
+		if err := model.Write(*binVOXFile, 0, 0, 0, 0, 0, 0); err != nil {
+			log.Fatalf("binvox.Write(%q): %v", *binVOXFile, err)
+		}
+	}
+
+	if *stlFile != "" {
+		var mesh *gl.Mesh
+		if *manifold {
+			mesh = model.ManifoldMesh()
+		} else {
+			mesh = model.ToMesh()
+		}
+
+		if *smoothDegrees > 0 {
+			log.Printf("Smoothing mesh normals with %v degree threshold...", *smoothDegrees)
+			mesh.SmoothNormalsThreshold(gl.Radians(*smoothDegrees))
+			log.Println("Done smoothing mesh normals.")
+		}
+
+		log.Printf("Writing file %v ...", *stlFile)
+		if err := mesh.SaveSTL(*stlFile); err != nil {
+			log.Fatalf("SaveSTL: %v", err)
+		}
+	}
+
+	log.Println("Done.")
+}
+
+// parsePoint parses a point of the form "X,Y,Z".
+func parsePoint(s string) (gl.Vector, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return gl.Vector{}, fmt.Errorf("incorrect format: %q", s)
+	}
+	var v [3]float64
+	for i, part := range parts {
+		var err error
+		if v[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64); err != nil {
+			return gl.Vector{}, fmt.Errorf("invalid number %v: %v", part, err)
+		}
+	}
+	return gl.V(v[0], v[1], v[2]), nil
+}
@@ -65,7 +65,9 @@ func main() {
 
 	// If any STL files are passed in, this is the master.
 	if *stl != "" {
-		master()
+		if err := master(); err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	if err := zipFile.Close(); err != nil {
@@ -75,10 +77,15 @@ func main() {
 	log.Print("Done.")
 }
 
-func master() {
-	_, dimZ, err := generateMR(strings.Split(*stl, ","), true) // just get dimZ
+func master() error {
+	ch, errc, dimZ, err := generateMR(strings.Split(*stl, ","), true) // just get dimZ
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	for range ch {
+	}
+	if err := <-errc; err != nil {
+		return err
 	}
 
 	// For Shapeways, create a black base and a black top.
@@ -98,18 +105,21 @@ func master() {
 	outf(fmt.Sprintf("%v/out-%v-%v-%v-%v-%04d.png", *dim, *dim, *nX, *nY, *nZ, 0))
 
 	for i := 0; i < dimZ; i++ {
-		agent(i)
+		if err := agent(i); err != nil {
+			return err
+		}
 	}
 
 	outf(fmt.Sprintf("%v/out-%v-%v-%v-%v-%04d.png", *dim, *dim, *nX, *nY, *nZ, dimZ+1))
+	return nil
 }
 
-func agent(agentID int) {
+func agent(agentID int) error {
 	log.Printf("Agent %v to process: %v...", agentID, *stl)
 
-	ch, _, err := generateMR(strings.Split(*stl, ","), false)
+	ch, errc, _, err := generateMR(strings.Split(*stl, ","), false)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	vxCh := make(chan voxelInfo, 1000)
@@ -121,11 +131,20 @@ func agent(agentID int) {
 	}()
 
 	for bv := range ch {
-		voxelize(bv, agentID, vxCh)
+		if err == nil {
+			err = voxelize(bv, agentID, vxCh)
+		}
 	}
 	close(vxCh)
 	wg.Wait()
+	if err == nil {
+		err = <-errc
+	}
+	if err != nil {
+		return fmt.Errorf("agent %v: %v", agentID, err)
+	}
 	log.Printf("Agent %v done.", agentID)
+	return nil
 }
 
 type bvInfo struct {
@@ -139,8 +158,10 @@ type bvInfo struct {
 // be performed by each mapper. It also sends all the data each mapper needs
 // through the provided channel.
 // dimZ is the number of agents needed to output the images.
-func generateMR(args []string, dimOnly bool) (ch chan *bvInfo, dimZ int, err error) {
+// Any error after dimZ has been calculated is sent on errc once ch is closed.
+func generateMR(args []string, dimOnly bool) (ch chan *bvInfo, errc <-chan error, dimZ int, err error) {
 	ch = make(chan *bvInfo)
+	errCh := make(chan error, 1)
 	var wg sync.WaitGroup // Wait to return when dimZ has been calculated
 	wg.Add(1)
 	go func() {
@@ -148,13 +169,25 @@ func generateMR(args []string, dimOnly bool) (ch chan *bvInfo, dimZ int, err err
 			mbb                  *gl.Box
 			subregionScale, mmpv float64
 			dimX, dimY           int
+			ready                bool // dimZ has been calculated
 		)
+		fail := func(e error) {
+			if ready {
+				errCh <- e
+			} else {
+				err = e
+				wg.Done()
+			}
+		}
+		defer close(errCh)
+		defer close(ch)
 
 		for i, arg := range args {
 			log.Printf("generateMR: loading file %q...", arg)
 			mesh, err := gl.LoadSTL(arg)
 			if err != nil {
-				log.Fatalf("Unable to load file %q: %v", arg, err)
+				fail(fmt.Errorf("unable to load file %q: %v", arg, err))
+				return
 			}
 			log.Printf("generateMR: loaded %v triangles", len(mesh.Triangles))
 
@@ -179,21 +212,23 @@ func generateMR(args []string, dimOnly bool) (ch chan *bvInfo, dimZ int, err err
 				dimY = newModelDimY / *nY
 				dimZ = newModelDimZ / *nZ
 				if dimX == 0 || dimY == 0 || dimZ == 0 {
-					log.Fatalf("too many divisions: region dimensions = (%v,%v,%v)", dimX, dimY, dimZ)
+					fail(fmt.Errorf("too many divisions: region dimensions = (%v,%v,%v)", dimX, dimY, dimZ))
+					return
 				}
 				// dimZ has now been calculated... parent function can return.
+				ready = true
 				wg.Done()
 				if dimOnly { // generate the manifest file.
 					// Note that "Up" in Shapeways is +Y, so swap Y and Z (and keep mirroring Y when writing the image).
 					// Shapeways will *not* accept slicesOrientation="Z" despite documentation.
-					// Also swap the originY/Z values.
+					// Also swap the originY/Z values (which, like voxelSize, are in meters).
 					b := fmt.Sprintf(`<?xml version="1.0"?>
 <grid version="1.0" gridSizeX="%v" gridSizeY="%v" gridSizeZ="%v" voxelSize="%v" subvoxelBits="8" originX="%v" originY="%v" originZ="%v" slicesOrientation="Y" >
   <channels>
     <channel type="DENSITY" bits="8" slices="%v/out-%v-%v-%v-%v-%%04d.png" />
   </channels>
 </grid>
-`, newModelDimX+2, newModelDimZ+2, newModelDimY+2, mmpv*1e-3, mbb.Min.X, mbb.Min.Z, mbb.Min.Y, *dim, *dim, *nX, *nY, *nZ)
+`, newModelDimX+2, newModelDimZ+2, newModelDimY+2, mmpv*1e-3, mbb.Min.X*1e-3, mbb.Min.Z*1e-3, mbb.Min.Y*1e-3, *dim, *dim, *nX, *nY, *nZ)
 					log.Printf("SVX manifest.xml file:\n\n%v\n\n", b)
 					writeManifest(b, *dim)
 					break // don't continue
@@ -235,10 +270,9 @@ func generateMR(args []string, dimOnly bool) (ch chan *bvInfo, dimZ int, err err
 				}
 			}
 		}
-		close(ch)
 	}()
 	wg.Wait()
-	return ch, dimZ, nil
+	return ch, errCh, dimZ, err
 }
 
 type voxelInfo struct {
@@ -248,11 +282,10 @@ type voxelInfo struct {
 
 // voxelize takes an STL file and dices it into voxels
 // then sends them to the imager.
-func voxelize(bvi *bvInfo, zi int, ch chan<- voxelInfo) {
+func voxelize(bvi *bvInfo, zi int, ch chan<- voxelInfo) error {
 	bv := bvi.bv
 	if err := bv.VoxelizeZ(bvi.mesh, zi); err != nil {
-		log.Printf("voxelize(%v): %v", zi, err)
-		return
+		return fmt.Errorf("voxelize(%v): %v", zi, err)
 	}
 
 	vType := "cut"
@@ -261,24 +294,30 @@ func voxelize(bvi *bvInfo, zi int, ch chan<- voxelInfo) {
 	}
 	log.Printf("voxelize(%v): sending %v %v voxels to imager(%v)...", zi, len(bv.WhiteVoxels), vType, zi)
 
-	keyFunc := func(k binvox.Key) {
+	keyFunc := func(k binvox.Key) error {
 		k.X += bvi.dx
 		k.Y += bvi.dy
 		k.Z += bvi.dz
 		if zi != k.Z {
-			log.Fatalf("voxelize(%v): k{%v,%v,%v} does not match zi=%v", zi, k.X, k.Y, k.Z, zi)
+			return fmt.Errorf("voxelize(%v): k{%v,%v,%v} does not match zi=%v", zi, k.X, k.Y, k.Z, zi)
 		}
 		if k.X < 0 || k.Y < 0 || k.Z < 0 {
-			return // common for a cut to extend beyond the bounds of the base.
+			return nil // common for a cut to extend beyond the bounds of the base.
 		}
 		ch <- voxelInfo{X: k.X, Y: k.Y, Base: bvi.base}
+		return nil
 	}
 	for k := range bv.WhiteVoxels {
-		keyFunc(k)
+		if err := keyFunc(k); err != nil {
+			return err
+		}
 	}
 	for k := range bv.ColorVoxels {
-		keyFunc(k)
+		if err := keyFunc(k); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 type pixelKey struct {
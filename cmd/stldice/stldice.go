@@ -1,9 +1,15 @@
 // stldice dices up STL meshes into one or more 'binvox' files.
 //
+// In place of an STL file, a job file (with the .sdf extension) may be
+// given that describes a solid made of simple primitives (see sdf.Parse),
+// which is voxelized directly without first being modeled as a mesh.
+//
 // Usage:
 //
 //	stldice base.stl all-cuts.stl
 //	stldice -mbb "(-80,-80,-2.6)-(80,80,0.6)" all-cuts.stl
+//	stldice -transform "0,-1,0,0, 1,0,0,0, 0,0,1,0, 0,0,0,1" base.stl
+//	stldice base.stl bolt-holes.sdf
 package main
 
 import (
@@ -20,6 +26,7 @@ import (
 
 	gl "github.com/fogleman/fauxgl"
 	"github.com/gmlewis/stldice/v4/binvox"
+	"github.com/gmlewis/stldice/v4/sdf"
 )
 
 var (
@@ -29,6 +36,7 @@ var (
 	nY            = flag.Int("ny", 8, "Number of slices along the Y dimension")
 	nZ            = flag.Int("nz", 1, "Number of slices along the Z dimension")
 	smoothDegrees = flag.Float64("smooth", 0, "Degrees used for smoothing normals (0=no smoothing)")
+	transformStr  = flag.String("transform", "", "4x4 matrix (16 comma-separated values in row-major order) applied to the STL meshes before voxelizing; it is recorded beside the binvox files so that meshing restores the original placement; -mbb is in the transformed space; not allowed with .sdf job files")
 
 	mbbRE = regexp.MustCompile(`^\s*[\(\[]?\s*([^,\s]+)\s*,\s*([^,\s]+)\s*,\s*([^\]\)\s]+)\s*[\]\)]?\s*\-\s*[\(\[]?\s*([^,\s]+)\s*,\s*([^,\s]+)\s*,\s*([^\]\)\s]+)\s*[\)\]]?\s*$`)
 )
@@ -56,22 +64,23 @@ func main() {
 		}
 	}
 
-	log.Printf("stldice -dim %v -nx %v -ny %v -nz %v -mbb %q %v", *dim, *nX, *nY, *nZ, *mbbString, strings.Join(flag.Args(), " "))
+	var transform *gl.Matrix
+	if *transformStr != "" {
+		var err error
+		if transform, err = binvox.ParseMatrix(*transformStr); err != nil {
+			log.Fatalf("Unable to parse transform: %v", err)
+		}
+	}
+
+	log.Printf("stldice -dim %v -nx %v -ny %v -nz %v -mbb %q -transform %q %v", *dim, *nX, *nY, *nZ, *mbbString, *transformStr, strings.Join(flag.Args(), " "))
 
 	prefixes := map[string]bool{}
 	for _, arg := range flag.Args() {
-		log.Printf("Loading file %q...", arg)
-		mesh, err := gl.LoadSTL(arg)
+		voxelize, box, m, err := load(arg, transform)
 		if err != nil {
-			log.Fatalf("Unable to load file %q: %v", arg, err)
-		}
-		if *smoothDegrees > 0 {
-			log.Printf("Smoothing mesh normals with %v degree threshold...", *smoothDegrees)
-			mesh.SmoothNormalsThreshold(gl.Radians(*smoothDegrees))
-			log.Println("Done smoothing mesh normals.")
+			log.Fatal(err)
 		}
 		if mbb == nil {
-			box := mesh.BoundingBox()
 			mbb = &box
 			log.Printf(`Mesh MBB: "(%v,%v,%v)-(%v,%v,%v)"`, mbb.Min.X, mbb.Min.Y, mbb.Min.Z, mbb.Max.X, mbb.Max.Y, mbb.Max.Z)
 		}
@@ -83,10 +92,10 @@ func main() {
 			scale = dz
 		}
 
-		outPrefix := fmt.Sprintf("%v-%v-%v-%v-%v", strings.TrimSuffix(arg, ".stl"), *dim, *nX, *nY, *nZ)
-		pfxs, err := diceMesh(mesh, mbb, scale, *dim, *nX, *nY, *nZ, outPrefix)
+		outPrefix := fmt.Sprintf("%v-%v-%v-%v-%v", trimExt(arg), *dim, *nX, *nY, *nZ)
+		pfxs, err := dice(voxelize, m, mbb, scale, *dim, *nX, *nY, *nZ, outPrefix)
 		if err != nil {
-			log.Fatalf("Unable to dice mesh: %v", err)
+			log.Fatalf("Unable to dice %q: %v", arg, err)
 		}
 		for _, v := range pfxs {
 			prefixes[v] = true // dedupe
@@ -102,13 +111,13 @@ func main() {
 	sort.Strings(pfxs)
 	stlOutPrefix := "out"
 	if flag.NArg() == 1 {
-		stlOutPrefix = strings.TrimSuffix(flag.Arg(0), ".stl")
+		stlOutPrefix = trimExt(flag.Arg(0))
 	}
 	common := fmt.Sprintf("%v-%v-%v-%v", *dim, *nX, *nY, *nZ)
 	for _, v := range pfxs {
-		fmt.Printf("voxcut -ostl %[1]v-%[4]v%[3]v.stl %[2]v-%[4]v%[3]v.binvox", stlOutPrefix, strings.TrimSuffix(flag.Arg(0), ".stl"), v, common)
+		fmt.Printf("voxcut -ostl %[1]v-%[4]v%[3]v.stl %[2]v-%[4]v%[3]v.binvox", stlOutPrefix, trimExt(flag.Arg(0)), v, common)
 		for _, arg := range flag.Args()[1:] {
-			fmt.Printf(" %v-%v%v.binvox", strings.TrimSuffix(arg, ".stl"), common, v)
+			fmt.Printf(" %v-%v%v.binvox", trimExt(arg), common, v)
 		}
 		fmt.Println()
 	}
@@ -119,14 +128,59 @@ func main() {
 	log.Println("Done.")
 }
 
-// diceMesh dices a mesh into voxelized regions and writes the regions into .binvox files.
+// load loads an STL file or .sdf job file and returns a function that
+// voxelizes it into a region along with its MBB and the transform (if
+// any) that has been applied to it.
+func load(filename string, transform *gl.Matrix) (voxelize func(bv *binvox.BinVOX) error, mbb gl.Box, m *gl.Matrix, err error) {
+	log.Printf("Loading file %q...", filename)
+	if strings.ToLower(filepath.Ext(filename)) == ".sdf" {
+		if transform != nil {
+			return nil, gl.Box{}, nil, fmt.Errorf("-transform cannot be applied to job file %q", filename)
+		}
+		s, err := sdf.ParseFile(filename)
+		if err != nil {
+			return nil, gl.Box{}, nil, err
+		}
+		voxelize = func(bv *binvox.BinVOX) error { return sdf.Voxelize(bv, s) }
+		return voxelize, s.Bounds(), nil, nil
+	}
+
+	mesh, err := gl.LoadSTL(filename)
+	if err != nil {
+		return nil, gl.Box{}, nil, fmt.Errorf("unable to load file %q: %v", filename, err)
+	}
+	if *smoothDegrees > 0 {
+		log.Printf("Smoothing mesh normals with %v degree threshold...", *smoothDegrees)
+		mesh.SmoothNormalsThreshold(gl.Radians(*smoothDegrees))
+		log.Println("Done smoothing mesh normals.")
+	}
+	if transform != nil {
+		log.Printf("Transforming mesh by %v...", *transform)
+		mesh.Transform(*transform)
+	}
+	voxelize = func(bv *binvox.BinVOX) error { return bv.Voxelize(mesh) }
+	return voxelize, mesh.BoundingBox(), transform, nil
+}
+
+// trimExt removes the .stl or .sdf extension from filename.
+func trimExt(filename string) string {
+	switch ext := filepath.Ext(filename); strings.ToLower(ext) {
+	case ".stl", ".sdf":
+		return strings.TrimSuffix(filename, ext)
+	}
+	return filename
+}
+
+// dice dices a model into voxelized regions and writes the regions into .binvox files.
+// voxelize voxelizes the model into a single region.
 // nx, ny, nz are the number of divisions for each dimension.
+// transform (if not nil) is the matrix that has already been applied to the model.
 // prefixes are the file prefixes that were generated.
-func diceMesh(mesh *gl.Mesh, mbb *gl.Box, scale float64, dim, nx, ny, nz int, outPrefix string) (prefixes []string, err error) {
+func dice(voxelize func(bv *binvox.BinVOX) error, transform *gl.Matrix, mbb *gl.Box, scale float64, dim, nx, ny, nz int, outPrefix string) (prefixes []string, err error) {
 	vpmm := float64(dim) / scale // voxels per millimeter
 	mmpv := 1.0 / vpmm           // millimeters per voxel
 	modelDimInMM := mbb.Size()
-	log.Printf("diceMesh(mbb=(%v,%v,%v)-(%v,%v,%v), size=%v, scale=%v, dim=%v, n=[%v,%v,%v], outPrefix=%v); %v voxels per millimeter; %v millimeters per voxel", mbb.Min.X, mbb.Min.Y, mbb.Min.Z, mbb.Max.X, mbb.Max.Y, mbb.Max.Z, modelDimInMM, scale, dim, nx, ny, nz, outPrefix, vpmm, mmpv)
+	log.Printf("dice(mbb=(%v,%v,%v)-(%v,%v,%v), size=%v, scale=%v, dim=%v, n=[%v,%v,%v], outPrefix=%v); %v voxels per millimeter; %v millimeters per voxel", mbb.Min.X, mbb.Min.Y, mbb.Min.Z, mbb.Max.X, mbb.Max.Y, mbb.Max.Z, modelDimInMM, scale, dim, nx, ny, nz, outPrefix, vpmm, mmpv)
 
 	newModelDimX := int(math.Ceil(modelDimInMM.X * vpmm))
 	newModelDimY := int(math.Ceil(modelDimInMM.Y * vpmm))
@@ -168,9 +222,10 @@ func diceMesh(mesh *gl.Mesh, mbb *gl.Box, scale float64, dim, nx, ny, nz int, ou
 				bv := &binvox.BinVOX{
 					NX: dimX, NY: dimY, NZ: dimZ,
 					TX: x1, TY: y1, TZ: z1,
-					Scale: subregionScale,
+					Scale:     subregionScale,
+					Transform: transform,
 				}
-				if err := bv.Voxelize(mesh); err != nil {
+				if err := voxelize(bv); err != nil {
 					return nil, fmt.Errorf("voxelize: %v", err)
 				}
 
@@ -0,0 +1,103 @@
+// voxcavity finds fully enclosed cavities in 'binvox' files that would
+// trap uncured resin or unsintered powder during printing.
+//
+// All files on the command line are treated as subregions of a single
+// model (such as those written by stldice), so cavities that span
+// several subregions are found. Gaps between the subregions (such as
+// the empty subregions that stldice does not write) are treated as empty
+// space within the model. Optionally, the cavities can be filled or
+// drained and the modified subregions written out.
+//
+// Usage:
+//
+//	voxcavity [options] region1.binvox [region2.binvox ...]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+var (
+	fill    = flag.Bool("fill", false, "Fill all cavities")
+	drain   = flag.Float64("drain", 0, "Drain hole diameter in millimeters to punch from the lowest point of each cavity (0=no drain holes)")
+	oSuffix = flag.String("osuffix", "", "Suffix added to each input filename (before '.binvox') to create the output filenames")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\t%v [options] region1.binvox [region2.binvox ...]\n\nOptions:\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatal("Must supply at least one filename")
+	}
+	if *fill && *drain > 0 {
+		log.Fatal("Must specify only one of -fill or -drain")
+	}
+	if (*fill || *drain > 0) && *oSuffix == "" {
+		log.Fatal("Must specify -osuffix with -fill or -drain")
+	}
+
+	var regions []*binvox.BinVOX
+	for _, arg := range flag.Args() {
+		b, err := binvox.Read(arg, 0, 0, 0, 0, 0, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		regions = append(regions, b)
+	}
+
+	cavities, err := binvox.FindCavities(regions)
+	if err != nil {
+		log.Fatalf("FindCavities: %v", err)
+	}
+
+	fmt.Printf("%v enclosed cavities\n", len(cavities))
+	for i, c := range cavities {
+		fmt.Printf("cavity #%v: %v voxels, volume %.3f mm^3, lowest point (%.3f,%.3f,%.3f), mbb=(%.3f,%.3f,%.3f)-(%.3f,%.3f,%.3f)\n",
+			i+1, c.NumVoxels, c.Volume,
+			c.Lowest.X, c.Lowest.Y, c.Lowest.Z,
+			c.MBB.Min.X, c.MBB.Min.Y, c.MBB.Min.Z,
+			c.MBB.Max.X, c.MBB.Max.Y, c.MBB.Max.Z)
+		for _, g := range c.Gaps {
+			fmt.Printf("  includes gap between the regions: (%.3f,%.3f,%.3f)-(%.3f,%.3f,%.3f)\n",
+				g.Min.X, g.Min.Y, g.Min.Z, g.Max.X, g.Max.Y, g.Max.Z)
+		}
+	}
+
+	if len(cavities) == 0 || (!*fill && *drain <= 0) {
+		log.Println("Done.")
+		return
+	}
+
+	if *fill {
+		for i, c := range cavities {
+			if len(c.Gaps) > 0 {
+				log.Printf("Unable to fill the %v gaps between the regions within cavity #%v", len(c.Gaps), i+1)
+			}
+		}
+		binvox.FillCavities(regions, cavities)
+	} else if err := binvox.DrainCavities(regions, cavities, *drain); err != nil {
+		log.Fatalf("DrainCavities: %v", err)
+	}
+
+	for i, b := range regions {
+		outFile := strings.TrimSuffix(flag.Arg(i), ".binvox") + *oSuffix + ".binvox"
+		log.Printf("Writing file %q...", outFile)
+		if err := b.Write(outFile, 0, 0, 0, 0, 0, 0); err != nil {
+			log.Fatalf("binvox.Write(%q): %v", outFile, err)
+		}
+	}
+
+	log.Println("Done.")
+}
@@ -2,15 +2,27 @@
 //
 // Note that the binvox files must be based on the same voxel 3D grid
 // meaning that all vox files have the same voxels per milliemeter.
+// Cuts with a different resolution than the base are automatically
+// resampled onto the voxel grid of the base. Cuts with the same
+// resolution whose translation is not a whole number of voxels away
+// from the base are a fatal error unless -align=snap (move the cut onto
+// the grid) or -align=resample (resample the cut onto the grid) is given.
+// Other cuts that cannot be read are skipped.
 //
 // Since binvox models can be very large and possibly not fit into
 // memory, voxcut supports dicing the model into smaller chunks.
 // To facilitate this, start indices and counts for each dimension
 // can be provided to process only a smaller section of the model.
 //
+// Cuts may also be simple primitives described by the -cut flag (which
+// may be repeated), using either the syntax of sdf.Parse or the name of
+// a .sdf job file. These are voxelized directly onto the voxel grid of
+// the base.
+//
 // Usage:
 //
 //	voxcut [options] base.binvox [cut1.binvox [cut2.binvox ...]]
+//	voxcut -obinvox out.binvox -cut "cylinder 0,0,-1 0,0,11 1.5" base.binvox
 package main
 
 import (
@@ -21,9 +33,12 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	gl "github.com/fogleman/fauxgl"
 	"github.com/gmlewis/stldice/v4/binvox"
+	"github.com/gmlewis/stldice/v4/sdf"
 )
 
 var (
@@ -38,8 +53,26 @@ var (
 	countZ        = flag.Int("cz", 0, "The number of voxels to process in the Z direction (default=0=all)")
 	smoothDegrees = flag.Float64("smooth", 0, "Degrees used for smoothing normals (0=no smoothing)")
 	manifold      = flag.Bool("manifold", false, "Output manifold mesh - useful for low-res cutouts")
+	align         = flag.String("align", "", "How to handle cuts that are not aligned to the voxel grid of the base: snap or resample (default=error)")
+	filterName    = flag.String("filter", "", "Filter used to resample cuts with a different resolution: majority, average, nearest, or trilinear (default=majority when downsampling, trilinear when upsampling)")
+
+	sdfCuts cutFlags
 )
 
+func init() {
+	flag.Var(&sdfCuts, "cut", "Primitive cut such as 'sphere 0,0,0 5; box 0,0,0 10,2,2' or the name of a .sdf job file (may be repeated)")
+}
+
+// cutFlags holds the values of the repeatable -cut flag.
+type cutFlags []string
+
+func (c *cutFlags) String() string { return strings.Join(*c, "; ") }
+
+func (c *cutFlags) Set(v string) error {
+	*c = append(*c, v)
+	return nil
+}
+
 func main() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
@@ -61,21 +94,24 @@ func main() {
 	}
 
 	for i := 1; i < flag.NArg(); i++ {
-		cut, err := binvox.Read(flag.Arg(i), *startX, *startY, *startZ, *countX, *countY, *countZ)
-		if err != nil {
+		cut, err := readCut(flag.Arg(i), base)
+		var alignErr *binvox.MisalignedError
+		switch {
+		case errors.As(err, &alignErr):
+			log.Fatalf("%q: %v (use -align=snap or -align=resample)", flag.Arg(i), err)
+		case err != nil:
 			log.Printf("skipping: %v", err)
 			continue
 		}
+		applyCut(base, cut, flag.Arg(i))
+	}
 
-		log.Printf("\n\nCutting voxel model with %q...", flag.Arg(i))
-		base.WhiteVoxels, err = voxcut(base, cut)
+	for _, spec := range sdfCuts {
+		cut, err := sdfCut(spec, base)
 		if err != nil {
-			log.Fatalf("arg #%v: cut with %q: %v", i, flag.Arg(i), err)
-		}
-		if len(base.WhiteVoxels) == 0 {
-			log.Fatal("result of cut leaves no non-zero voxels... no need to write file")
+			log.Fatalf("-cut %q: %v", spec, err)
 		}
-		log.Printf("Done cutting voxel model with %q.", flag.Arg(i))
+		applyCut(base, cut, spec)
 	}
 
 	if *binVOXFile != "" {
@@ -123,10 +159,131 @@ func main() {
 	log.Println("Done.")
 }
 
+// applyCut cuts the base by cut, which is described by name.
+func applyCut(base, cut *binvox.BinVOX, name string) {
+	log.Printf("\n\nCutting voxel model with %q...", name)
+	var err error
+	base.WhiteVoxels, err = voxcut(base, cut)
+	if err != nil {
+		log.Fatalf("cut with %q: %v", name, err)
+	}
+	if len(base.WhiteVoxels) == 0 {
+		log.Fatal("result of cut leaves no non-zero voxels... no need to write file")
+	}
+	log.Printf("Done cutting voxel model with %q.", name)
+}
+
+// sdfCut voxelizes the primitives described by spec (or the .sdf job file
+// named by spec) onto the voxel grid of base.
+func sdfCut(spec string, base *binvox.BinVOX) (*binvox.BinVOX, error) {
+	var s sdf.SDF
+	var err error
+	if strings.ToLower(filepath.Ext(spec)) == ".sdf" {
+		s, err = sdf.ParseFile(spec)
+	} else {
+		s, err = sdf.Parse(spec)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cut := binvox.New(base.NX, base.NY, base.NZ, base.TX, base.TY, base.TZ, base.Scale, false)
+	if err := sdf.Voxelize(cut, s); err != nil {
+		return nil, err
+	}
+	return cut, nil
+}
+
+// readCut reads the cut and aligns it to the voxel grid of base. It
+// returns a *binvox.MisalignedError if the cut is misaligned and -align
+// was not given.
+func readCut(filename string, base *binvox.BinVOX) (*binvox.BinVOX, error) {
+	cut, err := binvox.Read(filename, *startX, *startY, *startZ, *countX, *countY, *countZ)
+	if err != nil {
+		return nil, err
+	}
+
+	var scaleErr *binvox.ScaleMismatchError
+	var alignErr *binvox.MisalignedError
+	err = binvox.CheckGrid(base, cut)
+	switch {
+	case err == nil:
+	case errors.As(err, &scaleErr):
+		return resample(filename, base, scaleErr.VPMM, scaleErr.OtherVPMM)
+	case errors.As(err, &alignErr):
+		switch *align {
+		case "snap":
+			residual, err := binvox.SnapToGrid(base, cut)
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("Snapped %q onto the voxel grid of the base, incurring an error of (%v,%v,%v) mm", filename, residual.X, residual.Y, residual.Z)
+		case "resample":
+			log.Printf("Resampling %q onto the voxel grid of the base, which is misaligned by (%v,%v,%v) mm", filename, alignErr.Residual.X, alignErr.Residual.Y, alignErr.Residual.Z)
+			vpmm := base.VoxelsPerMM()
+			return resample(filename, base, vpmm, vpmm)
+		default:
+			return nil, alignErr
+		}
+	default:
+		return nil, err
+	}
+
+	// The start indices and counts refer to the base, so re-read the
+	// cut using the indices of the cut if the two are offset.
+	dx, dy, dz, err := binvox.GridOffset(cut, base)
+	if err != nil {
+		return nil, err
+	}
+	if (dx == 0 && dy == 0 && dz == 0) || (*startX == 0 && *startY == 0 && *startZ == 0 && *countX == 0 && *countY == 0 && *countZ == 0) {
+		return cut, nil // already read the correct voxels
+	}
+	shift := func(s, c, d int) (int, int) {
+		if c == 0 {
+			return 0, 0 // read everything
+		}
+		if s+d < 0 {
+			if c += s + d; c < 1 {
+				c = 1
+			}
+			return 0, c
+		}
+		return s + d, c
+	}
+	sx, cx := shift(*startX, *countX, dx)
+	sy, cy := shift(*startY, *countY, dy)
+	sz, cz := shift(*startZ, *countZ, dz)
+	offsetCut, err := binvox.Read(filename, sx, sy, sz, cx, cy, cz)
+	if err != nil {
+		return nil, err
+	}
+	offsetCut.TX, offsetCut.TY, offsetCut.TZ = cut.TX, cut.TY, cut.TZ // retain any snapping
+	return offsetCut, nil
+}
+
+// resample reads the full cut and resamples it onto the voxel grid of base.
+// The start indices and counts do not apply to the cut since it has a
+// different resolution or alignment.
+func resample(filename string, base *binvox.BinVOX, vpmm, cvpmm float64) (*binvox.BinVOX, error) {
+	cut, err := binvox.Read(filename, 0, 0, 0, 0, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	filter := binvox.Trilinear
+	if cvpmm > vpmm {
+		filter = binvox.Majority
+	}
+	if *filterName != "" {
+		if filter, err = binvox.ParseFilter(*filterName); err != nil {
+			return nil, err
+		}
+	}
+	return cut.ResampleOnto(base, filter)
+}
+
 // voxcut cuts the base voxels by the cut voxels and returns the newVoxels.
 // It accounts for different translation settings in the base and cutting voxels.
 func voxcut(base, cut *binvox.BinVOX) (newVoxels binvox.WhiteVoxelMap, err error) {
-	if len(cut.WhiteVoxels) == 0 {
+	if len(cut.WhiteVoxels) == 0 && len(cut.ColorVoxels) == 0 {
 		return base.WhiteVoxels, nil // Nothing to cut.
 	}
 	if len(base.WhiteVoxels) == 0 {
@@ -134,16 +291,16 @@ func voxcut(base, cut *binvox.BinVOX) (newVoxels binvox.WhiteVoxelMap, err error
 	}
 
 	newVoxels = binvox.WhiteVoxelMap{}
-	vpmm := base.VoxelsPerMM()
 
 	// Map base indices to cut indices by taking into account the translations.
-	dx := int((base.TX - cut.TX) * vpmm)
-	dy := int((base.TY - cut.TY) * vpmm)
-	dz := int((base.TZ - cut.TZ) * vpmm)
+	dx, dy, dz, err := binvox.GridOffset(cut, base)
+	if err != nil {
+		return nil, err
+	}
 	log.Printf("Translating cut voxels by [%v,%v,%v]", dx, dy, dz)
 
 	keyFunc := func(v binvox.Key, vc binvox.Color) {
-		c, ok := cut.Get(v.X, v.Y, v.Z)
+		c, ok := cut.Get(v.X+dx, v.Y+dy, v.Z+dz)
 		if !ok { // Nothing to cut - keep voxel.
 			newVoxels[v] = struct{}{}
 			return
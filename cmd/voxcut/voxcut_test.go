@@ -1,5 +1,12 @@
 package main
 
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
 // TODO: Figure out what it means to support full color.
 /*
 func TestVoxCut(t *testing.T) {
@@ -45,3 +52,52 @@ func TestVoxCut(t *testing.T) {
 	}
 }
 */
+
+// writeCube writes a model of n^3 voxels covering a 4mm cube whose voxels
+// with X less than solidX are solid.
+func writeCube(t *testing.T, filename string, n, solidX int) {
+	t.Helper()
+	b := binvox.New(n, n, n, 0, 0, 0, 4, false)
+	for z := 0; z < n; z++ {
+		for y := 0; y < n; y++ {
+			for x := 0; x < solidX; x++ {
+				b.Add(x, y, z)
+			}
+		}
+	}
+	if err := b.Write(filename, 0, 0, 0, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResampledCut(t *testing.T) {
+	defer func(f string) { *filterName = f }(*filterName)
+	dir := t.TempDir()
+	baseFile, cutFile := filepath.Join(dir, "base.binvox"), filepath.Join(dir, "cut.binvox")
+	writeCube(t, baseFile, 4, 4)
+	// The cut has twice the resolution of the base and covers its first 2mm in X.
+	writeCube(t, cutFile, 8, 4)
+
+	for _, filter := range []string{"majority", "average", "nearest"} {
+		t.Run(filter, func(t *testing.T) {
+			*filterName = filter
+			base, err := binvox.Read(baseFile, 0, 0, 0, 0, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cut, err := readCut(cutFile, base)
+			if err != nil {
+				t.Fatalf("readCut: %v", err)
+			}
+			applyCut(base, cut, cutFile)
+			if got, want := len(base.WhiteVoxels), 32; got != want {
+				t.Errorf("cut leaves %v voxels, want %v", got, want)
+			}
+			for k := range base.WhiteVoxels {
+				if k.X < 2 {
+					t.Errorf("voxel %v was not cut", k)
+				}
+			}
+		})
+	}
+}
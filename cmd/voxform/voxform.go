@@ -0,0 +1,178 @@
+// voxform transforms 'binvox' files by scaling, mirroring, rotating,
+// and translating them.
+//
+// The transforms are applied in that order, about the world space
+// origin (so that diced subregions transformed identically still tile
+// seamlessly), unless -center is given, in which case the transformed
+// model is moved so that it remains centered where it started.
+//
+// Quarter turn rotations, mirroring, integer scaling and translation
+// are lossless. Arbitrary rotations resample the model.
+//
+// Usage:
+//
+//	voxform [options] model.binvox
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+var (
+	binVOXFile    = flag.String("obinvox", "", "The output binvox filename to create")
+	stlFile       = flag.String("ostl", "", "The output stl filename to create")
+	scale         = flag.Int("scale", 1, "Integer scale factor")
+	mirror        = flag.String("mirror", "", "Comma separated list of axes to mirror across, e.g. 'x,z'")
+	rot90         = flag.String("rot90", "", "Comma separated list of quarter turn rotations as axis:turns, e.g. 'x:1,-z:2' (right-hand rule)")
+	rotate        = flag.String("rotate", "", "Arbitrary rotation as 'ax,ay,az,degrees' about the axis (ax,ay,az) (right-hand rule)")
+	filterName    = flag.String("filter", "trilinear", "Filter used for arbitrary rotations: nearest or trilinear")
+	translate     = flag.String("translate", "", "Translation in whole voxels as 'dx,dy,dz'")
+	center        = flag.Bool("center", false, "Keep the model centered where it started instead of transforming about the origin")
+	smoothDegrees = flag.Float64("smooth", 0, "Degrees used for smoothing normals (0=no smoothing)")
+	manifold      = flag.Bool("manifold", false, "Output manifold mesh - useful for low-res models")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\t%v [options] model.binvox\n\nOptions:\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		log.Fatal("Must supply exactly one filename")
+	}
+	if *binVOXFile == "" && *stlFile == "" {
+		log.Fatal("Must specify at least one of -obinvox or -ostl")
+	}
+
+	model, err := binvox.Read(flag.Arg(0), 0, 0, 0, 0, 0, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	oldCenter := model.MBB().Center()
+
+	if err := model.ScaleInt(*scale); err != nil {
+		log.Fatalf("ScaleInt: %v", err)
+	}
+
+	if *mirror != "" {
+		for _, s := range strings.Split(*mirror, ",") {
+			axis, err := binvox.ParseAxis(s)
+			if err != nil {
+				log.Fatalf("Unable to parse -mirror: %v", err)
+			}
+			model.Mirror(axis)
+		}
+	}
+
+	if *rot90 != "" {
+		for _, s := range strings.Split(*rot90, ",") {
+			parts := strings.Split(s, ":")
+			if len(parts) != 2 {
+				log.Fatalf("Unable to parse -rot90: incorrect format: %q", s)
+			}
+			axis, err := binvox.ParseAxis(parts[0])
+			if err != nil {
+				log.Fatalf("Unable to parse -rot90: %v", err)
+			}
+			turns, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+			if err != nil {
+				log.Fatalf("Unable to parse -rot90: invalid turns %q: %v", parts[1], err)
+			}
+			model.Rotate90(axis, turns)
+		}
+	}
+
+	if *rotate != "" {
+		v, err := parseFloats(*rotate, 4)
+		if err != nil {
+			log.Fatalf("Unable to parse -rotate: %v", err)
+		}
+		filter, err := binvox.ParseFilter(*filterName)
+		if err != nil {
+			log.Fatalf("Unable to parse -filter: %v", err)
+		}
+		if model, err = model.Rotate(gl.V(v[0], v[1], v[2]), v[3], filter); err != nil {
+			log.Fatalf("Rotate: %v", err)
+		}
+	}
+
+	if *translate != "" {
+		v, err := parseFloats(*translate, 3)
+		if err != nil {
+			log.Fatalf("Unable to parse -translate: %v", err)
+		}
+		for _, f := range v {
+			if f != math.Trunc(f) {
+				log.Fatalf("Unable to parse -translate: %v is not a whole number of voxels", f)
+			}
+		}
+		model.Translate(int(v[0]), int(v[1]), int(v[2]))
+	}
+
+	if *center {
+		d := oldCenter.Sub(model.MBB().Center())
+		log.Printf("Moving model by (%v,%v,%v) mm to keep it centered at (%v,%v,%v)", d.X, d.Y, d.Z, oldCenter.X, oldCenter.Y, oldCenter.Z)
+		model.TX += d.X
+		model.TY += d.Y
+		model.TZ += d.Z
+	}
+
+	if *binVOXFile != "" {
+		log.Printf("Writing file %q...", *binVOXFile)
+		if err := model.Write(*binVOXFile, 0, 0, 0, 0, 0, 0); err != nil {
+			log.Fatalf("binvox.Write(%q): %v", *binVOXFile, err)
+		}
+	}
+
+	if *stlFile != "" {
+		var mesh *gl.Mesh
+		if *manifold {
+			mesh = model.ManifoldMesh()
+		} else {
+			mesh = model.ToMesh()
+		}
+
+		if *smoothDegrees > 0 {
+			log.Printf("Smoothing mesh normals with %v degree threshold...", *smoothDegrees)
+			mesh.SmoothNormalsThreshold(gl.Radians(*smoothDegrees))
+			log.Println("Done smoothing mesh normals.")
+		}
+
+		log.Printf("Writing file %v ...", *stlFile)
+		if err := mesh.SaveSTL(*stlFile); err != nil {
+			log.Fatalf("SaveSTL: %v", err)
+		}
+	}
+
+	log.Println("Done.")
+}
+
+// parseFloats parses a comma separated list of exactly n numbers.
+func parseFloats(s string, n int) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("incorrect format: %q", s)
+	}
+	result := make([]float64, n)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %v: %v", part, err)
+		}
+		result[i] = v
+	}
+	return result, nil
+}
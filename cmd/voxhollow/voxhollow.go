@@ -0,0 +1,129 @@
+// voxhollow hollows out 'binvox' files for resin and powder printing.
+//
+// A wall of the requested thickness is retained around the cavity,
+// which can optionally be filled with a lattice (cubic, or a gyroid,
+// Schwarz-P or diamond minimal surface for lightweighting). Lattices
+// are evaluated in world space so diced regions tile seamlessly.
+// Drain holes can be punched at given points or at the lowest point
+// of each cavity.
+//
+// Usage:
+//
+//	voxhollow [options] model.binvox
+//	voxhollow -lattice gyroid -cell 8 -beam 0.8 -obinvox out.binvox model.binvox
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+var (
+	binVOXFile    = flag.String("obinvox", "", "The output binvox filename to create")
+	stlFile       = flag.String("ostl", "", "The output stl filename to create")
+	wall          = flag.Float64("wall", 2, "Wall thickness in millimeters")
+	lattice       = flag.String("lattice", "cubic", "Lattice infill: cubic, gyroid, schwarzp, or diamond")
+	cellSize      = flag.Float64("cell", 0, "Lattice cell size in millimeters (0=no lattice)")
+	beamWidth     = flag.Float64("beam", 1, "Lattice beam width (cubic) or wall thickness (gyroid, schwarzp, diamond) in millimeters")
+	drain         = flag.Float64("drain", 0, "Drain hole diameter in millimeters (0=no drain holes)")
+	holes         = flag.String("holes", "", "Semicolon separated list of drain hole locations in millimeters, e.g. '10,10,2;-10,-10,2' (empty=lowest point of each cavity)")
+	smoothDegrees = flag.Float64("smooth", 0, "Degrees used for smoothing normals (0=no smoothing)")
+	manifold      = flag.Bool("manifold", false, "Output manifold mesh - useful for low-res models")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\t%v [options] model.binvox\n\nOptions:\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		log.Fatal("Must supply exactly one filename")
+	}
+	if *binVOXFile == "" && *stlFile == "" {
+		log.Fatal("Must specify at least one of -obinvox or -ostl")
+	}
+
+	opts := &binvox.HollowOptions{WallThickness: *wall, DrainDiameter: *drain}
+	if *cellSize > 0 {
+		l, err := binvox.ParseLattice(*lattice)
+		if err != nil {
+			log.Fatalf("Unable to parse -lattice: %v", err)
+		}
+		if opts.Lattice, err = l.Func(*cellSize, *beamWidth); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *holes != "" {
+		var err error
+		if opts.DrainHoles, err = parsePoints(*holes); err != nil {
+			log.Fatalf("Unable to parse -holes: %v", err)
+		}
+	}
+
+	model, err := binvox.Read(flag.Arg(0), 0, 0, 0, 0, 0, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := model.Hollow(opts); err != nil {
+		log.Fatalf("Hollow: %v", err)
+	}
+
+	if *binVOXFile != "" {
+		log.Printf("Writing file %q...", *binVOXFile)
+		if err := model.Write(*binVOXFile, 0, 0, 0, 0, 0, 0); err != nil {
+			log.Fatalf("binvox.Write(%q): %v", *binVOXFile, err)
+		}
+	}
+
+	if *stlFile != "" {
+		var mesh *gl.Mesh
+		if *manifold {
+			mesh = model.ManifoldMesh()
+		} else {
+			mesh = model.ToMesh()
+		}
+
+		if *smoothDegrees > 0 {
+			log.Printf("Smoothing mesh normals with %v degree threshold...", *smoothDegrees)
+			mesh.SmoothNormalsThreshold(gl.Radians(*smoothDegrees))
+			log.Println("Done smoothing mesh normals.")
+		}
+
+		log.Printf("Writing file %v ...", *stlFile)
+		if err := mesh.SaveSTL(*stlFile); err != nil {
+			log.Fatalf("SaveSTL: %v", err)
+		}
+	}
+
+	log.Println("Done.")
+}
+
+// parsePoints parses a semicolon separated list of points, e.g. "1,2,3;4,5,6".
+func parsePoints(s string) (points []gl.Vector, err error) {
+	for _, p := range strings.Split(s, ";") {
+		parts := strings.Split(p, ",")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("incorrect format: %q", p)
+		}
+		var v [3]float64
+		for i, part := range parts {
+			if v[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64); err != nil {
+				return nil, fmt.Errorf("invalid number %v: %v", part, err)
+			}
+		}
+		points = append(points, gl.V(v[0], v[1], v[2]))
+	}
+	return points, nil
+}
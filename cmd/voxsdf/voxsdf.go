@@ -0,0 +1,74 @@
+// voxsdf computes the signed distance field of a 'binvox' file and writes
+// it out as a raw float volume.
+//
+// To process a single region of a diced model (see stldice), pass the
+// neighboring regions after the region of interest so that the halo
+// is filled in with their voxels.
+//
+// Usage:
+//
+//	voxsdf [options] region.binvox [neighbor1.binvox [neighbor2.binvox ...]]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+var (
+	outFile = flag.String("o", "", "The output distance field filename to create (default=region name with .sdf extension)")
+	halo    = flag.Int("halo", 0, "Number of halo voxels to process on every side of the region")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\t%v [options] region.binvox [neighbor1.binvox [neighbor2.binvox ...]]\n\nOptions:\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		log.Fatal("Must supply at least one filename")
+	}
+	if *outFile == "" {
+		*outFile = strings.TrimSuffix(flag.Arg(0), ".binvox") + ".sdf"
+	}
+
+	model, err := binvox.Read(flag.Arg(0), 0, 0, 0, 0, 0, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var neighbors []*binvox.BinVOX
+	for i := 1; i < flag.NArg(); i++ {
+		neighbor, err := binvox.Read(flag.Arg(i), 0, 0, 0, 0, 0, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		neighbors = append(neighbors, neighbor)
+	}
+	if len(neighbors) > 0 {
+		if model, err = model.WithHalo(*halo, neighbors...); err != nil {
+			log.Fatalf("WithHalo: %v", err)
+		}
+	}
+
+	field, err := model.SignedDistanceField(*halo)
+	if err != nil {
+		log.Fatalf("SignedDistanceField: %v", err)
+	}
+
+	log.Printf("Writing file %q...", *outFile)
+	if err := field.Write(*outFile); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Done.")
+}
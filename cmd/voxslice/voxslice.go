@@ -0,0 +1,188 @@
+// voxslice writes the layer images of a model for resin (DLP/SLA/MSLA)
+// printers as a zip archive of PNG files plus the configuration file
+// expected by the printer (see the -profile flag).
+//
+// The model may be an STL file (which is voxelized at the resolution of
+// the printer) or one or more 'binvox' files that are treated as
+// subregions of a single model (such as those written by stldice).
+//
+// If the -o filename ends in ".svg" or ".cli", the contours of each layer
+// are instead written as vectors: as one SVG group per layer or in the
+// Common Layer Interface (CLI) format used by laser cutters and SLS
+// machines. Only the layer height of the profile is used in this mode.
+//
+// Usage:
+//
+//	voxslice -profile sl1 -o model.sl1 model.stl
+//	voxslice -profile sl1 -o model.svg model.stl
+//	voxslice -profile my-printer.json -o model.zip region1.binvox [region2.binvox ...]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+	"github.com/gmlewis/stldice/v4/slicer"
+)
+
+var (
+	outFile     = flag.String("o", "", "The output archive filename to create (or .svg or .cli file of layer contours)")
+	profileName = flag.String("profile", "sl1", fmt.Sprintf("Printer profile: one of %v, or the name of a JSON profile file", strings.Join(slicer.ProfileNames(), ", ")))
+	jobName     = flag.String("job", "", "Name of the print job (default=base name of -o)")
+	antiAlias   = flag.Int("aa", 0, "Anti-aliasing samples along each axis of a pixel (0=use profile)")
+	mirrorX     = flag.Bool("mirrorx", false, "Mirror the layer images horizontally (in addition to the profile)")
+	mirrorY     = flag.Bool("mirrory", false, "Mirror the layer images vertically (in addition to the profile)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\t%v [options] model.stl\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\t%v [options] region1.binvox [region2.binvox ...]\n\nOptions:\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatal("Must supply at least one filename")
+	}
+	if *outFile == "" {
+		log.Fatal("Must specify -o")
+	}
+
+	p, err := slicer.LoadProfile(*profileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	profile := *p // copy so that the built-in profiles are not modified
+	if *antiAlias > 0 {
+		profile.AntiAlias = *antiAlias
+	}
+	profile.MirrorX = profile.MirrorX != *mirrorX
+	profile.MirrorY = profile.MirrorY != *mirrorY
+
+	switch ext := strings.ToLower(filepath.Ext(*outFile)); ext {
+	case ".svg", ".cli":
+		if err := writeContours(*outFile, ext, profile.LayerHeight); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Done.")
+		return
+	}
+
+	var regions []*binvox.BinVOX
+	if strings.ToLower(filepath.Ext(flag.Arg(0))) == ".stl" {
+		if flag.NArg() != 1 {
+			log.Fatal("Must supply exactly one STL file")
+		}
+		model, err := voxelize(flag.Arg(0), &profile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		regions = append(regions, model)
+	} else {
+		for _, arg := range flag.Args() {
+			model, err := binvox.Read(arg, 0, 0, 0, 0, 0, 0)
+			if err != nil {
+				log.Fatal(err)
+			}
+			regions = append(regions, model)
+		}
+	}
+
+	s, err := slicer.New(&profile, regions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	job := *jobName
+	if job == "" {
+		job = strings.TrimSuffix(filepath.Base(*outFile), filepath.Ext(*outFile))
+	}
+	if err := s.WriteFile(*outFile, job); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Done.")
+}
+
+// writeContours writes the contours of each layer of the model to the
+// named SVG or CLI file.
+func writeContours(filename, ext string, layerHeight float64) error {
+	var mesh *gl.Mesh
+	if strings.ToLower(filepath.Ext(flag.Arg(0))) == ".stl" {
+		if flag.NArg() != 1 {
+			return fmt.Errorf("must supply exactly one STL file")
+		}
+		log.Printf("Loading file %q...", flag.Arg(0))
+		m, err := gl.LoadSTL(flag.Arg(0))
+		if err != nil {
+			return fmt.Errorf("unable to load file %q: %v", flag.Arg(0), err)
+		}
+		mesh = m
+	} else {
+		// Merge the regions so that no contours are found along their seams.
+		var model *binvox.BinVOX
+		for _, arg := range flag.Args() {
+			r, err := binvox.Read(arg, 0, 0, 0, 0, 0, 0)
+			if err != nil {
+				return err
+			}
+			if model == nil {
+				model = r
+				continue
+			}
+			if err := model.Union(r); err != nil {
+				return fmt.Errorf("%v: %v", arg, err)
+			}
+		}
+		mesh = model.ManifoldMesh()
+	}
+
+	layers := binvox.ContourLayers(mesh, layerHeight)
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("unable to create file %q: %v", filename, err)
+	}
+	write := slicer.WriteSVG
+	if ext == ".cli" {
+		write = slicer.WriteCLI
+	}
+	if err := write(f, layers); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write %q: %v", filename, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close %q: %v", filename, err)
+	}
+	log.Printf("Done writing the contours of %v layers to file %q.", len(layers), filename)
+	return nil
+}
+
+// voxelize loads an STL file and voxelizes it with voxels no larger than
+// the pixels or layers of the printer.
+func voxelize(filename string, profile *slicer.Profile) (*binvox.BinVOX, error) {
+	log.Printf("Loading file %q...", filename)
+	mesh, err := gl.LoadSTL(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load file %q: %v", filename, err)
+	}
+	mbb := mesh.BoundingBox()
+	size := mbb.Size()
+	vpmm := 1 / math.Min(profile.PixelPitch, profile.LayerHeight)
+	nx := int(math.Ceil(size.X * vpmm))
+	ny := int(math.Ceil(size.Y * vpmm))
+	nz := int(math.Ceil(size.Z * vpmm))
+	model := binvox.New(nx, ny, nz, mbb.Min.X, mbb.Min.Y, mbb.Min.Z, 0, false)
+	model.Scale = float64(model.Dim()) / vpmm
+	if err := model.Voxelize(mesh); err != nil {
+		return nil, fmt.Errorf("voxelize: %v", err)
+	}
+	return model, nil
+}
@@ -0,0 +1,87 @@
+// voxstat reports the mass properties (volume, surface area, centroid,
+// and inertia tensor) of voxel models.
+//
+// All files on the command line are treated as subregions of a single
+// model (such as those written by stldice) and may be any mix of
+// 'binvox', 'vsh' (vshell), and 'svx' files.
+//
+// Usage:
+//
+//	voxstat [options] region1.binvox [region2.vsh region3.svx ...]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gmlewis/stldice/v4/binvox"
+	"github.com/gmlewis/stldice/v4/svx"
+	"github.com/gmlewis/stldice/v4/vshell"
+)
+
+var (
+	density = flag.Float64("density", 1.24, "Material density in grams per cubic centimeter (1.24=PLA)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\t%v [options] region1.binvox [region2.vsh region3.svx ...]\n\nOptions:\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatal("Must supply at least one filename")
+	}
+
+	var stats *binvox.Stats
+	for _, arg := range flag.Args() {
+		model, err := read(arg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s := model.Stats(*density)
+		if stats == nil {
+			stats = s
+			continue
+		}
+		if err := stats.Add(s); err != nil {
+			log.Fatalf("%v: %v", arg, err)
+		}
+	}
+
+	c := stats.Centroid()
+	fmt.Printf("voxels: %v (%.6f mm per voxel)\n", stats.Voxels, stats.VoxelSize)
+	fmt.Printf("volume: %.3f mm^3\n", stats.Volume())
+	fmt.Printf("surface area: %.3f mm^2\n", stats.SurfaceArea())
+	fmt.Printf("mass: %.3f g (density %v g/cm^3)\n", stats.Mass(), stats.Density)
+	fmt.Printf("mbb: (%.3f,%.3f,%.3f)-(%.3f,%.3f,%.3f)\n",
+		stats.MBB.Min.X, stats.MBB.Min.Y, stats.MBB.Min.Z,
+		stats.MBB.Max.X, stats.MBB.Max.Y, stats.MBB.Max.Z)
+	fmt.Printf("centroid: (%.3f,%.3f,%.3f)\n", c.X, c.Y, c.Z)
+	fmt.Println("inertia tensor about centroid (g*mm^2):")
+	for _, row := range stats.Inertia() {
+		fmt.Printf("\t%14.3f %14.3f %14.3f\n", row[0], row[1], row[2])
+	}
+}
+
+// read reads a binvox, vshell, or svx file based on its extension.
+func read(filename string) (*binvox.BinVOX, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".svx":
+		return svx.Read(filename)
+	case ".vsh":
+		vs, err := vshell.Read(filename, 0, 0, 0, 0, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		return vs.ToBinVOX(), nil
+	default:
+		return binvox.Read(filename, 0, 0, 0, 0, 0, 0)
+	}
+}
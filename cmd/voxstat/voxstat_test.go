@@ -0,0 +1,82 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// writeSVX writes a Y-up SVX file (as written by stl2svx) with 0.5mm
+// voxels whose slices are 4x2 voxels. All slices are solid except the
+// last, which only has its first row of voxels.
+func writeSVX(t *testing.T, filename string, slices int) {
+	t.Helper()
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	mf, err := zw.Create("manifest.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(mf, `<?xml version="1.0"?>
+<grid version="1.0" gridSizeX="4" gridSizeY="%v" gridSizeZ="2" voxelSize="0.0005" subvoxelBits="8" originX="0.001" originY="-0.003" originZ="0.002" slicesOrientation="Y" >
+  <channels>
+    <channel type="DENSITY" bits="8" slices="density/slice%%04d.png" />
+  </channels>
+</grid>
+`, slices)
+	for z := 0; z < slices; z++ {
+		img := image.NewGray(image.Rect(0, 0, 4, 2))
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 4; x++ {
+				if z < slices-1 || y == 0 {
+					img.Set(x, y, color.White)
+				}
+			}
+		}
+		w, err := zw.Create(fmt.Sprintf("density/slice%04d.png", z))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(w, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadSVX(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "model.svx")
+	writeSVX(t, filename, 3)
+
+	model, err := read(filename)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	s := model.Stats(1)
+	if s.Voxels != 20 {
+		t.Errorf("Voxels = %v, want 20", s.Voxels)
+	}
+	if got, want := s.Volume(), 2.5; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Volume = %v mm^3, want %v", got, want)
+	}
+	want := gl.Box{Min: gl.V(1, 2, -3), Max: gl.V(3, 3, -1.5)}
+	if s.MBB.Min.Sub(want.Min).Length() > 1e-9 || s.MBB.Max.Sub(want.Max).Length() > 1e-9 {
+		t.Errorf("MBB = %v, want %v", s.MBB, want)
+	}
+}
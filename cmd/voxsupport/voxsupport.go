@@ -0,0 +1,114 @@
+// voxsupport finds the overhangs of 'binvox' files for a given build
+// direction and generates simple vertical support pillars beneath them.
+//
+// Usage:
+//
+//	voxsupport [options] model.binvox
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+var (
+	supportFile   = flag.String("osupport", "", "The output binvox filename to create containing only the supports")
+	binVOXFile    = flag.String("obinvox", "", "The output binvox filename to create containing the model and its supports")
+	stlFile       = flag.String("ostl", "", "The output stl filename to create containing the model and its supports")
+	axis          = flag.String("axis", "+z", "Build direction: +x, -x, +y, -y, +z or -z")
+	angle         = flag.Float64("angle", 45, "Maximum self-supporting overhang angle in degrees from the build direction")
+	spacing       = flag.Int("spacing", 1, "Distance between support pillars in voxels")
+	width         = flag.Int("width", 1, "Width of each support pillar in voxels")
+	smoothDegrees = flag.Float64("smooth", 0, "Degrees used for smoothing normals (0=no smoothing)")
+	manifold      = flag.Bool("manifold", false, "Output manifold mesh - useful for low-res models")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\t%v [options] model.binvox\n\nOptions:\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		log.Fatal("Must supply exactly one filename")
+	}
+
+	a, err := binvox.ParseAxis(*axis)
+	if err != nil {
+		log.Fatalf("Unable to parse -axis: %v", err)
+	}
+
+	model, err := binvox.Read(flag.Arg(0), 0, 0, 0, 0, 0, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	overhangs, err := model.Overhangs(a, *angle)
+	if err != nil {
+		log.Fatalf("Overhangs: %v", err)
+	}
+	fmt.Printf("%v: %v overhanging voxels when built in the %v direction\n", flag.Arg(0), len(overhangs), a)
+
+	if *supportFile == "" && *binVOXFile == "" && *stlFile == "" {
+		log.Println("Done.")
+		return
+	}
+
+	supports, err := model.Supports(&binvox.SupportOptions{Axis: a, MaxAngle: *angle, Spacing: *spacing, PillarWidth: *width})
+	if err != nil {
+		log.Fatalf("Supports: %v", err)
+	}
+
+	if *supportFile != "" {
+		log.Printf("Writing file %q...", *supportFile)
+		if err := supports.Write(*supportFile, 0, 0, 0, 0, 0, 0); err != nil {
+			log.Fatalf("binvox.Write(%q): %v", *supportFile, err)
+		}
+	}
+
+	if *binVOXFile == "" && *stlFile == "" {
+		log.Println("Done.")
+		return
+	}
+
+	if err := model.Union(supports); err != nil {
+		log.Fatalf("Union: %v", err)
+	}
+
+	if *binVOXFile != "" {
+		log.Printf("Writing file %q...", *binVOXFile)
+		if err := model.Write(*binVOXFile, 0, 0, 0, 0, 0, 0); err != nil {
+			log.Fatalf("binvox.Write(%q): %v", *binVOXFile, err)
+		}
+	}
+
+	if *stlFile != "" {
+		var mesh *gl.Mesh
+		if *manifold {
+			mesh = model.ManifoldMesh()
+		} else {
+			mesh = model.ToMesh()
+		}
+
+		if *smoothDegrees > 0 {
+			log.Printf("Smoothing mesh normals with %v degree threshold...", *smoothDegrees)
+			mesh.SmoothNormalsThreshold(gl.Radians(*smoothDegrees))
+			log.Println("Done smoothing mesh normals.")
+		}
+
+		log.Printf("Writing file %v ...", *stlFile)
+		if err := mesh.SaveSTL(*stlFile); err != nil {
+			log.Fatalf("SaveSTL: %v", err)
+		}
+	}
+
+	log.Println("Done.")
+}
@@ -0,0 +1,67 @@
+// voxthick analyzes the wall thickness of 'binvox' files and reports
+// all regions that are thinner than the given minimum.
+//
+// Optionally, a colored PLY mesh is written with the thin regions
+// highlighted, ready for inspection in any mesh viewer.
+//
+// Usage:
+//
+//	voxthick [options] model.binvox
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+var (
+	minThickness = flag.Float64("min", 1, "Minimum wall thickness in millimeters")
+	plyFile      = flag.String("oply", "", "The output PLY filename to create with thin regions highlighted in red")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\t%v [options] model.binvox\n\nOptions:\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		log.Fatal("Must supply exactly one filename")
+	}
+
+	model, err := binvox.Read(flag.Arg(0), 0, 0, 0, 0, 0, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	thickness, err := model.Thickness()
+	if err != nil {
+		log.Fatalf("Thickness: %v", err)
+	}
+
+	regions := model.ThinRegions(thickness, *minThickness)
+	fmt.Printf("%v: %v regions thinner than %v mm\n", flag.Arg(0), len(regions), *minThickness)
+	for i, r := range regions {
+		fmt.Printf("region #%v: %v voxels, min thickness %.3f mm, mbb=(%.3f,%.3f,%.3f)-(%.3f,%.3f,%.3f)\n",
+			i+1, len(r.Voxels), r.MinThickness,
+			r.MBB.Min.X, r.MBB.Min.Y, r.MBB.Min.Z,
+			r.MBB.Max.X, r.MBB.Max.Y, r.MBB.Max.Z)
+	}
+
+	if *plyFile != "" {
+		mesh := model.Highlight(thickness, *minThickness, binvox.Color{R: 1, A: 1}).ToMesh()
+		log.Printf("Writing file %v ...", *plyFile)
+		if err := binvox.SavePLY(*plyFile, mesh); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	log.Println("Done.")
+}
@@ -7,6 +7,7 @@ require (
 	github.com/chrislusf/gleam v0.0.0-20210513221725-ec443283eab3
 	github.com/fogleman/fauxgl v0.0.0-20200818143847-27cddc103802
 	github.com/golang/protobuf v1.5.4
+	golang.org/x/image v0.40.0
 	golang.org/x/net v0.56.0
 	golang.org/x/oauth2 v0.36.0
 	google.golang.org/api v0.114.0
@@ -0,0 +1,180 @@
+// Package raster creates voxel models from raster images, such as
+// grayscale heightmaps (for lithophanes and embossing) and stacks of
+// slice images (like the slices within SVX files).
+package raster
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png" // register the PNG format
+	"log"
+	"math"
+	"os"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+	_ "golang.org/x/image/tiff" // register the TIFF format
+)
+
+// HeightmapOptions controls how a heightmap is converted to a voxel model.
+type HeightmapOptions struct {
+	// MMPerPixel is the size of each pixel (and therefore of each voxel)
+	// in millimeters.
+	MMPerPixel float64
+
+	// MaxHeight is the height (in millimeters) of a white pixel above
+	// the base. Black pixels have a height of zero.
+	MaxHeight float64
+
+	// BaseHeight is the thickness (in millimeters) of the solid base
+	// beneath the relief. A zero base leaves black pixels empty.
+	BaseHeight float64
+
+	// Invert makes dark pixels tall and light pixels short, as used
+	// for lithophanes.
+	Invert bool
+
+	// Origin is the world space location (in millimeters) of the
+	// bottom left corner of the image at the bottom of the base.
+	Origin gl.Vector
+}
+
+// Heightmap converts a grayscale heightmap into a voxel model whose
+// columns rise in +Z from the XY plane. The top row of the image is at
+// the maximum Y so that the image reads correctly when viewed from +Z.
+func Heightmap(img image.Image, opts *HeightmapOptions) (*binvox.BinVOX, error) {
+	if opts == nil || opts.MMPerPixel <= 0 {
+		return nil, fmt.Errorf("mm per pixel must be positive")
+	}
+	if opts.MaxHeight < 0 || opts.BaseHeight < 0 {
+		return nil, fmt.Errorf("heights must not be negative")
+	}
+	vpmm := 1 / opts.MMPerPixel
+	bounds := img.Bounds()
+	nx, ny := bounds.Dx(), bounds.Dy()
+	nz := int(math.Ceil((opts.BaseHeight+opts.MaxHeight)*vpmm - 1e-9))
+	if nx == 0 || ny == 0 || nz == 0 {
+		return nil, fmt.Errorf("model dimensions must be non-zero (%v,%v,%v)", nx, ny, nz)
+	}
+	log.Printf("Converting %vx%v heightmap to %v voxels high...", nx, ny, nz)
+
+	b := binvox.New(nx, ny, nz, opts.Origin.X, opts.Origin.Y, opts.Origin.Z, 0, false)
+	b.Scale = float64(b.Dim()) * opts.MMPerPixel
+	for y := 0; y < ny; y++ {
+		for x := 0; x < nx; x++ {
+			v := gray(img.At(bounds.Min.X+x, bounds.Min.Y+y))
+			if opts.Invert {
+				v = 1 - v
+			}
+			h := opts.BaseHeight + v*opts.MaxHeight
+			top := int(math.Round(h * vpmm))
+			for z := 0; z < top; z++ {
+				b.Add(x, ny-1-y, z)
+			}
+		}
+	}
+	log.Printf("Done converting heightmap; %v voxels.", len(b.WhiteVoxels))
+	return b, nil
+}
+
+// ReadHeightmap reads a PNG or TIFF heightmap and converts it into a
+// voxel model. See Heightmap.
+func ReadHeightmap(filename string, opts *HeightmapOptions) (*binvox.BinVOX, error) {
+	img, err := readImage(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Heightmap(img, opts)
+}
+
+// StackOptions controls how a stack of slice images is converted to a
+// voxel model.
+type StackOptions struct {
+	// VoxelSize is the size of each voxel (and pixel) in millimeters,
+	// which is also the distance between slices.
+	VoxelSize float64
+
+	// Threshold is the brightness (from 0 to 1) at or above which a
+	// pixel is solid. Zero means 0.5, as used by SVX files.
+	Threshold float64
+
+	// Origin is the world space location (in millimeters) of the
+	// corner of the first pixel of the first slice.
+	Origin gl.Vector
+}
+
+// Stack converts a stack of slice images (ordered from the bottom of the
+// model to the top) into a voxel model. Pixel (x,y) of slice z becomes
+// voxel (x,y,z), as with the slices of SVX files. All slices must have
+// the same dimensions.
+func Stack(slices []image.Image, opts *StackOptions) (*binvox.BinVOX, error) {
+	if len(slices) == 0 {
+		return nil, fmt.Errorf("no slices")
+	}
+	if opts == nil || opts.VoxelSize <= 0 {
+		return nil, fmt.Errorf("voxel size must be positive")
+	}
+	threshold := opts.Threshold
+	if threshold == 0 {
+		threshold = 0.5
+	}
+	bounds := slices[0].Bounds()
+	nx, ny, nz := bounds.Dx(), bounds.Dy(), len(slices)
+	if nx == 0 || ny == 0 {
+		return nil, fmt.Errorf("slice dimensions must be non-zero (%v,%v)", nx, ny)
+	}
+	log.Printf("Converting %v slices of %vx%v pixels...", nz, nx, ny)
+
+	b := binvox.New(nx, ny, nz, opts.Origin.X, opts.Origin.Y, opts.Origin.Z, 0, false)
+	b.Scale = float64(b.Dim()) * opts.VoxelSize
+	for z, img := range slices {
+		sb := img.Bounds()
+		if sb.Dx() != nx || sb.Dy() != ny {
+			return nil, fmt.Errorf("slice #%v is %vx%v pixels, want %vx%v", z, sb.Dx(), sb.Dy(), nx, ny)
+		}
+		for y := 0; y < ny; y++ {
+			for x := 0; x < nx; x++ {
+				if gray(img.At(sb.Min.X+x, sb.Min.Y+y)) >= threshold {
+					b.Add(x, y, z)
+				}
+			}
+		}
+	}
+	log.Printf("Done converting slices; %v voxels.", len(b.WhiteVoxels))
+	return b, nil
+}
+
+// ReadStack reads PNG or TIFF slice images (ordered from the bottom of
+// the model to the top) and converts them into a voxel model. See Stack.
+func ReadStack(filenames []string, opts *StackOptions) (*binvox.BinVOX, error) {
+	slices := make([]image.Image, 0, len(filenames))
+	for _, filename := range filenames {
+		img, err := readImage(filename)
+		if err != nil {
+			return nil, err
+		}
+		slices = append(slices, img)
+	}
+	return Stack(slices, opts)
+}
+
+// readImage reads a PNG or TIFF image.
+func readImage(filename string) (image.Image, error) {
+	log.Printf("Loading file %q...", filename)
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %q: %v", filename, err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode %q: %v", filename, err)
+	}
+	return img, nil
+}
+
+// gray returns the brightness of c from 0 (black) to 1 (white).
+func gray(c color.Color) float64 {
+	return float64(color.Gray16Model.Convert(c).(color.Gray16).Y) / 0xffff
+}
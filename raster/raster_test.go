@@ -0,0 +1,135 @@
+package raster
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+	"golang.org/x/image/tiff"
+)
+
+// ramp returns a 4x2 image whose top row brightens from left to right
+// and whose bottom row is black.
+func ramp() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		img.SetGray(x, 0, color.Gray{Y: uint8(85 * x)})
+	}
+	return img
+}
+
+// heights returns the number of voxels in each column of b.
+func heights(b *binvox.BinVOX) map[[2]int]int {
+	result := map[[2]int]int{}
+	for k := range b.WhiteVoxels {
+		result[[2]int{k.X, k.Y}]++
+	}
+	return result
+}
+
+func TestHeightmap(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *HeightmapOptions
+		want map[[2]int]int
+	}{
+		{
+			name: "relief",
+			opts: &HeightmapOptions{MMPerPixel: 0.5, MaxHeight: 1.5},
+			// Top row of the image is at the maximum Y.
+			want: map[[2]int]int{{1, 1}: 1, {2, 1}: 2, {3, 1}: 3},
+		},
+		{
+			name: "lithophane",
+			opts: &HeightmapOptions{MMPerPixel: 0.5, MaxHeight: 1.5, BaseHeight: 0.5, Invert: true},
+			want: map[[2]int]int{
+				{0, 1}: 4, {1, 1}: 3, {2, 1}: 2, {3, 1}: 1,
+				{0, 0}: 4, {1, 0}: 4, {2, 0}: 4, {3, 0}: 4,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Origin = gl.V(10, 20, 30)
+			b, err := Heightmap(ramp(), tt.opts)
+			if err != nil {
+				t.Fatalf("Heightmap: %v", err)
+			}
+			if got, want := b.VoxelsPerMM(), 2.0; got != want {
+				t.Errorf("VoxelsPerMM = %v, want %v", got, want)
+			}
+			if got, want := b.VoxelCenter(binvox.Key{}), gl.V(10.25, 20.25, 30.25); got != want {
+				t.Errorf("VoxelCenter = %v, want %v", got, want)
+			}
+			got := heights(b)
+			if len(got) != len(tt.want) {
+				t.Errorf("Heightmap = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("column %v has %v voxels, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+
+	if _, err := Heightmap(ramp(), &HeightmapOptions{MaxHeight: 1}); err == nil {
+		t.Error("Heightmap with zero mm per pixel = nil error, want error")
+	}
+}
+
+func TestReadStack(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, img image.Image, encode func(f *os.File, img image.Image) error) string {
+		filename := filepath.Join(dir, name)
+		f, err := os.Create(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	encodePNG := func(f *os.File, img image.Image) error { return png.Encode(f, img) }
+	encodeTIFF := func(f *os.File, img image.Image) error { return tiff.Encode(f, img, nil) }
+
+	filenames := []string{
+		write("slice0.png", ramp(), encodePNG),
+		write("slice1.tif", ramp(), encodeTIFF),
+	}
+	b, err := ReadStack(filenames, &StackOptions{VoxelSize: 0.1, Origin: gl.V(-1, -2, -3)})
+	if err != nil {
+		t.Fatalf("ReadStack: %v", err)
+	}
+	if b.NX != 4 || b.NY != 2 || b.NZ != 2 {
+		t.Errorf("dimensions = (%v,%v,%v), want (4,2,2)", b.NX, b.NY, b.NZ)
+	}
+	want := binvox.WhiteVoxelMap{}
+	for z := 0; z < 2; z++ {
+		for _, x := range []int{2, 3} { // brightness 170 and 255
+			want[binvox.Key{X: x, Y: 0, Z: z}] = struct{}{}
+		}
+	}
+	if len(b.WhiteVoxels) != len(want) {
+		t.Errorf("ReadStack = %v, want %v", b.WhiteVoxels, want)
+	}
+	for k := range want {
+		if _, ok := b.WhiteVoxels[k]; !ok {
+			t.Errorf("missing voxel %v", k)
+		}
+	}
+
+	if _, err := Stack([]image.Image{ramp(), image.NewGray(image.Rect(0, 0, 2, 2))}, &StackOptions{VoxelSize: 1}); err == nil {
+		t.Error("Stack with mismatched slices = nil error, want error")
+	}
+}
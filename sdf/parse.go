@@ -0,0 +1,183 @@
+package sdf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// Parse parses a textual description of a solid, as used by job files
+// (with the .sdf extension) and the -cut flag of voxcut.
+//
+// Each statement (separated by newlines or semicolons) describes one
+// primitive, and the solid is the union of all of them. Points are
+// written as comma-separated X,Y,Z values and all dimensions are in
+// millimeters. Text following a '#' is a comment. The statements are:
+//
+//	sphere X,Y,Z RADIUS
+//	box X1,Y1,Z1 X2,Y2,Z2
+//	cylinder X1,Y1,Z1 X2,Y2,Z2 RADIUS
+//	cone X1,Y1,Z1 X2,Y2,Z2 RADIUS1 RADIUS2
+//	torus X,Y,Z MAJOR MINOR
+//	capsule X1,Y1,Z1 X2,Y2,Z2 RADIUS
+//	smooth K
+//
+// "smooth K" smoothly blends all following primitives (until the next
+// smooth statement) with each other and with everything before them
+// using fillets of radius K. "smooth 0" returns to a plain union.
+func Parse(s string) (SDF, error) {
+	var shapes []SDF
+	var k float64
+	collapse := func() {
+		if len(shapes) < 2 {
+			return
+		}
+		if k > 0 {
+			shapes = []SDF{&SmoothUnion{K: k, Shapes: shapes}}
+		} else {
+			shapes = []SDF{Union(shapes)}
+		}
+	}
+
+	for i, line := range strings.Split(s, "\n") {
+		if n := strings.Index(line, "#"); n >= 0 {
+			line = line[:n]
+		}
+		for _, stmt := range strings.Split(line, ";") {
+			fields := strings.Fields(stmt)
+			if len(fields) == 0 {
+				continue
+			}
+			if strings.ToLower(fields[0]) == "smooth" {
+				if len(fields) != 2 {
+					return nil, fmt.Errorf("line %v: smooth takes 1 argument, got %v", i+1, len(fields)-1)
+				}
+				v, err := strconv.ParseFloat(fields[1], 64)
+				if err != nil || v < 0 {
+					return nil, fmt.Errorf("line %v: invalid smooth radius %q", i+1, fields[1])
+				}
+				collapse()
+				k = v
+				continue
+			}
+			shape, err := parseShape(fields)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v", i+1, err)
+			}
+			shapes = append(shapes, shape)
+		}
+	}
+
+	if len(shapes) == 0 {
+		return nil, fmt.Errorf("no primitives found")
+	}
+	collapse()
+	return shapes[0], nil
+}
+
+// ParseFile parses a job file. See Parse for its format.
+func ParseFile(filename string) (SDF, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %q: %v", filename, err)
+	}
+	s, err := Parse(string(buf))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", filename, err)
+	}
+	return s, nil
+}
+
+// parseShape parses a single primitive.
+func parseShape(fields []string) (SDF, error) {
+	name := strings.ToLower(fields[0])
+	args := fields[1:]
+	check := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%v takes %v arguments, got %v", name, n, len(args))
+		}
+		return nil
+	}
+
+	var err error
+	point := func(i int) gl.Vector {
+		if err != nil {
+			return gl.Vector{}
+		}
+		var v gl.Vector
+		v, err = parsePoint(args[i])
+		return v
+	}
+	number := func(i int) float64 {
+		if err != nil {
+			return 0
+		}
+		var v float64
+		if v, err = strconv.ParseFloat(args[i], 64); err != nil {
+			err = fmt.Errorf("invalid number %v: %v", args[i], err)
+		} else if v < 0 {
+			err = fmt.Errorf("dimensions must not be negative: %v", v)
+		}
+		return v
+	}
+
+	var s SDF
+	switch name {
+	case "sphere":
+		if err := check(2); err != nil {
+			return nil, err
+		}
+		s = &Sphere{Center: point(0), Radius: number(1)}
+	case "box":
+		if err := check(2); err != nil {
+			return nil, err
+		}
+		a, b := point(0), point(1)
+		s = &Box{Min: a.Min(b), Max: a.Max(b)}
+	case "cylinder":
+		if err := check(3); err != nil {
+			return nil, err
+		}
+		s = &Cylinder{A: point(0), B: point(1), Radius: number(2)}
+	case "cone":
+		if err := check(4); err != nil {
+			return nil, err
+		}
+		s = &Cone{A: point(0), B: point(1), RA: number(2), RB: number(3)}
+	case "torus":
+		if err := check(3); err != nil {
+			return nil, err
+		}
+		s = &Torus{Center: point(0), Major: number(1), Minor: number(2)}
+	case "capsule":
+		if err := check(3); err != nil {
+			return nil, err
+		}
+		s = &Capsule{A: point(0), B: point(1), Radius: number(2)}
+	default:
+		return nil, fmt.Errorf("unknown primitive %q", fields[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", name, err)
+	}
+	return s, nil
+}
+
+// parsePoint parses a point of the form "X,Y,Z".
+func parsePoint(s string) (gl.Vector, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return gl.Vector{}, fmt.Errorf("incorrect point format: %q", s)
+	}
+	var v [3]float64
+	for i, part := range parts {
+		var err error
+		if v[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64); err != nil {
+			return gl.Vector{}, fmt.Errorf("invalid number %v: %v", part, err)
+		}
+	}
+	return gl.V(v[0], v[1], v[2]), nil
+}
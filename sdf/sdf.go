@@ -0,0 +1,284 @@
+// Package sdf provides signed distance function (SDF) primitives that can
+// be voxelized directly into binvox models, avoiding the need to model
+// simple cuts (holes, slots, etc.) as STL files.
+//
+// All dimensions are in millimeters in world space, so SDFs voxelized
+// into the subregions of a diced model tile seamlessly.
+package sdf
+
+import (
+	"math"
+
+	gl "github.com/fogleman/fauxgl"
+)
+
+// SDF represents a solid defined by a signed distance function.
+type SDF interface {
+	// Distance returns the signed distance (in millimeters) from the
+	// world space point p to the surface of the solid. The distance is
+	// negative inside the solid and positive outside of it.
+	Distance(p gl.Vector) float64
+
+	// Bounds returns a box that contains the entire solid.
+	Bounds() gl.Box
+}
+
+// Sphere represents a sphere.
+type Sphere struct {
+	Center gl.Vector
+	Radius float64
+}
+
+// Distance implements the SDF interface.
+func (s *Sphere) Distance(p gl.Vector) float64 {
+	return p.Distance(s.Center) - s.Radius
+}
+
+// Bounds implements the SDF interface.
+func (s *Sphere) Bounds() gl.Box {
+	return gl.Box{Min: s.Center.SubScalar(s.Radius), Max: s.Center.AddScalar(s.Radius)}
+}
+
+// Box represents an axis-aligned box.
+type Box struct {
+	Min, Max gl.Vector
+}
+
+// Distance implements the SDF interface.
+func (s *Box) Distance(p gl.Vector) float64 {
+	c := s.Min.Add(s.Max).MulScalar(0.5)
+	h := s.Max.Sub(s.Min).MulScalar(0.5)
+	q := p.Sub(c).Abs().Sub(h)
+	return q.Max(gl.Vector{}).Length() + math.Min(q.MaxComponent(), 0)
+}
+
+// Bounds implements the SDF interface.
+func (s *Box) Bounds() gl.Box {
+	return gl.Box{Min: s.Min, Max: s.Max}
+}
+
+// Cylinder represents a capped cylinder whose axis runs from A to B.
+type Cylinder struct {
+	A, B   gl.Vector
+	Radius float64
+}
+
+// Distance implements the SDF interface.
+func (s *Cylinder) Distance(p gl.Vector) float64 {
+	radial, axial, h := segmentCoords(p, s.A, s.B)
+	dx := radial - s.Radius
+	dy := math.Max(-axial, axial-h)
+	return math.Hypot(math.Max(dx, 0), math.Max(dy, 0)) + math.Min(math.Max(dx, dy), 0)
+}
+
+// Bounds implements the SDF interface.
+func (s *Cylinder) Bounds() gl.Box {
+	return segmentBounds(s.A, s.B, s.Radius)
+}
+
+// Cone represents a capped cone whose axis runs from A (with radius RA)
+// to B (with radius RB). Either radius may be zero.
+type Cone struct {
+	A, B   gl.Vector
+	RA, RB float64
+}
+
+// Distance implements the SDF interface.
+func (s *Cone) Distance(p gl.Vector) float64 {
+	radial, axial, h := segmentCoords(p, s.A, s.B)
+	if h == 0 {
+		return p.Distance(s.A)
+	}
+	t := axial / h // fractional position along the axis
+	rba := s.RB - s.RA
+
+	// Distance to the caps.
+	r := s.RB
+	if t < 0.5 {
+		r = s.RA
+	}
+	cax := math.Max(0, radial-r)
+	cay := math.Abs(t-0.5) - 0.5
+
+	// Distance to the slanted side.
+	k := rba*rba + h*h
+	f := gl.Clamp((rba*(radial-s.RA)+t*h*h)/k, 0, 1)
+	cbx := radial - s.RA - f*rba
+	cby := t - f
+
+	sign := 1.0
+	if cbx < 0 && cay < 0 {
+		sign = -1
+	}
+	return sign * math.Sqrt(math.Min(cax*cax+cay*cay*h*h, cbx*cbx+cby*cby*h*h))
+}
+
+// Bounds implements the SDF interface.
+func (s *Cone) Bounds() gl.Box {
+	return segmentBounds(s.A, s.B, math.Max(s.RA, s.RB))
+}
+
+// Torus represents a torus centered at Center that lies in the XY plane.
+// Major is the distance from the center to the middle of the tube and
+// Minor is the radius of the tube.
+type Torus struct {
+	Center       gl.Vector
+	Major, Minor float64
+}
+
+// Distance implements the SDF interface.
+func (s *Torus) Distance(p gl.Vector) float64 {
+	d := p.Sub(s.Center)
+	return math.Hypot(math.Hypot(d.X, d.Y)-s.Major, d.Z) - s.Minor
+}
+
+// Bounds implements the SDF interface.
+func (s *Torus) Bounds() gl.Box {
+	r := s.Major + s.Minor
+	h := gl.V(r, r, s.Minor)
+	return gl.Box{Min: s.Center.Sub(h), Max: s.Center.Add(h)}
+}
+
+// Capsule represents a cylinder with hemispherical ends whose axis runs
+// from A to B.
+type Capsule struct {
+	A, B   gl.Vector
+	Radius float64
+}
+
+// Distance implements the SDF interface.
+func (s *Capsule) Distance(p gl.Vector) float64 {
+	ba := s.B.Sub(s.A)
+	t := 0.0
+	if l2 := ba.LengthSquared(); l2 > 0 {
+		t = gl.Clamp(p.Sub(s.A).Dot(ba)/l2, 0, 1)
+	}
+	return p.Distance(s.A.Add(ba.MulScalar(t))) - s.Radius
+}
+
+// Bounds implements the SDF interface.
+func (s *Capsule) Bounds() gl.Box {
+	return gl.Box{Min: s.A.Min(s.B).SubScalar(s.Radius), Max: s.A.Max(s.B).AddScalar(s.Radius)}
+}
+
+// Union represents the union of solids.
+type Union []SDF
+
+// Distance implements the SDF interface.
+func (s Union) Distance(p gl.Vector) float64 {
+	d := math.Inf(1)
+	for _, v := range s {
+		d = math.Min(d, v.Distance(p))
+	}
+	return d
+}
+
+// Bounds implements the SDF interface.
+func (s Union) Bounds() gl.Box {
+	return unionBounds(s)
+}
+
+// Intersection represents the intersection of solids.
+type Intersection []SDF
+
+// Distance implements the SDF interface.
+func (s Intersection) Distance(p gl.Vector) float64 {
+	d := math.Inf(-1)
+	for _, v := range s {
+		d = math.Max(d, v.Distance(p))
+	}
+	return d
+}
+
+// Bounds implements the SDF interface.
+func (s Intersection) Bounds() gl.Box {
+	if len(s) == 0 {
+		return gl.Box{}
+	}
+	box := s[0].Bounds()
+	for _, v := range s[1:] {
+		box = box.Intersection(v.Bounds())
+	}
+	return box
+}
+
+// Difference represents solid A with solid B removed from it.
+type Difference struct {
+	A, B SDF
+}
+
+// Distance implements the SDF interface.
+func (s *Difference) Distance(p gl.Vector) float64 {
+	return math.Max(s.A.Distance(p), -s.B.Distance(p))
+}
+
+// Bounds implements the SDF interface.
+func (s *Difference) Bounds() gl.Box {
+	return s.A.Bounds()
+}
+
+// SmoothUnion represents the union of solids that are blended together
+// with fillets of (approximately) radius K millimeters where they meet.
+type SmoothUnion struct {
+	K      float64
+	Shapes []SDF
+}
+
+// Distance implements the SDF interface.
+func (s *SmoothUnion) Distance(p gl.Vector) float64 {
+	if len(s.Shapes) == 0 {
+		return math.Inf(1)
+	}
+	d := s.Shapes[0].Distance(p)
+	for _, v := range s.Shapes[1:] {
+		d = smin(d, v.Distance(p), s.K)
+	}
+	return d
+}
+
+// Bounds implements the SDF interface.
+func (s *SmoothUnion) Bounds() gl.Box {
+	// The blend adds at most K/4 to each of the shapes.
+	return unionBounds(s.Shapes).Offset(0.25 * s.K)
+}
+
+// smin is the polynomial smooth minimum of a and b with blend radius k.
+func smin(a, b, k float64) float64 {
+	if k <= 0 {
+		return math.Min(a, b)
+	}
+	h := math.Max(k-math.Abs(a-b), 0) / k
+	return math.Min(a, b) - 0.25*h*h*k
+}
+
+// segmentCoords returns the distance of p from the line through a and b,
+// the distance of its projection along the line from a, and the length
+// of the segment.
+func segmentCoords(p, a, b gl.Vector) (radial, axial, length float64) {
+	ba := b.Sub(a)
+	length = ba.Length()
+	pa := p.Sub(a)
+	if length == 0 {
+		return pa.Length(), 0, 0
+	}
+	axial = pa.Dot(ba) / length
+	radial = math.Sqrt(math.Max(pa.LengthSquared()-axial*axial, 0))
+	return radial, axial, length
+}
+
+// segmentBounds returns the bounds of a disk of radius r swept from a to b.
+func segmentBounds(a, b gl.Vector, r float64) gl.Box {
+	return gl.Box{Min: a.Min(b).SubScalar(r), Max: a.Max(b).AddScalar(r)}
+}
+
+// unionBounds returns the bounds containing all of the solids.
+func unionBounds(shapes []SDF) gl.Box {
+	if len(shapes) == 0 {
+		return gl.Box{}
+	}
+	box := shapes[0].Bounds()
+	for _, v := range shapes[1:] {
+		box = box.Extend(v.Bounds())
+	}
+	return box
+}
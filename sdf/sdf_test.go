@@ -0,0 +1,134 @@
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		s    SDF
+		p    gl.Vector
+		want float64
+	}{
+		{"sphere inside", &Sphere{Center: gl.V(1, 0, 0), Radius: 2}, gl.V(1, 0, 0), -2},
+		{"sphere outside", &Sphere{Center: gl.V(1, 0, 0), Radius: 2}, gl.V(1, 5, 0), 3},
+		{"box face", &Box{Min: gl.V(-1, -1, -1), Max: gl.V(1, 1, 1)}, gl.V(3, 0, 0), 2},
+		{"box corner", &Box{Min: gl.V(-1, -1, -1), Max: gl.V(1, 1, 1)}, gl.V(2, 2, 1), math.Sqrt2},
+		{"box inside", &Box{Min: gl.V(-1, -1, -1), Max: gl.V(1, 1, 1)}, gl.V(0.5, 0, 0), -0.5},
+		{"cylinder side", &Cylinder{A: gl.V(0, 0, 0), B: gl.V(0, 0, 10), Radius: 2}, gl.V(5, 0, 5), 3},
+		{"cylinder cap", &Cylinder{A: gl.V(0, 0, 0), B: gl.V(0, 0, 10), Radius: 2}, gl.V(1, 0, 12), 2},
+		{"cylinder edge", &Cylinder{A: gl.V(0, 0, 0), B: gl.V(0, 0, 10), Radius: 2}, gl.V(5, 0, -4), 5},
+		{"cylinder inside", &Cylinder{A: gl.V(0, 0, 0), B: gl.V(10, 0, 0), Radius: 2}, gl.V(1, 0, 0), -1},
+		{"cone base", &Cone{A: gl.V(0, 0, 0), B: gl.V(0, 0, 4), RA: 3, RB: 0}, gl.V(0, 0, -1), 1},
+		{"cone side", &Cone{A: gl.V(0, 0, 0), B: gl.V(0, 0, 4), RA: 3, RB: 0}, gl.V(3, 0, 4), 2.4},
+		{"cone tip", &Cone{A: gl.V(0, 0, 0), B: gl.V(0, 0, 4), RA: 3, RB: 0}, gl.V(0, 0, 6), 2},
+		{"cone inside", &Cone{A: gl.V(0, 0, 0), B: gl.V(0, 0, 4), RA: 3, RB: 0}, gl.V(0, 0, 0.5), -0.5},
+		{"torus tube", &Torus{Center: gl.V(0, 0, 1), Major: 5, Minor: 1}, gl.V(5, 0, 1), -1},
+		{"torus hole", &Torus{Center: gl.V(0, 0, 1), Major: 5, Minor: 1}, gl.V(0, 0, 1), 4},
+		{"capsule end", &Capsule{A: gl.V(0, 0, 0), B: gl.V(4, 0, 0), Radius: 1}, gl.V(7, 0, 0), 2},
+		{"capsule side", &Capsule{A: gl.V(0, 0, 0), B: gl.V(4, 0, 0), Radius: 1}, gl.V(2, 3, 0), 2},
+		{"union", Union{&Sphere{Radius: 1}, &Sphere{Center: gl.V(4, 0, 0), Radius: 1}}, gl.V(2, 0, 0), 1},
+		{"intersection", Intersection{&Sphere{Radius: 2}, &Sphere{Center: gl.V(2, 0, 0), Radius: 2}}, gl.V(1, 0, 0), -1},
+		{"difference", &Difference{A: &Sphere{Radius: 3}, B: &Sphere{Radius: 1}}, gl.V(0, 0, 0), 1},
+		{"smooth union", &SmoothUnion{K: 2, Shapes: []SDF{&Sphere{Radius: 1}, &Sphere{Center: gl.V(4, 0, 0), Radius: 1}}}, gl.V(2, 0, 0), 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.Distance(tt.p); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Distance(%v) = %v, want %v", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse(`
+# Two bolt holes and a slot.
+cylinder 0,0,-1 0,0,11 1.5; cylinder 20,0,-1 20,0,11 1.5
+box 5,-1,-1 15,1,11
+
+smooth 2
+sphere 10,10,5 3 # blended with everything above
+`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	su, ok := s.(*SmoothUnion)
+	if !ok || su.K != 2 || len(su.Shapes) != 2 {
+		t.Fatalf("Parse = %#v, want SmoothUnion of 2 shapes with K=2", s)
+	}
+	if u, ok := su.Shapes[0].(Union); !ok || len(u) != 3 {
+		t.Errorf("Parse first shape = %#v, want Union of 3 shapes", su.Shapes[0])
+	}
+	if got := s.Distance(gl.V(20, 0, 5)); got > 0 {
+		t.Errorf("Distance inside second hole = %v, want <= 0", got)
+	}
+
+	for _, bad := range []string{
+		"",
+		"# nothing",
+		"sphere 0,0,0",
+		"sphere 0,0 1",
+		"sphere 0,0,0 -1",
+		"pyramid 0,0,0 1",
+		"smooth",
+		"smooth x; sphere 0,0,0 1",
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) = nil error, want error", bad)
+		}
+	}
+}
+
+func TestVoxelize(t *testing.T) {
+	s := &Sphere{Center: gl.V(0, 0, 0), Radius: 4}
+
+	// One region covering the whole sphere.
+	whole := binvox.New(10, 10, 10, -5, -5, -5, 10, false)
+	if err := Voxelize(whole, s); err != nil {
+		t.Fatalf("Voxelize: %v", err)
+	}
+	var want int
+	for z := 0; z < 10; z++ {
+		for y := 0; y < 10; y++ {
+			for x := 0; x < 10; x++ {
+				k := binvox.Key{X: x, Y: y, Z: z}
+				_, got := whole.WhiteVoxels[k]
+				inside := whole.VoxelCenter(k).Length() <= 4
+				if got != inside {
+					t.Errorf("voxel %v = %v, want %v", k, got, inside)
+				}
+				if inside {
+					want++
+				}
+			}
+		}
+	}
+	if got := len(whole.WhiteVoxels); got != want {
+		t.Errorf("Voxelize = %v voxels, want %v", got, want)
+	}
+
+	// Diced into two regions along X.
+	var total int
+	for i := 0; i < 2; i++ {
+		r := binvox.New(5, 10, 10, -5+5*float64(i), -5, -5, 10, false)
+		if err := Voxelize(r, s); err != nil {
+			t.Fatalf("Voxelize region #%v: %v", i, err)
+		}
+		for k := range r.WhiteVoxels {
+			if _, ok := whole.WhiteVoxels[binvox.Key{X: k.X + 5*i, Y: k.Y, Z: k.Z}]; !ok {
+				t.Errorf("region #%v voxel %v not in whole model", i, k)
+			}
+		}
+		total += len(r.WhiteVoxels)
+	}
+	if total != len(whole.WhiteVoxels) {
+		t.Errorf("diced regions have %v voxels, want %v", total, len(whole.WhiteVoxels))
+	}
+}
@@ -0,0 +1,67 @@
+package sdf
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+// Voxelize voxelizes the solid into the subregion of b, in the same manner
+// as binvox.Voxelize voxelizes a mesh: (b.TX,b.TY,b.TZ) is the origin,
+// the voxelized subregion will be (0,0,0)-(b.NX-1,b.NY-1,b.NZ-1) (inclusive),
+// and b.Scale determines the scale of the voxelization.
+//
+// A voxel is solid when its center lies within the solid.
+// Voxelize overwrites the WhiteVoxels map in b.
+func Voxelize(b *binvox.BinVOX, s SDF) error {
+	if b.NX == 0 || b.NY == 0 || b.NZ == 0 {
+		return fmt.Errorf("dimensions must be non-zero (%v,%v,%v)", b.NX, b.NY, b.NZ)
+	}
+	log.Printf("\n\nVoxelizing SDF into %v...", b)
+
+	// Only visit the voxels within the bounds of the solid.
+	vpmm := b.VoxelsPerMM()
+	box := s.Bounds()
+	lo := func(v, t float64) int {
+		return int(math.Max(0, math.Floor((v-t)*vpmm)))
+	}
+	hi := func(v, t float64, n int) int {
+		return int(math.Min(float64(n-1), math.Ceil((v-t)*vpmm)))
+	}
+	x0, x1 := lo(box.Min.X, b.TX), hi(box.Max.X, b.TX, b.NX)
+	y0, y1 := lo(box.Min.Y, b.TY), hi(box.Max.Y, b.TY, b.NY)
+	z0, z1 := lo(box.Min.Z, b.TZ), hi(box.Max.Z, b.TZ, b.NZ)
+
+	var mu sync.Mutex // protects the voxels map
+	voxels := binvox.WhiteVoxelMap{}
+	var wg sync.WaitGroup
+	for zi := z0; zi <= z1; zi++ {
+		wg.Add(1)
+		go func(zi int) {
+			var keys []binvox.Key
+			for yi := y0; yi <= y1; yi++ {
+				for xi := x0; xi <= x1; xi++ {
+					k := binvox.Key{X: xi, Y: yi, Z: zi}
+					if s.Distance(b.VoxelCenter(k)) <= 0 {
+						keys = append(keys, k)
+					}
+				}
+			}
+			mu.Lock()
+			for _, k := range keys {
+				voxels[k] = struct{}{}
+			}
+			mu.Unlock()
+			wg.Done()
+		}(zi)
+	}
+	wg.Wait()
+
+	b.WhiteVoxels = voxels
+	b.ColorVoxels = nil
+	log.Printf("Done creating %v voxels.", len(b.WhiteVoxels))
+	return nil
+}
@@ -1,47 +1,472 @@
 // stldice-master is a gRPC server that listens for commands from the
 // dicer command-line tool and communicates with stldice-slave servers
 // to process the requests.
+//
+// The VoxelRegion of each request is split into -nx by -ny by -nz
+// subregions that are distributed across the -slaves. Each subregion sent
+// to a slave is padded by a halo of -halo voxels (clipped to the region)
+// so that neighboring slaves mesh the voxels along their shared seams
+// identically. GetSTLMesh keeps only the triangles within the core of
+// each subregion and merges them into a single watertight mesh. The
+// slaves keep their models until the merge succeeds, so a failed or lost
+// GetSTLMesh reply from a slave can be retried without losing its model.
+//
+// Usage:
+//
+//	stldice-master -slaves host1:5333,host2:5333 -nx 4 -ny 4
 package main
 
 import (
+	"bytes"
+	"encoding/binary"
 	"flag"
+	"fmt"
 	"log"
+	"math"
 	"net"
+	"strings"
+	"sync"
+	"time"
 
+	gl "github.com/fogleman/fauxgl"
 	pb "github.com/gmlewis/stldice/v4/stldice"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 )
 
+const (
+	maxSize = 1 << 30 // 1GB
+)
+
 var (
-	port = flag.String("port", ":5334", "Port to listen on")
+	port    = flag.String("port", ":5334", "Port to listen on")
+	slaves  = flag.String("slaves", "localhost:5333", "Comma-separated list of stldice-slave addresses")
+	nX      = flag.Int("nx", 2, "Number of subregions along the X dimension")
+	nY      = flag.Int("ny", 2, "Number of subregions along the Y dimension")
+	nZ      = flag.Int("nz", 1, "Number of subregions along the Z dimension")
+	halo    = flag.Int("halo", 2, "Number of voxels (at least 1) by which each subregion overlaps its neighbors")
+	retries = flag.Int("retries", 3, "Number of times to retry a failed request to a slave")
 )
 
 // server is used to implement stldice.STLDiceServer
-type server struct{}
+type server struct {
+	addresses []string
+	slaves    []pb.STLDiceClient
+
+	nx, ny, nz int           // number of subregions along each axis
+	halo       int           // voxels of overlap between subregions
+	retries    int           // retries of each failed request to a slave
+	backoff    time.Duration // delay before the first retry; doubled after each retry
+}
+
+// subregion is the part of a VoxelRegion processed by a single slave.
+type subregion struct {
+	index  int             // selects the slave
+	region *pb.VoxelRegion // the core of the subregion plus its halo
+	lo, hi [3]int          // voxel range [lo,hi) of the core within the whole region
+}
 
 // Add an STL mesh to the voxels.
 func (s *server) AddSTLMesh(ctx context.Context, in *pb.AddSTLMeshRequest) (*pb.AddSTLMeshReply, error) {
+	if err := s.voxelOp(ctx, in.GetVoxelRegion(), in.GetStlFile(), true); err != nil {
+		return nil, fmt.Errorf("AddSTLMesh: %v", err)
+	}
 	return &pb.AddSTLMeshReply{}, nil
 }
 
 // Subtract an STL mesh from the voxels.
 func (s *server) SubSTLMesh(ctx context.Context, in *pb.SubSTLMeshRequest) (*pb.SubSTLMeshReply, error) {
+	if err := s.voxelOp(ctx, in.GetVoxelRegion(), in.GetStlFile(), false); err != nil {
+		return nil, fmt.Errorf("SubSTLMesh: %v", err)
+	}
 	return &pb.SubSTLMeshReply{}, nil
 }
 
 // Get back the model voxels as STL.
-// This also resets the server by clearing out the model.
+// Once the subregions have been merged, this also resets the slaves by
+// clearing out their models.
 func (s *server) GetSTLMesh(ctx context.Context, in *pb.GetSTLMeshRequest) (*pb.GetSTLMeshReply, error) {
-	return &pb.GetSTLMeshReply{}, nil
+	buf, err := s.getMesh(ctx, in.GetVoxelRegion())
+	if err != nil {
+		return nil, fmt.Errorf("GetSTLMesh: %v", err)
+	}
+	return &pb.GetSTLMeshReply{StlFile: buf}, nil
+}
+
+// Reset the slaves by clearing out the models of all the subregions.
+func (s *server) ResetModel(ctx context.Context, in *pb.ResetModelRequest) (*pb.ResetModelReply, error) {
+	if err := s.reset(ctx, in.GetVoxelRegion()); err != nil {
+		return nil, fmt.Errorf("ResetModel: %v", err)
+	}
+	return &pb.ResetModelReply{}, nil
+}
+
+// AddSTLMeshStream is AddSTLMesh with the STL file streamed in chunks.
+func (s *server) AddSTLMeshStream(stream pb.STLDice_AddSTLMeshStreamServer) error {
+	region, stlFile, err := pb.ReceiveSTLMesh(stream.Recv)
+	if err != nil {
+		return fmt.Errorf("AddSTLMeshStream: %v", err)
+	}
+	if err := s.voxelOp(stream.Context(), region, stlFile, true); err != nil {
+		return fmt.Errorf("AddSTLMeshStream: %v", err)
+	}
+	return stream.SendAndClose(&pb.AddSTLMeshReply{})
+}
+
+// SubSTLMeshStream is SubSTLMesh with the STL file streamed in chunks.
+func (s *server) SubSTLMeshStream(stream pb.STLDice_SubSTLMeshStreamServer) error {
+	region, stlFile, err := pb.ReceiveSTLMesh(stream.Recv)
+	if err != nil {
+		return fmt.Errorf("SubSTLMeshStream: %v", err)
+	}
+	if err := s.voxelOp(stream.Context(), region, stlFile, false); err != nil {
+		return fmt.Errorf("SubSTLMeshStream: %v", err)
+	}
+	return stream.SendAndClose(&pb.SubSTLMeshReply{})
+}
+
+// GetSTLMeshStream is GetSTLMesh with the STL file streamed back in chunks.
+func (s *server) GetSTLMeshStream(in *pb.GetSTLMeshRequest, stream pb.STLDice_GetSTLMeshStreamServer) error {
+	buf, err := s.getMesh(stream.Context(), in.GetVoxelRegion())
+	if err != nil {
+		return fmt.Errorf("GetSTLMeshStream: %v", err)
+	}
+	return pb.SendChunks(buf, stream.Send)
+}
+
+// voxelOp streams the STL file to the slaves of all the subregions of
+// the region to be added to (union=true) or subtracted from their models.
+func (s *server) voxelOp(ctx context.Context, region *pb.VoxelRegion, stlFile []byte, union bool) error {
+	return s.fanOut(ctx, region, func(c pb.STLDiceClient, sub *subregion) error {
+		if union {
+			stream, err := c.AddSTLMeshStream(ctx)
+			if err != nil {
+				return err
+			}
+			if err := pb.SendSTLMesh(sub.region, stlFile, stream.Send); err != nil {
+				return err
+			}
+			_, err = stream.CloseAndRecv()
+			return err
+		}
+		stream, err := c.SubSTLMeshStream(ctx)
+		if err != nil {
+			return err
+		}
+		if err := pb.SendSTLMesh(sub.region, stlFile, stream.Send); err != nil {
+			return err
+		}
+		_, err = stream.CloseAndRecv()
+		return err
+	})
+}
+
+// getMesh gathers the meshes of all the subregions of the region from the
+// slaves and returns them merged as a single binary STL file. The models
+// of the subregions are only reset after all of them have been merged.
+func (s *server) getMesh(ctx context.Context, region *pb.VoxelRegion) ([]byte, error) {
+	var mu sync.Mutex // protects tris
+	var tris []*gl.Triangle
+	err := s.fanOut(ctx, region, func(c pb.STLDiceClient, sub *subregion) error {
+		stream, err := c.GetSTLMeshStream(ctx, &pb.GetSTLMeshRequest{VoxelRegion: sub.region})
+		if err != nil {
+			return err
+		}
+		buf, err := pb.ReceiveChunks(stream.Recv)
+		if err != nil {
+			return err
+		}
+		mesh, err := stlToMesh(buf)
+		if err != nil {
+			return fmt.Errorf("bad STL from slave: %v", err)
+		}
+		core := clip(region, sub, mesh)
+		mu.Lock()
+		tris = append(tris, core...)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Merged %v triangles from the subregions of %v", len(tris), region)
+	buf, err := meshToSTL(tris)
+	if err != nil {
+		return nil, err
+	}
+	// The merged mesh is returned even if a slave could not be reset, since
+	// the models can still be cleared out later with ResetModel.
+	if err := s.reset(ctx, region); err != nil {
+		log.Printf("unable to reset the subregions of %v: %v", region, err)
+	}
+	return buf, nil
+}
+
+// reset clears out the models of all the subregions of the region.
+// Resetting is idempotent, so failed calls are safely retried.
+func (s *server) reset(ctx context.Context, region *pb.VoxelRegion) error {
+	return s.fanOut(ctx, region, func(c pb.STLDiceClient, sub *subregion) error {
+		_, err := c.ResetModel(ctx, &pb.ResetModelRequest{VoxelRegion: sub.region})
+		return err
+	})
 }
 
 func main() {
+	flag.Parse()
+	if *halo < 1 {
+		// Without neighboring voxels, each subregion is meshed as if it
+		// were the whole model, leaving faces along every seam.
+		log.Fatalf("-halo must be at least 1: %v", *halo)
+	}
+
+	s := &server{nx: *nX, ny: *nY, nz: *nZ, halo: *halo, retries: *retries, backoff: time.Second}
+	for _, address := range strings.Split(*slaves, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		conn, err := grpc.Dial(address, grpc.WithInsecure(), grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxSize)), grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(maxSize)))
+		if err != nil {
+			log.Fatalf("unable to dial slave %v: %v", address, err)
+		}
+		defer conn.Close()
+		s.addresses = append(s.addresses, address)
+		s.slaves = append(s.slaves, pb.NewSTLDiceClient(conn))
+	}
+	if len(s.slaves) == 0 {
+		log.Fatal("Must specify at least one address with -slaves")
+	}
+
 	lis, err := net.Listen("tcp", *port)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
-	s := grpc.NewServer()
-	pb.RegisterSTLDiceServer(s, &server{})
-	s.Serve(lis)
+	gs := grpc.NewServer(grpc.MaxRecvMsgSize(maxSize), grpc.MaxSendMsgSize(maxSize))
+	pb.RegisterSTLDiceServer(gs, s)
+	log.Printf("Distributing requests across %v slaves: %v", len(s.slaves), strings.Join(s.addresses, ", "))
+	gs.Serve(lis)
+}
+
+// fanOut calls f concurrently for each subregion of the region (with the
+// client of the slave assigned to the subregion) and returns the first error.
+func (s *server) fanOut(ctx context.Context, region *pb.VoxelRegion, f func(c pb.STLDiceClient, sub *subregion) error) error {
+	subs, err := s.split(region)
+	if err != nil {
+		return err
+	}
+	errs := make([]error, len(subs))
+	var wg sync.WaitGroup
+	for i, sub := range subs {
+		wg.Add(1)
+		go func(i int, sub *subregion) {
+			defer wg.Done()
+			errs[i] = s.call(ctx, sub, func(c pb.STLDiceClient) error { return f(c, sub) })
+		}(i, sub)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// call calls f with the client of the slave assigned to the subregion,
+// retrying failures with exponential backoff.
+func (s *server) call(ctx context.Context, sub *subregion, f func(c pb.STLDiceClient) error) error {
+	i := sub.index % len(s.slaves)
+	delay := s.backoff
+	for attempt := 0; ; attempt++ {
+		err := f(s.slaves[i])
+		if err == nil {
+			return nil
+		}
+		if attempt >= s.retries {
+			return fmt.Errorf("slave %v failed on subregion #%v after %v attempts: %v", s.addresses[i], sub.index, attempt+1, err)
+		}
+		log.Printf("slave %v failed on subregion #%v (attempt %v of %v), retrying in %v: %v", s.addresses[i], sub.index, attempt+1, s.retries+1, delay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// voxelSize returns the size of a voxel (in millimeters) in the region.
+func voxelSize(region *pb.VoxelRegion) float64 {
+	return (region.GetUrx() - region.GetLlx()) / float64(region.GetNx())
+}
+
+// split divides the region into s.nx*s.ny*s.nz subregions (fewer if the
+// region has too few voxels).
+func (s *server) split(region *pb.VoxelRegion) ([]*subregion, error) {
+	if region == nil {
+		return nil, fmt.Errorf("missing voxel region")
+	}
+	n := [3]int{int(region.GetNx()), int(region.GetNy()), int(region.GetNz())}
+	if n[0] <= 0 || n[1] <= 0 || n[2] <= 0 || region.GetUrx() <= region.GetLlx() {
+		return nil, fmt.Errorf("invalid voxel region: %v", region)
+	}
+	div := [3]int{s.nx, s.ny, s.nz}
+	for i := range div {
+		if div[i] < 1 {
+			div[i] = 1
+		}
+		if div[i] > n[i] {
+			div[i] = n[i]
+		}
+	}
+	ll := [3]float64{region.GetLlx(), region.GetLly(), region.GetLlz()}
+	mmpv := voxelSize(region)
+
+	var result []*subregion
+	for zi := 0; zi < div[2]; zi++ {
+		for yi := 0; yi < div[1]; yi++ {
+			for xi := 0; xi < div[0]; xi++ {
+				sub := &subregion{index: len(result)}
+				var a, b [3]int // voxel range of the core plus the halo
+				for axis, i := range [3]int{xi, yi, zi} {
+					lo, hi := i*n[axis]/div[axis], (i+1)*n[axis]/div[axis]
+					a[axis], b[axis] = imax(0, lo-s.halo), imin(n[axis], hi+s.halo)
+					// The cores along the edges of the region extend
+					// outward to claim the faces on its boundary.
+					if i == 0 {
+						lo = math.MinInt32
+					}
+					if i == div[axis]-1 {
+						hi = math.MaxInt32
+					}
+					sub.lo[axis], sub.hi[axis] = lo, hi
+				}
+				sub.region = &pb.VoxelRegion{
+					Llx: ll[0] + float64(a[0])*mmpv,
+					Lly: ll[1] + float64(a[1])*mmpv,
+					Llz: ll[2] + float64(a[2])*mmpv,
+					Urx: ll[0] + float64(b[0])*mmpv,
+					Ury: ll[1] + float64(b[1])*mmpv,
+					Urz: ll[2] + float64(b[2])*mmpv,
+					Nx:  int64(b[0] - a[0]),
+					Ny:  int64(b[1] - a[1]),
+					Nz:  int64(b[2] - a[2]),
+				}
+				result = append(result, sub)
+			}
+		}
+	}
+	return result, nil
+}
+
+// clip returns the triangles of the mesh of a subregion whose centroids
+// lie within its core. The vertices of the meshes generated by the slaves
+// lie on a half-voxel grid, so they are snapped to the half-voxel grid of
+// the whole region. This stitches the seams (neighboring subregions then
+// share identical vertices) and makes the ownership of each triangle
+// exact, so every triangle in an overlapping halo is kept exactly once.
+func clip(region *pb.VoxelRegion, sub *subregion, mesh *gl.Mesh) []*gl.Triangle {
+	origin := gl.V(region.GetLlx(), region.GetLly(), region.GetLlz())
+	mmpv := voxelSize(region)
+	halfVoxels := func(v gl.Vector) [3]int {
+		g := v.Sub(origin).MulScalar(2 / mmpv)
+		return [3]int{int(math.Round(g.X)), int(math.Round(g.Y)), int(math.Round(g.Z))}
+	}
+	toVector := func(h [3]int) gl.Vector {
+		return gl.V(float64(h[0]), float64(h[1]), float64(h[2])).MulScalar(0.5 * mmpv).Add(origin)
+	}
+
+	var result []*gl.Triangle
+	for _, t := range mesh.Triangles {
+		h1, h2, h3 := halfVoxels(t.V1.Position), halfVoxels(t.V2.Position), halfVoxels(t.V3.Position)
+		inside := true
+		for axis := range h1 {
+			// The centroid is (h1+h2+h3)/6 voxels from the origin.
+			k := floorDiv(h1[axis]+h2[axis]+h3[axis], 6)
+			if k < sub.lo[axis] || k >= sub.hi[axis] {
+				inside = false
+				break
+			}
+		}
+		if inside {
+			result = append(result, gl.NewTriangleForPoints(toVector(h1), toVector(h2), toVector(h3)))
+		}
+	}
+	return result
+}
+
+// floorDiv returns a/b rounded toward negative infinity (for b > 0).
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((b - 1 - a) / b)
+	}
+	return a / b
+}
+
+// stlToMesh parses binary STL data (as returned by the slaves) and returns a mesh.
+func stlToMesh(buf []byte) (*gl.Mesh, error) {
+	r := bytes.NewReader(buf)
+	header := gl.STLHeader{}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if got, want := int64(len(buf)), int64(header.Count)*50+84; got != want {
+		return nil, fmt.Errorf("got %v bytes of STL data for %v triangles, want %v bytes", got, header.Count, want)
+	}
+	triangles := make([]*gl.Triangle, header.Count)
+	for i := range triangles {
+		d := gl.STLTriangle{}
+		if err := binary.Read(r, binary.LittleEndian, &d); err != nil {
+			return nil, err
+		}
+		v1 := gl.V(float64(d.V1[0]), float64(d.V1[1]), float64(d.V1[2]))
+		v2 := gl.V(float64(d.V2[0]), float64(d.V2[1]), float64(d.V2[2]))
+		v3 := gl.V(float64(d.V3[0]), float64(d.V3[1]), float64(d.V3[2]))
+		triangles[i] = gl.NewTriangleForPoints(v1, v2, v3)
+	}
+	return gl.NewTriangleMesh(triangles), nil
+}
+
+// meshToSTL returns the triangles as binary STL data.
+func meshToSTL(tris []*gl.Triangle) ([]byte, error) {
+	var buf bytes.Buffer
+	header := gl.STLHeader{}
+	header.Count = uint32(len(tris))
+	if err := binary.Write(&buf, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	for _, triangle := range tris {
+		n := triangle.Normal()
+		d := gl.STLTriangle{}
+		d.N[0] = float32(n.X)
+		d.N[1] = float32(n.Y)
+		d.N[2] = float32(n.Z)
+		d.V1[0] = float32(triangle.V1.Position.X)
+		d.V1[1] = float32(triangle.V1.Position.Y)
+		d.V1[2] = float32(triangle.V1.Position.Z)
+		d.V2[0] = float32(triangle.V2.Position.X)
+		d.V2[1] = float32(triangle.V2.Position.Y)
+		d.V2[2] = float32(triangle.V2.Position.Z)
+		d.V3[0] = float32(triangle.V3.Position.X)
+		d.V3[1] = float32(triangle.V3.Position.Y)
+		d.V3[2] = float32(triangle.V3.Position.Z)
+		if err := binary.Write(&buf, binary.LittleEndian, &d); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func imin(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func imax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
 }
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+	pb "github.com/gmlewis/stldice/v4/stldice"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// fakeSlave keeps its region models in memory and fails its first
+// failures requests.
+type fakeSlave struct {
+	mu       sync.Mutex
+	models   map[string]*binvox.BinVOX
+	failures int
+	calls    int
+}
+
+func (f *fakeSlave) fail() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.failures > 0 {
+		f.failures--
+		return fmt.Errorf("injected failure")
+	}
+	return nil
+}
+
+func (f *fakeSlave) op(region *pb.VoxelRegion, stlFile []byte, union bool) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+	mesh, err := stlToMesh(stlFile)
+	if err != nil {
+		return err
+	}
+	mmpv := voxelSize(region)
+	bv := binvox.New(int(region.GetNx()), int(region.GetNy()), int(region.GetNz()), region.GetLlx(), region.GetLly(), region.GetLlz(), 0, false)
+	bv.Scale = float64(bv.Dim()) * mmpv
+	if err := bv.Voxelize(mesh); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	base, ok := f.models[region.String()]
+	switch {
+	case !ok && union:
+		f.models[region.String()] = bv
+	case !ok:
+		return fmt.Errorf("no model")
+	case union:
+		return base.Union(bv)
+	default:
+		return base.Difference(bv)
+	}
+	return nil
+}
+
+func (f *fakeSlave) AddSTLMesh(ctx context.Context, in *pb.AddSTLMeshRequest) (*pb.AddSTLMeshReply, error) {
+	return &pb.AddSTLMeshReply{}, f.op(in.GetVoxelRegion(), in.GetStlFile(), true)
+}
+
+func (f *fakeSlave) SubSTLMesh(ctx context.Context, in *pb.SubSTLMeshRequest) (*pb.SubSTLMeshReply, error) {
+	return &pb.SubSTLMeshReply{}, f.op(in.GetVoxelRegion(), in.GetStlFile(), false)
+}
+
+func (f *fakeSlave) GetSTLMesh(ctx context.Context, in *pb.GetSTLMeshRequest) (*pb.GetSTLMeshReply, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	base, ok := f.models[in.GetVoxelRegion().String()]
+	if !ok {
+		return nil, fmt.Errorf("no model")
+	}
+	buf, err := meshToSTL(base.ManifoldMesh().Triangles)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetSTLMeshReply{StlFile: buf}, nil
+}
+
+func (f *fakeSlave) ResetModel(ctx context.Context, in *pb.ResetModelRequest) (*pb.ResetModelReply, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.models, in.GetVoxelRegion().String())
+	return &pb.ResetModelReply{}, nil
+}
+
+func (f *fakeSlave) AddSTLMeshStream(stream pb.STLDice_AddSTLMeshStreamServer) error {
+	region, stlFile, err := pb.ReceiveSTLMesh(stream.Recv)
+	if err != nil {
+		return err
+	}
+	if err := f.op(region, stlFile, true); err != nil {
+		return err
+	}
+	return stream.SendAndClose(&pb.AddSTLMeshReply{})
+}
+
+func (f *fakeSlave) SubSTLMeshStream(stream pb.STLDice_SubSTLMeshStreamServer) error {
+	region, stlFile, err := pb.ReceiveSTLMesh(stream.Recv)
+	if err != nil {
+		return err
+	}
+	if err := f.op(region, stlFile, false); err != nil {
+		return err
+	}
+	return stream.SendAndClose(&pb.SubSTLMeshReply{})
+}
+
+func (f *fakeSlave) GetSTLMeshStream(in *pb.GetSTLMeshRequest, stream pb.STLDice_GetSTLMeshStreamServer) error {
+	reply, err := f.GetSTLMesh(stream.Context(), in)
+	if err != nil {
+		return err
+	}
+	return pb.SendChunks(reply.GetStlFile(), stream.Send)
+}
+
+// startSlaves starts n fake slaves on localhost and returns a master that uses them.
+func startSlaves(t *testing.T, n int) (*server, []*fakeSlave) {
+	t.Helper()
+	s := &server{nx: 2, ny: 2, nz: 2, halo: 2, retries: 2}
+	var fakes []*fakeSlave
+	for i := 0; i < n; i++ {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("net.Listen: %v", err)
+		}
+		f := &fakeSlave{models: map[string]*binvox.BinVOX{}}
+		gs := grpc.NewServer()
+		pb.RegisterSTLDiceServer(gs, f)
+		go gs.Serve(lis)
+		t.Cleanup(gs.Stop)
+
+		conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+		if err != nil {
+			t.Fatalf("grpc.Dial: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		s.addresses = append(s.addresses, lis.Addr().String())
+		s.slaves = append(s.slaves, pb.NewSTLDiceClient(conn))
+		fakes = append(fakes, f)
+	}
+	return s, fakes
+}
+
+// box returns the binary STL data of an axis-aligned box.
+func box(t *testing.T, min, max gl.Vector) []byte {
+	t.Helper()
+	mesh := gl.NewCube()
+	mesh.Transform(gl.Scale(max.Sub(min)).Translate(min.Add(max).MulScalar(0.5)))
+	buf, err := meshToSTL(mesh.Triangles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+// sortedTriangles returns the triangles as sorted strings for comparison.
+func sortedTriangles(tris []*gl.Triangle) []string {
+	var result []string
+	for _, t := range tris {
+		result = append(result, fmt.Sprintf("%.4f %.4f %.4f", t.V1.Position, t.V2.Position, t.V3.Position))
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestFanOut(t *testing.T) {
+	s, fakes := startSlaves(t, 3)
+	ctx := context.Background()
+	// A 10x10x10 voxel region of 0.5mm voxels.
+	region := &pb.VoxelRegion{Llx: -2.5, Lly: -2.5, Llz: -2.5, Urx: 2.5, Ury: 2.5, Urz: 2.5, Nx: 10, Ny: 10, Nz: 10}
+	add := box(t, gl.V(-1.9, -1.6, -1.4), gl.V(1.4, 2.1, 1.9))
+	sub := box(t, gl.V(-0.9, -0.9, -0.4), gl.V(0.4, 0.9, 3.1))
+
+	if _, err := s.AddSTLMesh(ctx, &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: add}); err != nil {
+		t.Fatalf("AddSTLMesh: %v", err)
+	}
+	if _, err := s.SubSTLMesh(ctx, &pb.SubSTLMeshRequest{VoxelRegion: region, StlFile: sub}); err != nil {
+		t.Fatalf("SubSTLMesh: %v", err)
+	}
+	for i, f := range fakes {
+		if len(f.models) == 0 {
+			t.Errorf("slave #%v received no subregions", i)
+		}
+	}
+	reply, err := s.GetSTLMesh(ctx, &pb.GetSTLMeshRequest{VoxelRegion: region})
+	if err != nil {
+		t.Fatalf("GetSTLMesh: %v", err)
+	}
+	got, err := stlToMesh(reply.GetStlFile())
+	if err != nil {
+		t.Fatalf("stlToMesh: %v", err)
+	}
+
+	compareMeshes(t, got, wholeMesh(t, region, [][]byte{add}, [][]byte{sub}))
+
+	// The slaves are reset once the subregions have been merged.
+	for i, f := range fakes {
+		if len(f.models) != 0 {
+			t.Errorf("slave #%v kept %v models after GetSTLMesh", i, len(f.models))
+		}
+	}
+}
+
+// wholeMesh returns the mesh of the STL files added to and then
+// subtracted from a model voxelized as a single region.
+func wholeMesh(t *testing.T, region *pb.VoxelRegion, adds, subs [][]byte) *gl.Mesh {
+	t.Helper()
+	whole := &fakeSlave{models: map[string]*binvox.BinVOX{}}
+	for _, add := range adds {
+		if err := whole.op(region, add, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, sub := range subs {
+		if err := whole.op(region, sub, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return whole.models[region.String()].ManifoldMesh()
+}
+
+// compareMeshes compares the merged mesh with the wanted one.
+func compareMeshes(t *testing.T, got, want *gl.Mesh) {
+	t.Helper()
+	gotTris, wantTris := sortedTriangles(got.Triangles), sortedTriangles(want.Triangles)
+	if len(gotTris) != len(wantTris) {
+		t.Fatalf("merged mesh has %v triangles, want %v", len(gotTris), len(wantTris))
+	}
+	for i := range gotTris {
+		if gotTris[i] != wantTris[i] {
+			t.Errorf("triangle #%v = %v, want %v", i, gotTris[i], wantTris[i])
+		}
+	}
+}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+	region := &pb.VoxelRegion{Llx: 0, Lly: 0, Llz: 0, Urx: 4, Ury: 4, Urz: 4, Nx: 4, Ny: 4, Nz: 4}
+	add := box(t, gl.V(0.5, 0.5, 0.5), gl.V(3.5, 3.5, 3.5))
+
+	s, fakes := startSlaves(t, 2)
+	fakes[1].failures = 2
+	if _, err := s.AddSTLMesh(ctx, &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: add}); err != nil {
+		t.Fatalf("AddSTLMesh: %v", err)
+	}
+	if got, want := fakes[1].calls, 4+2; got != want { // 4 subregions per slave
+		t.Errorf("slave #1 received %v calls, want %v", got, want)
+	}
+
+	s, fakes = startSlaves(t, 2)
+	fakes[0].failures = 100
+	if _, err := s.AddSTLMesh(ctx, &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: add}); err == nil {
+		t.Error("AddSTLMesh = nil error, want error after exhausting retries")
+	}
+}
+
+func TestGetRetriesKeepModels(t *testing.T) {
+	ctx := context.Background()
+	region := &pb.VoxelRegion{Llx: 0, Lly: 0, Llz: 0, Urx: 4, Ury: 4, Urz: 4, Nx: 4, Ny: 4, Nz: 4}
+	add := box(t, gl.V(0.5, 0.5, 0.5), gl.V(3.5, 3.5, 3.5))
+
+	s, fakes := startSlaves(t, 2)
+	if _, err := s.AddSTLMesh(ctx, &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: add}); err != nil {
+		t.Fatalf("AddSTLMesh: %v", err)
+	}
+	// A failed GetSTLMesh on a slave is retried without losing its models.
+	fakes[1].failures = 1
+	reply, err := s.GetSTLMesh(ctx, &pb.GetSTLMeshRequest{VoxelRegion: region})
+	if err != nil {
+		t.Fatalf("GetSTLMesh: %v", err)
+	}
+	got, err := stlToMesh(reply.GetStlFile())
+	if err != nil {
+		t.Fatalf("stlToMesh: %v", err)
+	}
+	compareMeshes(t, got, wholeMesh(t, region, [][]byte{add}, nil))
+
+	// A GetSTLMesh that exhausts its retries leaves all the models intact.
+	if _, err := s.AddSTLMesh(ctx, &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: add}); err != nil {
+		t.Fatalf("AddSTLMesh: %v", err)
+	}
+	fakes[0].failures = 100
+	if _, err := s.GetSTLMesh(ctx, &pb.GetSTLMeshRequest{VoxelRegion: region}); err == nil {
+		t.Fatal("GetSTLMesh = nil error, want error after exhausting retries")
+	}
+	for i, f := range fakes {
+		if len(f.models) == 0 {
+			t.Errorf("slave #%v lost its models after a failed GetSTLMesh", i)
+		}
+	}
+	fakes[0].failures = 0
+	if _, err := s.ResetModel(ctx, &pb.ResetModelRequest{VoxelRegion: region}); err != nil {
+		t.Fatalf("ResetModel: %v", err)
+	}
+	for i, f := range fakes {
+		if len(f.models) != 0 {
+			t.Errorf("slave #%v kept %v models after ResetModel", i, len(f.models))
+		}
+	}
+}
+
+// startRealSlaves builds the stldice-slave server, runs n of them on
+// localhost and returns a master that uses them.
+func startRealSlaves(t *testing.T, n int) *server {
+	t.Helper()
+	bin := filepath.Join(t.TempDir(), "stldice-slave")
+	if out, err := exec.Command("go", "build", "-o", bin, "../stldice-slave").CombinedOutput(); err != nil {
+		t.Fatalf("unable to build stldice-slave: %v\n%s", err, out)
+	}
+	s := &server{nx: 2, ny: 2, nz: 2, halo: 2, retries: 2, backoff: 10 * time.Millisecond}
+	for i := 0; i < n; i++ {
+		// Find a free port for the slave.
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("net.Listen: %v", err)
+		}
+		address := lis.Addr().String()
+		lis.Close()
+
+		cmd := exec.Command(bin, "-port", address, "-dir", t.TempDir())
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("unable to start stldice-slave: %v", err)
+		}
+		t.Cleanup(func() {
+			cmd.Process.Kill()
+			cmd.Wait()
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		conn, err := grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock())
+		cancel()
+		if err != nil {
+			t.Fatalf("grpc.Dial(%v): %v", address, err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		s.addresses = append(s.addresses, address)
+		s.slaves = append(s.slaves, pb.NewSTLDiceClient(conn))
+	}
+	return s
+}
+
+func TestFanOutRealSlaves(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that builds and runs stldice-slave servers in short mode")
+	}
+	s := startRealSlaves(t, 3)
+	ctx := context.Background()
+	// A 10x10x10 voxel region of 0.5mm voxels.
+	region := &pb.VoxelRegion{Llx: -2.5, Lly: -2.5, Llz: -2.5, Urx: 2.5, Ury: 2.5, Urz: 2.5, Nx: 10, Ny: 10, Nz: 10}
+	// The first box misses most of the subregions, so their slaves start
+	// out with empty models that the second box is then added to.
+	first := box(t, gl.V(-2.1, -2.1, -2.1), gl.V(-1.1, -1.1, -1.1))
+	second := box(t, gl.V(-1.4, -1.9, -1.6), gl.V(2.1, 1.4, 1.9))
+	sub := box(t, gl.V(-0.9, -0.9, -0.4), gl.V(0.4, 0.9, 3.1))
+
+	if _, err := s.AddSTLMesh(ctx, &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: first}); err != nil {
+		t.Fatalf("AddSTLMesh: %v", err)
+	}
+	if _, err := s.AddSTLMesh(ctx, &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: second}); err != nil {
+		t.Fatalf("second AddSTLMesh: %v", err)
+	}
+	if _, err := s.SubSTLMesh(ctx, &pb.SubSTLMeshRequest{VoxelRegion: region, StlFile: sub}); err != nil {
+		t.Fatalf("SubSTLMesh: %v", err)
+	}
+
+	// Retrieving the subregions from the slaves leaves their models intact.
+	subs, err := s.split(region)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	for _, sub := range subs {
+		c := s.slaves[sub.index%len(s.slaves)]
+		for i := 0; i < 2; i++ {
+			stream, err := c.GetSTLMeshStream(ctx, &pb.GetSTLMeshRequest{VoxelRegion: sub.region})
+			if err != nil {
+				t.Fatalf("GetSTLMeshStream: %v", err)
+			}
+			if _, err := pb.ReceiveChunks(stream.Recv); err != nil {
+				t.Fatalf("subregion #%v GetSTLMeshStream #%v: %v", sub.index, i, err)
+			}
+		}
+	}
+
+	reply, err := s.GetSTLMesh(ctx, &pb.GetSTLMeshRequest{VoxelRegion: region})
+	if err != nil {
+		t.Fatalf("GetSTLMesh: %v", err)
+	}
+	got, err := stlToMesh(reply.GetStlFile())
+	if err != nil {
+		t.Fatalf("stlToMesh: %v", err)
+	}
+	compareMeshes(t, got, wholeMesh(t, region, [][]byte{first, second}, [][]byte{sub}))
+
+	// The slaves are reset once the subregions have been merged.
+	if _, err := s.GetSTLMesh(ctx, &pb.GetSTLMeshRequest{VoxelRegion: region}); err == nil {
+		t.Error("GetSTLMesh after reset = nil error, want error")
+	}
+}
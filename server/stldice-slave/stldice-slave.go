@@ -9,9 +9,13 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	gl "github.com/fogleman/fauxgl"
 	"github.com/gmlewis/stldice/v4/binvox"
@@ -20,86 +24,239 @@ import (
 	"google.golang.org/grpc"
 )
 
-const modelFilePrefix = "model"
+const (
+	modelFilePrefix = "model"
+	maxSize         = 1 << 30 // 1GB
+)
 
 var (
 	port = flag.String("port", ":5333", "Port to listen on")
+	dir  = flag.String("dir", ".", "Directory in which to store the region models")
 )
 
 // server is used to implement stldice.STLDiceServer
-type server struct{}
+type server struct {
+	dir string // directory of the region models
+
+	mu    sync.Mutex             // protects locks
+	locks map[string]*sync.Mutex // per-region model file locks
+}
+
+func newServer(dir string) *server {
+	return &server{dir: dir, locks: map[string]*sync.Mutex{}}
+}
+
+// lock locks the model file of a region and returns the function that unlocks it.
+func (s *server) lock(modelFilename string) func() {
+	s.mu.Lock()
+	l, ok := s.locks[modelFilename]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[modelFilename] = l
+	}
+	s.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
 
 // Add an STL mesh to the voxels (possibly a subregion on the slave).
 func (s *server) AddSTLMesh(ctx context.Context, in *pb.AddSTLMeshRequest) (*pb.AddSTLMeshReply, error) {
 	// Voxelize the STL file using the provided VoxelRegion.
 	mesh, err := stlToMesh(in.GetStlFile())
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("AddSTLMesh: bad STL file: %v", err)
 	}
 	addBV, err := voxelize(in.GetVoxelRegion(), mesh)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("AddSTLMesh: %v", err)
 	}
 
+	modelFilename := s.filename(in.GetVoxelRegion())
+	defer s.lock(modelFilename)()
+
 	// If there is no model on disk with the same coordinates, write the new binvox file.
-	modelFilename := filename(in.GetVoxelRegion())
-	base, err := binvox.Read(modelFilename, 0, 0, 0, 0, 0, 0)
-	if err != nil { // No existing model of the same name. Write the new one.
-		if err := mesh.SaveSTL(modelFilename); err != nil {
-			return nil, err
+	if _, err := os.Stat(modelFilename); os.IsNotExist(err) {
+		if err := writeModel(addBV, modelFilename); err != nil {
+			return nil, fmt.Errorf("AddSTLMesh: %v", err)
 		}
 		return &pb.AddSTLMeshReply{}, nil // Success.
 	}
 
 	// If there already is a model on disk, load it, add the newly-generated voxels to it, then save it back out,
 	// overwriting the old version.
-	newBase := voxelOp(base, addBV, true)
-	if err := newBase.Write(modelFilename, 0, 0, 0, 0, 0, 0); err != nil {
-		return nil, err
+	base, err := readModel(modelFilename, addBV)
+	if err != nil {
+		return nil, fmt.Errorf("AddSTLMesh: %v", err)
+	}
+	newBase, err := voxelOp(base, addBV, true)
+	if err != nil {
+		return nil, fmt.Errorf("AddSTLMesh: %v", err)
+	}
+	if err := writeModel(newBase, modelFilename); err != nil {
+		return nil, fmt.Errorf("AddSTLMesh: %v", err)
 	}
 	return &pb.AddSTLMeshReply{}, nil // Success.
 }
 
 // Subtract an STL mesh from the voxels (possibly a subregion on the slave).
 func (s *server) SubSTLMesh(ctx context.Context, in *pb.SubSTLMeshRequest) (*pb.SubSTLMeshReply, error) {
-	// If there is no model on disk with the same coordinates, return an error.
-	modelFilename := filename(in.GetVoxelRegion())
-	// Load the model binvox file from disk.
-	base, err := binvox.Read(modelFilename, 0, 0, 0, 0, 0, 0)
-	if err != nil {
-		return nil, fmt.Errorf("unable to subtract, no existing model: %v", err)
-	}
-
 	// Voxelize the STL file using the provided VoxelRegion.
 	mesh, err := stlToMesh(in.GetStlFile())
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("SubSTLMesh: bad STL file: %v", err)
 	}
 	subBV, err := voxelize(in.GetVoxelRegion(), mesh)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("SubSTLMesh: %v", err)
+	}
+
+	modelFilename := s.filename(in.GetVoxelRegion())
+	defer s.lock(modelFilename)()
+
+	// If there is no model on disk with the same coordinates, return an error.
+	// Load the model binvox file from disk.
+	base, err := readModel(modelFilename, subBV)
+	if err != nil {
+		return nil, fmt.Errorf("unable to subtract, no existing model: %v", err)
 	}
 
 	// Cut the base model with this mesh, then save it back out, overwriting the old version.
-	newBase := voxelOp(base, subBV, false)
-	if err := newBase.Write(modelFilename, 0, 0, 0, 0, 0, 0); err != nil {
-		return nil, err
+	newBase, err := voxelOp(base, subBV, false)
+	if err != nil {
+		return nil, fmt.Errorf("SubSTLMesh: %v", err)
+	}
+	if err := writeModel(newBase, modelFilename); err != nil {
+		return nil, fmt.Errorf("SubSTLMesh: %v", err)
 	}
 	return &pb.SubSTLMeshReply{}, nil
 }
 
 // Get back the model voxels as STL (possibly a subregion on the slave).
-// This also resets the server by clearing out the model.
+// The model is left intact so that a lost reply can be retried.
 func (s *server) GetSTLMesh(ctx context.Context, in *pb.GetSTLMeshRequest) (*pb.GetSTLMeshReply, error) {
+	modelFilename := s.filename(in.GetVoxelRegion())
+	defer s.lock(modelFilename)()
+
 	// Load the model binvox file from disk.
-	modelFilename := filename(in.GetVoxelRegion())
 	base, err := binvox.Read(modelFilename, 0, 0, 0, 0, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("GetSTLMesh: no model: %v", err)
+	}
+
+	buf, err := meshToSTL(base.ManifoldMesh())
+	if err != nil {
+		return nil, fmt.Errorf("GetSTLMesh: %v", err)
+	}
+	return &pb.GetSTLMeshReply{StlFile: buf}, nil
+}
+
+// Reset the server by clearing out the model (possibly a subregion on the slave).
+// Resetting a region without a model is not an error.
+func (s *server) ResetModel(ctx context.Context, in *pb.ResetModelRequest) (*pb.ResetModelReply, error) {
+	modelFilename := s.filename(in.GetVoxelRegion())
+	defer s.lock(modelFilename)()
+
+	if err := os.Remove(modelFilename); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("ResetModel: %v", err)
+	}
+	return &pb.ResetModelReply{}, nil
+}
+
+// AddSTLMeshStream is AddSTLMesh with the STL file streamed in chunks.
+func (s *server) AddSTLMeshStream(stream pb.STLDice_AddSTLMeshStreamServer) error {
+	region, stlFile, err := pb.ReceiveSTLMesh(stream.Recv)
+	if err != nil {
+		return fmt.Errorf("AddSTLMeshStream: %v", err)
+	}
+	reply, err := s.AddSTLMesh(stream.Context(), &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: stlFile})
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(reply)
+}
+
+// SubSTLMeshStream is SubSTLMesh with the STL file streamed in chunks.
+func (s *server) SubSTLMeshStream(stream pb.STLDice_SubSTLMeshStreamServer) error {
+	region, stlFile, err := pb.ReceiveSTLMesh(stream.Recv)
+	if err != nil {
+		return fmt.Errorf("SubSTLMeshStream: %v", err)
+	}
+	reply, err := s.SubSTLMesh(stream.Context(), &pb.SubSTLMeshRequest{VoxelRegion: region, StlFile: stlFile})
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(reply)
+}
+
+// GetSTLMeshStream is GetSTLMesh with the STL file streamed back in chunks.
+func (s *server) GetSTLMeshStream(in *pb.GetSTLMeshRequest, stream pb.STLDice_GetSTLMeshStreamServer) error {
+	reply, err := s.GetSTLMesh(stream.Context(), in)
+	if err != nil {
+		return err
+	}
+	return pb.SendChunks(reply.GetStlFile(), stream.Send)
+}
+
+func main() {
+	flag.Parse()
+
+	lis, err := net.Listen("tcp", *port)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	s := grpc.NewServer(grpc.MaxRecvMsgSize(maxSize), grpc.MaxSendMsgSize(maxSize))
+	pb.RegisterSTLDiceServer(s, newServer(*dir))
+	s.Serve(lis)
+}
+
+// filename returns the model filename for the given VoxelRegion.
+func (s *server) filename(voxelRegion *pb.VoxelRegion) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%v-%v-%v-%v-%v-%v-%v-%v-%v-%v.binvox", modelFilePrefix,
+		voxelRegion.GetLlx(), voxelRegion.GetLly(), voxelRegion.GetLlz(),
+		voxelRegion.GetUrx(), voxelRegion.GetUry(), voxelRegion.GetUrz(),
+		voxelRegion.GetNx(), voxelRegion.GetNy(), voxelRegion.GetNz()))
+}
+
+// readModel reads the model file of a region. A model without any voxels
+// is written without its dimensions and scale (see binvox.Write), so it is
+// returned as an empty model on the grid of region, the voxelized mesh of
+// the current request.
+func readModel(modelFilename string, region *binvox.BinVOX) (*binvox.BinVOX, error) {
+	b, err := binvox.Read(modelFilename, 0, 0, 0, 0, 0, 0)
 	if err != nil {
 		return nil, err
 	}
+	if len(b.WhiteVoxels) > 0 {
+		return b, nil
+	}
+	empty := *region
+	empty.WhiteVoxels = binvox.WhiteVoxelMap{}
+	empty.ColorVoxels = nil
+	return &empty, nil
+}
 
-	mesh := base.ManifoldMesh()
+// writeModel atomically replaces the model file by writing the model to
+// a temporary file in the same directory and then renaming it.
+func writeModel(b *binvox.BinVOX, modelFilename string) error {
+	f, err := ioutil.TempFile(filepath.Dir(modelFilename), filepath.Base(modelFilename)+".tmp")
+	if err != nil {
+		return fmt.Errorf("unable to create temporary file: %v", err)
+	}
+	tmpFilename := f.Name()
+	f.Close()
+	if err := b.Write(tmpFilename, 0, 0, 0, 0, 0, 0); err != nil {
+		os.Remove(tmpFilename)
+		return err
+	}
+	if err := os.Rename(tmpFilename, modelFilename); err != nil {
+		os.Remove(tmpFilename)
+		return fmt.Errorf("unable to rename %q: %v", tmpFilename, err)
+	}
+	return nil
+}
 
+// meshToSTL returns the mesh as binary STL data.
+func meshToSTL(mesh *gl.Mesh) ([]byte, error) {
 	var buf bytes.Buffer
 	header := gl.STLHeader{}
 	header.Count = uint32(len(mesh.Triangles))
@@ -125,25 +282,7 @@ func (s *server) GetSTLMesh(ctx context.Context, in *pb.GetSTLMeshRequest) (*pb.
 			return nil, err
 		}
 	}
-	return &pb.GetSTLMeshReply{}, nil
-}
-
-func main() {
-	lis, err := net.Listen("tcp", *port)
-	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
-	}
-	s := grpc.NewServer()
-	pb.RegisterSTLDiceServer(s, &server{})
-	s.Serve(lis)
-}
-
-// filename returns the model filename for the given VoxelRegion.
-func filename(voxelRegion *pb.VoxelRegion) string {
-	return fmt.Sprintf("%v-%v-%v-%v-%v-%v-%v-%v-%v-%v.binvox", modelFilePrefix,
-		voxelRegion.GetLlx(), voxelRegion.GetLly(), voxelRegion.GetLlz(),
-		voxelRegion.GetUrx(), voxelRegion.GetUry(), voxelRegion.GetUrz(),
-		voxelRegion.GetNx(), voxelRegion.GetNy(), voxelRegion.GetNz())
+	return buf.Bytes(), nil
 }
 
 // stlToMesh parses STL data and returns a mesh.
@@ -219,11 +358,32 @@ func loadSTLB(r io.Reader) (*gl.Mesh, error) {
 
 // voxelize voxelizes a mesh using the provided VoxelRegion.
 func voxelize(voxelRegion *pb.VoxelRegion, mesh *gl.Mesh) (*binvox.BinVOX, error) {
-	// TODO(gmlewis); write this.
-	return nil, nil
+	if voxelRegion == nil {
+		return nil, fmt.Errorf("missing voxel region")
+	}
+	nx, ny, nz := int(voxelRegion.GetNx()), int(voxelRegion.GetNy()), int(voxelRegion.GetNz())
+	if nx <= 0 || ny <= 0 || nz <= 0 || voxelRegion.GetUrx() <= voxelRegion.GetLlx() {
+		return nil, fmt.Errorf("invalid voxel region: %v", voxelRegion)
+	}
+	mmpv := (voxelRegion.GetUrx() - voxelRegion.GetLlx()) / float64(nx) // voxels are cubes
+	b := binvox.New(nx, ny, nz, voxelRegion.GetLlx(), voxelRegion.GetLly(), voxelRegion.GetLlz(), 0, false)
+	b.Scale = float64(b.Dim()) * mmpv
+	if err := b.Voxelize(mesh); err != nil {
+		return nil, err
+	}
+	return b, nil
 }
 
-// voxelOp performs a boolean union or a boolean difference on the two meshes and returns the result.
-func voxelOp(base, opVoxels *binvox.BinVOX, union bool) *binvox.BinVOX {
-	return nil
+// voxelOp performs a boolean union or a boolean difference on the two voxel models and returns the result.
+func voxelOp(base, opVoxels *binvox.BinVOX, union bool) (*binvox.BinVOX, error) {
+	if union {
+		if err := base.Union(opVoxels); err != nil {
+			return nil, err
+		}
+		return base, nil
+	}
+	if err := base.Difference(opVoxels); err != nil {
+		return nil, err
+	}
+	return base, nil
 }
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+	pb "github.com/gmlewis/stldice/v4/stldice"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startSlave serves a slave over an in-memory connection and returns a client for it.
+func startSlave(t *testing.T) pb.STLDiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	pb.RegisterSTLDiceServer(gs, newServer(t.TempDir()))
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(dialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc.DialContext: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewSTLDiceClient(conn)
+}
+
+// box returns the binary STL data of an axis-aligned box.
+func box(t *testing.T, min, max gl.Vector) []byte {
+	t.Helper()
+	mesh := gl.NewCube()
+	mesh.Transform(gl.Scale(max.Sub(min)).Translate(min.Add(max).MulScalar(0.5)))
+	buf, err := meshToSTL(mesh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+// sortedTriangles returns the triangles as sorted strings for comparison.
+func sortedTriangles(mesh *gl.Mesh) []string {
+	var result []string
+	for _, t := range mesh.Triangles {
+		result = append(result, fmt.Sprintf("%.4f %.4f %.4f", t.V1.Position, t.V2.Position, t.V3.Position))
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestRoundTrip(t *testing.T) {
+	c := startSlave(t)
+	ctx := context.Background()
+	// An 8x8x8 voxel region of 0.5mm voxels.
+	region := &pb.VoxelRegion{Llx: -2, Lly: -2, Llz: -2, Urx: 2, Ury: 2, Urz: 2, Nx: 8, Ny: 8, Nz: 8}
+	add := box(t, gl.V(-1.4, -1.4, -1.4), gl.V(1.4, 1.4, 1.4))
+	sub := box(t, gl.V(-0.4, -0.4, -0.4), gl.V(0.4, 0.4, 3))
+
+	if _, err := c.SubSTLMesh(ctx, &pb.SubSTLMeshRequest{VoxelRegion: region, StlFile: sub}); err == nil {
+		t.Error("SubSTLMesh with no model = nil error, want error")
+	}
+	if _, err := c.AddSTLMesh(ctx, &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: add}); err != nil {
+		t.Fatalf("AddSTLMesh: %v", err)
+	}
+	if _, err := c.SubSTLMesh(ctx, &pb.SubSTLMeshRequest{VoxelRegion: region, StlFile: sub}); err != nil {
+		t.Fatalf("SubSTLMesh: %v", err)
+	}
+	reply, err := c.GetSTLMesh(ctx, &pb.GetSTLMeshRequest{VoxelRegion: region})
+	if err != nil {
+		t.Fatalf("GetSTLMesh: %v", err)
+	}
+	got, err := stlToMesh(reply.GetStlFile())
+	if err != nil {
+		t.Fatalf("stlToMesh: %v", err)
+	}
+
+	// Build the same model directly.
+	want, err := voxelize(region, mustMesh(t, add))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cut, err := voxelize(region, mustMesh(t, sub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := voxelOp(want, cut, false); err != nil {
+		t.Fatal(err)
+	}
+	// A 6x6x6 voxel cube with a 2x2x4 voxel hole in its top.
+	if got, want := len(want.WhiteVoxels), 216-16; got != want {
+		t.Errorf("model has %v voxels, want %v", got, want)
+	}
+	gotTris, wantTris := sortedTriangles(got), sortedTriangles(want.ManifoldMesh())
+	if len(gotTris) != len(wantTris) {
+		t.Fatalf("GetSTLMesh returned %v triangles, want %v", len(gotTris), len(wantTris))
+	}
+	for i := range gotTris {
+		if gotTris[i] != wantTris[i] {
+			t.Errorf("triangle #%v = %v, want %v", i, gotTris[i], wantTris[i])
+		}
+	}
+
+	// GetSTLMesh leaves the model intact so that it can be retried.
+	again, err := c.GetSTLMesh(ctx, &pb.GetSTLMeshRequest{VoxelRegion: region})
+	if err != nil {
+		t.Fatalf("second GetSTLMesh: %v", err)
+	}
+	againMesh, err := stlToMesh(again.GetStlFile())
+	if err != nil {
+		t.Fatalf("second GetSTLMesh: bad STL file: %v", err)
+	}
+	if againTris := sortedTriangles(againMesh); len(againTris) != len(gotTris) {
+		t.Errorf("second GetSTLMesh returned %v triangles, want %v", len(againTris), len(gotTris))
+	}
+
+	// ResetModel clears out the model and may be repeated.
+	for i := 0; i < 2; i++ {
+		if _, err := c.ResetModel(ctx, &pb.ResetModelRequest{VoxelRegion: region}); err != nil {
+			t.Fatalf("ResetModel #%v: %v", i, err)
+		}
+	}
+	if _, err := c.GetSTLMesh(ctx, &pb.GetSTLMeshRequest{VoxelRegion: region}); err == nil {
+		t.Error("GetSTLMesh after ResetModel = nil error, want error")
+	}
+
+	if _, err := c.AddSTLMesh(ctx, &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: []byte("bogus")}); err == nil {
+		t.Error("AddSTLMesh with bad STL = nil error, want error")
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	c := startSlave(t)
+	ctx := context.Background()
+	region := &pb.VoxelRegion{Llx: -2, Lly: -2, Llz: -2, Urx: 2, Ury: 2, Urz: 2, Nx: 8, Ny: 8, Nz: 8}
+	add := box(t, gl.V(-1.4, -1.4, -1.4), gl.V(1.4, 1.4, 1.4))
+
+	stream, err := c.AddSTLMeshStream(ctx)
+	if err != nil {
+		t.Fatalf("AddSTLMeshStream: %v", err)
+	}
+	if err := pb.SendSTLMesh(region, add, stream.Send); err != nil {
+		t.Fatalf("SendSTLMesh: %v", err)
+	}
+	if _, err := stream.CloseAndRecv(); err != nil {
+		t.Fatalf("CloseAndRecv: %v", err)
+	}
+
+	get, err := c.GetSTLMeshStream(ctx, &pb.GetSTLMeshRequest{VoxelRegion: region})
+	if err != nil {
+		t.Fatalf("GetSTLMeshStream: %v", err)
+	}
+	buf, err := pb.ReceiveChunks(get.Recv)
+	if err != nil {
+		t.Fatalf("ReceiveChunks: %v", err)
+	}
+	got, err := stlToMesh(buf)
+	if err != nil {
+		t.Fatalf("stlToMesh: %v", err)
+	}
+	if got, want := got.BoundingBox(), (gl.Box{Min: gl.V(-1.5, -1.5, -1.5), Max: gl.V(1.5, 1.5, 1.5)}); got != want {
+		t.Errorf("mesh MBB = %v, want %v", got, want)
+	}
+
+	// A stream without a voxel region fails.
+	stream, err = c.AddSTLMeshStream(ctx)
+	if err != nil {
+		t.Fatalf("AddSTLMeshStream: %v", err)
+	}
+	if err := pb.SendSTLMesh(nil, add, stream.Send); err != nil {
+		t.Fatalf("SendSTLMesh: %v", err)
+	}
+	if _, err := stream.CloseAndRecv(); err == nil {
+		t.Error("AddSTLMeshStream without voxel region = nil error, want error")
+	}
+}
+
+func TestConcurrentAdds(t *testing.T) {
+	c := startSlave(t)
+	ctx := context.Background()
+	region := &pb.VoxelRegion{Llx: 0, Lly: 0, Llz: 0, Urx: 8, Ury: 8, Urz: 1, Nx: 8, Ny: 8, Nz: 1}
+
+	// Add 8 disjoint 1x8 voxel bars at the same time.
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bar := box(t, gl.V(float64(i)+0.1, 0.1, 0.1), gl.V(float64(i)+0.9, 7.9, 0.9))
+			_, errs[i] = c.AddSTLMesh(ctx, &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: bar})
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("AddSTLMesh #%v: %v", i, err)
+		}
+	}
+
+	reply, err := c.GetSTLMesh(ctx, &pb.GetSTLMeshRequest{VoxelRegion: region})
+	if err != nil {
+		t.Fatalf("GetSTLMesh: %v", err)
+	}
+	got, err := stlToMesh(reply.GetStlFile())
+	if err != nil {
+		t.Fatalf("stlToMesh: %v", err)
+	}
+	// No additions are lost: the result is a single 8x8x1 voxel slab.
+	if got, want := got.BoundingBox(), (gl.Box{Min: gl.V(0, 0, 0), Max: gl.V(8, 8, 1)}); got != want {
+		t.Errorf("mesh MBB = %v, want %v", got, want)
+	}
+	if got, want := got.SurfaceArea(), 2*64+4*8.0; got != want {
+		t.Errorf("mesh area = %v, want %v", got, want)
+	}
+}
+
+func TestWriteModel(t *testing.T) {
+	dir := t.TempDir()
+	region := &pb.VoxelRegion{Llx: 0, Lly: 0, Llz: 0, Urx: 2, Ury: 2, Urz: 2, Nx: 2, Ny: 2, Nz: 2}
+	s := newServer(dir)
+	b, err := voxelize(region, mustMesh(t, box(t, gl.V(0.1, 0.1, 0.1), gl.V(1.9, 1.9, 1.9))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeModel(b, s.filename(region)); err != nil {
+		t.Fatalf("writeModel: %v", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != s.filename(region) {
+		t.Errorf("files = %v, want only %v (no temporary files)", files, s.filename(region))
+	}
+}
+
+func mustMesh(t *testing.T, buf []byte) *gl.Mesh {
+	t.Helper()
+	mesh, err := stlToMesh(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mesh
+}
+
+func TestEmptyRegion(t *testing.T) {
+	c := startSlave(t)
+	ctx := context.Background()
+	region := &pb.VoxelRegion{Llx: -2, Lly: -2, Llz: -2, Urx: 2, Ury: 2, Urz: 2, Nx: 8, Ny: 8, Nz: 8}
+	outside := box(t, gl.V(10, 10, 10), gl.V(12, 12, 12))
+	inside := box(t, gl.V(-1.4, -1.4, -1.4), gl.V(1.4, 1.4, 1.4))
+
+	// A mesh that misses the region leaves an empty model that later meshes can be added to.
+	if _, err := c.AddSTLMesh(ctx, &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: outside}); err != nil {
+		t.Fatalf("AddSTLMesh outside the region: %v", err)
+	}
+	if _, err := c.SubSTLMesh(ctx, &pb.SubSTLMeshRequest{VoxelRegion: region, StlFile: outside}); err != nil {
+		t.Fatalf("SubSTLMesh from empty model: %v", err)
+	}
+	if _, err := c.AddSTLMesh(ctx, &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: inside}); err != nil {
+		t.Fatalf("AddSTLMesh after empty model: %v", err)
+	}
+
+	// Subtracting everything empties the model again.
+	if _, err := c.SubSTLMesh(ctx, &pb.SubSTLMeshRequest{VoxelRegion: region, StlFile: inside}); err != nil {
+		t.Fatalf("SubSTLMesh: %v", err)
+	}
+	if _, err := c.AddSTLMesh(ctx, &pb.AddSTLMeshRequest{VoxelRegion: region, StlFile: inside}); err != nil {
+		t.Fatalf("AddSTLMesh after emptied model: %v", err)
+	}
+	reply, err := c.GetSTLMesh(ctx, &pb.GetSTLMeshRequest{VoxelRegion: region})
+	if err != nil {
+		t.Fatalf("GetSTLMesh: %v", err)
+	}
+	got, err := stlToMesh(reply.GetStlFile())
+	if err != nil {
+		t.Fatalf("stlToMesh: %v", err)
+	}
+	if got, want := got.BoundingBox(), (gl.Box{Min: gl.V(-1.5, -1.5, -1.5), Max: gl.V(1.5, 1.5, 1.5)}); got != want {
+		t.Errorf("mesh MBB = %v, want %v", got, want)
+	}
+}
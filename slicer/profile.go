@@ -0,0 +1,133 @@
+package slicer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Layout represents the layout of the archive written for a printer.
+type Layout string
+
+const (
+	// SL1 is the Prusa SL1 layout: a config.ini file and one PNG per
+	// layer named <job>00000.png, <job>00001.png, etc.
+	SL1 Layout = "sl1"
+
+	// ChiTu is the ChiTuBox (Anycubic Photon, Elegoo Mars, etc.) zip
+	// layout: a run.gcode file and one PNG per layer named 1.png,
+	// 2.png, etc.
+	ChiTu Layout = "chitu"
+)
+
+// Profile describes a resin (DLP/SLA/MSLA) printer and how its layer
+// images are written.
+type Profile struct {
+	Name string `json:"name"`
+
+	ResolutionX int     `json:"resolutionX"` // width of each layer image in pixels
+	ResolutionY int     `json:"resolutionY"` // height of each layer image in pixels
+	PixelPitch  float64 `json:"pixelPitch"`  // size of each pixel in millimeters
+	LayerHeight float64 `json:"layerHeight"` // thickness of each layer in millimeters
+
+	MirrorX bool `json:"mirrorX"` // mirror each image horizontally
+	MirrorY bool `json:"mirrorY"` // mirror each image vertically
+
+	// AntiAlias is the number of samples taken along each axis of a
+	// pixel (so AntiAlias*AntiAlias samples per pixel) to produce gray
+	// levels at the edges of the model. 0 or 1 means no anti-aliasing.
+	AntiAlias int `json:"antiAlias"`
+
+	Layout Layout `json:"layout"`
+
+	ExposureTime       float64 `json:"exposureTime"`       // seconds per layer
+	BottomExposureTime float64 `json:"bottomExposureTime"` // seconds per bottom layer
+	BottomLayers       int     `json:"bottomLayers"`       // number of bottom layers
+	LiftHeight         float64 `json:"liftHeight"`         // millimeters to lift after each layer
+	LiftSpeed          float64 `json:"liftSpeed"`          // lift speed in millimeters per minute
+	RetractSpeed       float64 `json:"retractSpeed"`       // retract speed in millimeters per minute
+}
+
+// Profiles are the built-in printer profiles, keyed by name.
+var Profiles = map[string]*Profile{
+	"sl1": {
+		Name:        "Original Prusa SL1",
+		ResolutionX: 1440, ResolutionY: 2560,
+		PixelPitch: 0.046875, LayerHeight: 0.05,
+		AntiAlias:    4,
+		Layout:       SL1,
+		ExposureTime: 8, BottomExposureTime: 35, BottomLayers: 10,
+	},
+	"photon": {
+		Name:        "Anycubic Photon",
+		ResolutionX: 1440, ResolutionY: 2560,
+		PixelPitch: 0.04725, LayerHeight: 0.05,
+		AntiAlias:    1,
+		Layout:       ChiTu,
+		ExposureTime: 8, BottomExposureTime: 60, BottomLayers: 6,
+		LiftHeight: 5, LiftSpeed: 65, RetractSpeed: 150,
+	},
+	"mars": {
+		Name:        "Elegoo Mars",
+		ResolutionX: 1440, ResolutionY: 2560,
+		PixelPitch: 0.04725, LayerHeight: 0.05,
+		AntiAlias:    4,
+		Layout:       ChiTu,
+		ExposureTime: 8, BottomExposureTime: 50, BottomLayers: 6,
+		LiftHeight: 5, LiftSpeed: 60, RetractSpeed: 150,
+	},
+}
+
+// ProfileNames returns the sorted names of the built-in profiles.
+func ProfileNames() []string {
+	var names []string
+	for k := range Profiles {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadProfile returns the built-in profile with the given name or,
+// failing that, reads a profile from the named JSON file.
+func LoadProfile(name string) (*Profile, error) {
+	if p, ok := Profiles[strings.ToLower(name)]; ok {
+		return p, nil
+	}
+	buf, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown profile %q (built-in profiles: %v): %v", name, strings.Join(ProfileNames(), ", "), err)
+	}
+	p := &Profile{}
+	if err := json.Unmarshal(buf, p); err != nil {
+		return nil, fmt.Errorf("unable to parse profile %q: %v", name, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("profile %q: %v", name, err)
+	}
+	return p, nil
+}
+
+// Validate reports whether the profile is usable.
+func (p *Profile) Validate() error {
+	if p.ResolutionX <= 0 || p.ResolutionY <= 0 {
+		return fmt.Errorf("resolution must be positive: %vx%v", p.ResolutionX, p.ResolutionY)
+	}
+	if p.PixelPitch <= 0 {
+		return fmt.Errorf("pixel pitch must be positive: %v", p.PixelPitch)
+	}
+	if p.LayerHeight <= 0 {
+		return fmt.Errorf("layer height must be positive: %v", p.LayerHeight)
+	}
+	if p.AntiAlias < 0 {
+		return fmt.Errorf("anti-alias must not be negative: %v", p.AntiAlias)
+	}
+	switch p.Layout {
+	case SL1, ChiTu:
+	default:
+		return fmt.Errorf("unknown layout %q", p.Layout)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+// Package slicer writes the layer images of voxel models for resin
+// (DLP/SLA/MSLA) printers using printer profiles that describe the
+// resolution, pixel pitch, layer height, mirroring, anti-aliasing and
+// archive layout expected by each printer.
+package slicer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"math"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+// Slicer slices a voxel model into layer images for a printer.
+type Slicer struct {
+	Profile *Profile
+
+	vpmm   float64
+	origin gl.Vector // world location of the corner of global voxel (0,0,0)
+	layers map[int]map[[2]int]struct{}
+	mbb    gl.Box    // world MBB of the solid voxels
+	plate  gl.Vector // translation from world space to the build plate
+}
+
+// New returns a Slicer for a model that has been diced into the given
+// subregions (such as those written by stldice), all of which must share
+// the same voxel grid. The model is centered on the build plate and its
+// lowest voxel rests on the plate.
+func New(profile *Profile, regions []*binvox.BinVOX) (*Slicer, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("no regions")
+	}
+	s := &Slicer{
+		Profile: profile,
+		vpmm:    regions[0].VoxelsPerMM(),
+		origin:  gl.V(regions[0].TX, regions[0].TY, regions[0].TZ),
+		layers:  map[int]map[[2]int]struct{}{},
+	}
+
+	min := binvox.Key{X: math.MaxInt32, Y: math.MaxInt32, Z: math.MaxInt32}
+	max := binvox.Key{X: math.MinInt32, Y: math.MinInt32, Z: math.MinInt32}
+	var n int
+	for i, r := range regions {
+		dx, dy, dz, err := binvox.GridOffset(regions[0], r)
+		if err != nil {
+			return nil, fmt.Errorf("region #%v: %v", i, err)
+		}
+		keyFunc := func(k binvox.Key) {
+			if k.X < 0 || k.Y < 0 || k.Z < 0 || k.X >= r.NX || k.Y >= r.NY || k.Z >= r.NZ {
+				return
+			}
+			g := binvox.Key{X: k.X + dx, Y: k.Y + dy, Z: k.Z + dz}
+			layer, ok := s.layers[g.Z]
+			if !ok {
+				layer = map[[2]int]struct{}{}
+				s.layers[g.Z] = layer
+			}
+			layer[[2]int{g.X, g.Y}] = struct{}{}
+			n++
+			min = binvox.Key{X: imin(min.X, g.X), Y: imin(min.Y, g.Y), Z: imin(min.Z, g.Z)}
+			max = binvox.Key{X: imax(max.X, g.X), Y: imax(max.Y, g.Y), Z: imax(max.Z, g.Z)}
+		}
+		for k := range r.WhiteVoxels {
+			keyFunc(k)
+		}
+		for k := range r.ColorVoxels {
+			keyFunc(k)
+		}
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("model has no voxels")
+	}
+
+	mmpv := 1 / s.vpmm
+	s.mbb = gl.Box{
+		Min: s.origin.Add(gl.V(float64(min.X), float64(min.Y), float64(min.Z)).MulScalar(mmpv)),
+		Max: s.origin.Add(gl.V(float64(max.X+1), float64(max.Y+1), float64(max.Z+1)).MulScalar(mmpv)),
+	}
+	size := s.mbb.Size()
+	w := float64(profile.ResolutionX) * profile.PixelPitch
+	h := float64(profile.ResolutionY) * profile.PixelPitch
+	if size.X > w || size.Y > h {
+		return nil, fmt.Errorf("model (%.2f x %.2f mm) does not fit on the %v build plate (%.2f x %.2f mm)", size.X, size.Y, profile.Name, w, h)
+	}
+	c := s.mbb.Center()
+	s.plate = gl.V(0.5*w-c.X, 0.5*h-c.Y, -s.mbb.Min.Z)
+	log.Printf("Slicing %v voxels with MBB %v into %v layers for the %v", n, s.mbb, s.NumLayers(), profile.Name)
+	return s, nil
+}
+
+// NumLayers returns the number of layers needed to print the model.
+func (s *Slicer) NumLayers() int {
+	return int(math.Ceil(s.mbb.Size().Z/s.Profile.LayerHeight - 1e-9))
+}
+
+// Layer returns the image of layer i (starting with 0 at the build plate).
+// The top row of the image is at the maximum Y of the build plate (unless
+// mirrored by the profile). Each pixel is sampled at the middle of the
+// layer, and with anti-aliasing, the brightness of each pixel is the
+// fraction of its samples that lie within the model.
+func (s *Slicer) Layer(i int) *image.Gray {
+	p := s.Profile
+	img := image.NewGray(image.Rect(0, 0, p.ResolutionX, p.ResolutionY))
+
+	z := (float64(i)+0.5)*p.LayerHeight - s.plate.Z
+	layer := s.layers[int(math.Floor((z-s.origin.Z)*s.vpmm))]
+	if len(layer) == 0 {
+		return img
+	}
+
+	aa := p.AntiAlias
+	if aa < 1 {
+		aa = 1
+	}
+	// Only pixels covering the MBB of the model need to be sampled.
+	min := s.mbb.Min.Add(s.plate).DivScalar(p.PixelPitch)
+	max := s.mbb.Max.Add(s.plate).DivScalar(p.PixelPitch)
+	x0, x1 := imax(0, int(math.Floor(min.X))), imin(p.ResolutionX-1, int(math.Ceil(max.X)))
+	y0, y1 := imax(0, int(math.Floor(min.Y))), imin(p.ResolutionY-1, int(math.Ceil(max.Y)))
+
+	for py := y0; py <= y1; py++ {
+		for px := x0; px <= x1; px++ {
+			var hits int
+			for sy := 0; sy < aa; sy++ {
+				for sx := 0; sx < aa; sx++ {
+					wx := (float64(px)+(float64(sx)+0.5)/float64(aa))*p.PixelPitch - s.plate.X
+					wy := (float64(py)+(float64(sy)+0.5)/float64(aa))*p.PixelPitch - s.plate.Y
+					k := [2]int{int(math.Floor((wx - s.origin.X) * s.vpmm)), int(math.Floor((wy - s.origin.Y) * s.vpmm))}
+					if _, ok := layer[k]; ok {
+						hits++
+					}
+				}
+			}
+			if hits == 0 {
+				continue
+			}
+			col, row := px, p.ResolutionY-1-py
+			if p.MirrorX {
+				col = p.ResolutionX - 1 - col
+			}
+			if p.MirrorY {
+				row = p.ResolutionY - 1 - row
+			}
+			img.SetGray(col, row, color.Gray{Y: uint8(math.Round(255 * float64(hits) / float64(aa*aa)))})
+		}
+	}
+	return img
+}
+
+func imin(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func imax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,225 @@
+package slicer
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+// cube returns an n*n*n voxel cube at 2 voxels per millimeter.
+func cube(n int) *binvox.BinVOX {
+	b := binvox.New(n, n, n, 100, 200, 300, 0, false)
+	b.Scale = float64(n) / 2
+	for z := 0; z < n; z++ {
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				b.Add(x, y, z)
+			}
+		}
+	}
+	return b
+}
+
+func testProfile(layout Layout) *Profile {
+	return &Profile{
+		Name:        "test",
+		ResolutionX: 20, ResolutionY: 10,
+		PixelPitch: 0.5, LayerHeight: 0.5,
+		Layout:       layout,
+		ExposureTime: 2, BottomExposureTime: 10, BottomLayers: 1,
+		LiftHeight: 5, LiftSpeed: 60, RetractSpeed: 150,
+	}
+}
+
+// lit returns the set of non-black pixels of layer i.
+func lit(s *Slicer, i int) map[[2]int]uint8 {
+	img := s.Layer(i)
+	result := map[[2]int]uint8{}
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			if v := img.GrayAt(x, y).Y; v > 0 {
+				result[[2]int{x, y}] = v
+			}
+		}
+	}
+	return result
+}
+
+func TestLayer(t *testing.T) {
+	// Dice a 4x4x4 voxel (2mm) cube into two regions.
+	b := cube(4)
+	r0 := binvox.New(2, 4, 4, b.TX, b.TY, b.TZ, b.Scale, false)
+	r1 := binvox.New(2, 4, 4, b.TX+1, b.TY, b.TZ, b.Scale, false)
+	for k := range b.WhiteVoxels {
+		if k.X < 2 {
+			r0.Add(k.X, k.Y, k.Z)
+		} else {
+			r1.Add(k.X-2, k.Y, k.Z)
+		}
+	}
+
+	p := testProfile(SL1)
+	p.MirrorX = true
+	s, err := New(p, []*binvox.BinVOX{r0, r1})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got, want := s.NumLayers(), 4; got != want {
+		t.Errorf("NumLayers = %v, want %v", got, want)
+	}
+	// The cube is centered on the 10x5mm plate: pixels 8-11 in X and 3-6 in Y.
+	for i := 0; i < 4; i++ {
+		got := lit(s, i)
+		if len(got) != 16 {
+			t.Errorf("layer %v has %v lit pixels, want 16", i, len(got))
+		}
+		for k, v := range got {
+			if k[0] < 8 || k[0] > 11 || k[1] < 3 || k[1] > 6 || v != 255 {
+				t.Errorf("layer %v pixel %v = %v, want no such pixel", i, k, v)
+			}
+		}
+	}
+	if got := lit(s, 4); len(got) != 0 {
+		t.Errorf("layer 4 has %v lit pixels, want 0", len(got))
+	}
+
+	// Too big for the plate.
+	if _, err := New(p, []*binvox.BinVOX{cube(12)}); err == nil {
+		t.Error("New with oversized model = nil error, want error")
+	}
+}
+
+func TestAntiAlias(t *testing.T) {
+	// A 3 voxel (1.5mm) cube centered on the plate has edges halfway across pixels.
+	p := testProfile(SL1)
+	p.AntiAlias = 2
+	s, err := New(p, []*binvox.BinVOX{cube(3)})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	got := lit(s, 0)
+	want := map[[2]int]uint8{
+		{8, 3}: 64, {9, 3}: 128, {10, 3}: 128, {11, 3}: 64,
+		{8, 4}: 128, {9, 4}: 255, {10, 4}: 255, {11, 4}: 128,
+		{8, 5}: 128, {9, 5}: 255, {10, 5}: 255, {11, 5}: 128,
+		{8, 6}: 64, {9, 6}: 128, {10, 6}: 128, {11, 6}: 64,
+	}
+	if len(got) != len(want) {
+		t.Errorf("layer 0 = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("pixel %v = %v, want %v", k, got[k], v)
+		}
+	}
+}
+
+func TestWriteZip(t *testing.T) {
+	tests := []struct {
+		layout Layout
+		want   []string
+		config string
+	}{
+		{SL1, []string{"job00000.png", "job00001.png", "config.ini"}, "numFast = 2"},
+		{ChiTu, []string{"1.png", "2.png", "run.gcode"}, ";totalLayer:2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.layout), func(t *testing.T) {
+			s, err := New(testProfile(tt.layout), []*binvox.BinVOX{cube(2)})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := s.WriteZip(&buf, "job"); err != nil {
+				t.Fatalf("WriteZip: %v", err)
+			}
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatalf("zip.NewReader: %v", err)
+			}
+			if len(zr.File) != len(tt.want) {
+				t.Fatalf("zip has %v files, want %v", len(zr.File), len(tt.want))
+			}
+			for i, f := range zr.File {
+				if f.Name != tt.want[i] {
+					t.Errorf("file #%v = %q, want %q", i, f.Name, tt.want[i])
+				}
+			}
+			rc, err := zr.File[len(zr.File)-1].Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, err := ioutil.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(config), tt.config) {
+				t.Errorf("config = %s, want it to contain %q", config, tt.config)
+			}
+		})
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	for _, name := range ProfileNames() {
+		p, err := LoadProfile(name)
+		if err != nil {
+			t.Errorf("LoadProfile(%q): %v", name, err)
+			continue
+		}
+		if err := p.Validate(); err != nil {
+			t.Errorf("profile %q: %v", name, err)
+		}
+	}
+	if _, err := LoadProfile("no-such-printer"); err == nil {
+		t.Error("LoadProfile(no-such-printer) = nil error, want error")
+	}
+}
+
+func TestWriteVector(t *testing.T) {
+	square := func(z float64, hole bool) *binvox.Contour {
+		c := &binvox.Contour{Hole: hole, Points: []gl.Vector{gl.V(0, 0, z), gl.V(2, 0, z), gl.V(2, 2, z), gl.V(0, 2, z)}}
+		if hole {
+			c.Points = []gl.Vector{gl.V(0.5, 0.5, z), gl.V(0.5, 1.5, z), gl.V(1.5, 1.5, z), gl.V(1.5, 0.5, z)}
+		}
+		return c
+	}
+	layers := []*binvox.Layer{
+		{Z: 0.25, Contours: []*binvox.Contour{square(0.25, false), square(0.25, true)}},
+		{Z: 0.75, Contours: []*binvox.Contour{square(0.75, false)}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCLI(&buf, layers); err != nil {
+		t.Fatalf("WriteCLI: %v", err)
+	}
+	for _, want := range []string{
+		"$$DIMENSION/0,0,0.25,2,2,0.75\n",
+		"$$LAYERS/2\n",
+		"$$LAYER/0.25\n$$POLYLINE/1,1,5,0,0,2,0,2,2,0,2,0,0\n$$POLYLINE/1,0,5,0.5,0.5,0.5,1.5,1.5,1.5,1.5,0.5,0.5,0.5\n$$LAYER/0.75\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteCLI = %s, want it to contain %q", buf.String(), want)
+		}
+	}
+
+	buf.Reset()
+	if err := WriteSVG(&buf, layers); err != nil {
+		t.Fatalf("WriteSVG: %v", err)
+	}
+	for _, want := range []string{
+		`viewBox="0 -2 2 2"`,
+		`<g id="layer-1" data-z="0.75">`,
+		`d="M0,0 L2,0 L2,-2 L0,-2 Z M0.5,-0.5 L0.5,-1.5 L1.5,-1.5 L1.5,-0.5 Z"`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteSVG = %s, want it to contain %q", buf.String(), want)
+		}
+	}
+}
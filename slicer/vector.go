@@ -0,0 +1,101 @@
+package slicer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+// contourBounds returns the XY bounds of all the contours and the Z
+// bounds of the layers.
+func contourBounds(layers []*binvox.Layer) gl.Box {
+	box := gl.Box{Min: gl.V(math.Inf(1), math.Inf(1), math.Inf(1)), Max: gl.V(math.Inf(-1), math.Inf(-1), math.Inf(-1))}
+	for _, layer := range layers {
+		for _, c := range layer.Contours {
+			for _, p := range c.Points {
+				box.Min = box.Min.Min(p)
+				box.Max = box.Max.Max(p)
+			}
+		}
+	}
+	if math.IsInf(box.Min.X, 1) {
+		return gl.Box{}
+	}
+	return box
+}
+
+// WriteSVG writes the contours of the layers as an SVG file with one group
+// (with an id of "layer-N") per layer. Units are millimeters and Y is
+// flipped so that the layers appear as viewed from +Z. Holes are oriented
+// opposite to the outer boundaries, so the nonzero fill rule renders them
+// correctly.
+func WriteSVG(w io.Writer, layers []*binvox.Layer) error {
+	bw := bufio.NewWriter(w)
+	box := contourBounds(layers)
+	size := box.Size()
+	fmt.Fprintf(bw, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%gmm\" height=\"%gmm\" viewBox=\"%g %g %g %g\">\n",
+		size.X, size.Y, box.Min.X, -box.Max.Y, size.X, size.Y)
+	for i, layer := range layers {
+		fmt.Fprintf(bw, "<g id=\"layer-%v\" data-z=\"%g\">\n", i, layer.Z)
+		if len(layer.Contours) > 0 {
+			var path []string
+			for _, c := range layer.Contours {
+				var sb strings.Builder
+				for j, p := range c.Points {
+					cmd := "L"
+					if j == 0 {
+						cmd = "M"
+					}
+					fmt.Fprintf(&sb, "%v%g,%g ", cmd, p.X, 0-p.Y) // 0-y avoids writing -0
+				}
+				sb.WriteString("Z")
+				path = append(path, sb.String())
+			}
+			fmt.Fprintf(bw, "<path fill-rule=\"nonzero\" d=\"%v\"/>\n", strings.Join(path, " "))
+		}
+		fmt.Fprintf(bw, "</g>\n")
+	}
+	fmt.Fprintf(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+// WriteCLI writes the contours of the layers in the ASCII Common Layer
+// Interface (CLI) format used by laser cutters and SLS machines. Outer
+// boundaries are written counterclockwise (direction 1) and holes are
+// written clockwise (direction 0), with units of millimeters.
+func WriteCLI(w io.Writer, layers []*binvox.Layer) error {
+	bw := bufio.NewWriter(w)
+	box := contourBounds(layers)
+	fmt.Fprintf(bw, "$$HEADERSTART\n")
+	fmt.Fprintf(bw, "$$ASCII\n")
+	fmt.Fprintf(bw, "$$UNITS/1.0\n")
+	fmt.Fprintf(bw, "$$VERSION/200\n")
+	fmt.Fprintf(bw, "$$LABEL/1,part1\n")
+	fmt.Fprintf(bw, "$$DIMENSION/%g,%g,%g,%g,%g,%g\n", box.Min.X, box.Min.Y, box.Min.Z, box.Max.X, box.Max.Y, box.Max.Z)
+	fmt.Fprintf(bw, "$$LAYERS/%v\n", len(layers))
+	fmt.Fprintf(bw, "$$HEADEREND\n")
+	fmt.Fprintf(bw, "$$GEOMETRYSTART\n")
+	for _, layer := range layers {
+		fmt.Fprintf(bw, "$$LAYER/%g\n", layer.Z)
+		for _, c := range layer.Contours {
+			dir := 1
+			if c.Hole {
+				dir = 0
+			}
+			// Closed polylines repeat the first point.
+			fmt.Fprintf(bw, "$$POLYLINE/1,%v,%v", dir, len(c.Points)+1)
+			for _, p := range c.Points {
+				fmt.Fprintf(bw, ",%g,%g", p.X, p.Y)
+			}
+			fmt.Fprintf(bw, ",%g,%g\n", c.Points[0].X, c.Points[0].Y)
+		}
+	}
+	fmt.Fprintf(bw, "$$GEOMETRYEND\n")
+	return bw.Flush()
+}
@@ -0,0 +1,164 @@
+package slicer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image/png"
+	"io"
+	"log"
+	"os"
+)
+
+// WriteFile writes the layer images (and printer configuration) for the
+// model to the named archive. See WriteZip.
+func (s *Slicer) WriteFile(filename, job string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("unable to create file %q: %v", filename, err)
+	}
+	if err := s.WriteZip(f, job); err != nil {
+		f.Close()
+		return fmt.Errorf("WriteFile(%q): %v", filename, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close %q: %v", filename, err)
+	}
+	log.Printf("Done writing %v layers to file %q.", s.NumLayers(), filename)
+	return nil
+}
+
+// WriteZip writes a zip archive containing the layer images (as PNG
+// files) and the printer configuration in the layout given by the
+// profile. job is the name of the print job.
+func (s *Slicer) WriteZip(w io.Writer, job string) error {
+	p := s.Profile
+	zw := zip.NewWriter(w)
+	n := s.NumLayers()
+	var volume float64 // in cubic millimeters
+	for i := 0; i < n; i++ {
+		img := s.Layer(i)
+		var sum int
+		for _, v := range img.Pix {
+			sum += int(v)
+		}
+		volume += float64(sum) / 255 * p.PixelPitch * p.PixelPitch * p.LayerHeight
+
+		name := fmt.Sprintf("%v%05d.png", job, i)
+		if p.Layout == ChiTu {
+			name = fmt.Sprintf("%v.png", i+1)
+		}
+		f, err := zw.Create(name)
+		if err != nil {
+			return fmt.Errorf("unable to create %q: %v", name, err)
+		}
+		if err := png.Encode(f, img); err != nil {
+			return fmt.Errorf("png.Encode(%q): %v", name, err)
+		}
+		if (i+1)%100 == 0 {
+			log.Printf("Wrote %v of %v layers...", i+1, n)
+		}
+	}
+
+	var name string
+	var config bytes.Buffer
+	switch p.Layout {
+	case SL1:
+		name = "config.ini"
+		s.writeSL1Config(&config, job, volume)
+	case ChiTu:
+		name = "run.gcode"
+		s.writeChiTuGCode(&config, job, volume)
+	default:
+		return fmt.Errorf("unknown layout %q", p.Layout)
+	}
+	f, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("unable to create %q: %v", name, err)
+	}
+	if _, err := f.Write(config.Bytes()); err != nil {
+		return fmt.Errorf("unable to write %q: %v", name, err)
+	}
+	return zw.Close()
+}
+
+// writeSL1Config writes the config.ini file of the SL1 layout.
+func (s *Slicer) writeSL1Config(w io.Writer, job string, volume float64) {
+	p := s.Profile
+	n := s.NumLayers()
+	fmt.Fprintf(w, "action = print\n")
+	fmt.Fprintf(w, "jobDir = %v\n", job)
+	fmt.Fprintf(w, "expTime = %v\n", p.ExposureTime)
+	fmt.Fprintf(w, "expTimeFirst = %v\n", p.BottomExposureTime)
+	fmt.Fprintf(w, "numFade = %v\n", p.BottomLayers)
+	fmt.Fprintf(w, "layerHeight = %v\n", p.LayerHeight)
+	fmt.Fprintf(w, "numFast = %v\n", n)
+	fmt.Fprintf(w, "numSlow = 0\n")
+	fmt.Fprintf(w, "printerModel = SL1\n")
+	fmt.Fprintf(w, "printerProfile = %v\n", p.Name)
+	fmt.Fprintf(w, "printTime = %.0f\n", s.printTime())
+	fmt.Fprintf(w, "usedMaterial = %.3f\n", volume/1000)
+}
+
+// writeChiTuGCode writes the run.gcode file of the ChiTu layout.
+func (s *Slicer) writeChiTuGCode(w io.Writer, job string, volume float64) {
+	p := s.Profile
+	n := s.NumLayers()
+	projectType := "normal"
+	if p.MirrorX || p.MirrorY {
+		projectType = "mirror_LCD"
+	}
+	fmt.Fprintf(w, ";fileName:%v\n", job)
+	fmt.Fprintf(w, ";machineType:%v\n", p.Name)
+	fmt.Fprintf(w, ";estimatedPrintTime:%.0f\n", s.printTime())
+	fmt.Fprintf(w, ";volume:%.3f\n", volume/1000)
+	fmt.Fprintf(w, ";layerHeight:%v\n", p.LayerHeight)
+	fmt.Fprintf(w, ";resolutionX:%v\n", p.ResolutionX)
+	fmt.Fprintf(w, ";resolutionY:%v\n", p.ResolutionY)
+	fmt.Fprintf(w, ";machineX:%.2f\n", float64(p.ResolutionX)*p.PixelPitch)
+	fmt.Fprintf(w, ";machineY:%.2f\n", float64(p.ResolutionY)*p.PixelPitch)
+	fmt.Fprintf(w, ";projectType:%v\n", projectType)
+	fmt.Fprintf(w, ";normalExposureTime:%v\n", p.ExposureTime)
+	fmt.Fprintf(w, ";bottomLayExposureTime:%v\n", p.BottomExposureTime)
+	fmt.Fprintf(w, ";bottomLayerCount:%v\n", p.BottomLayers)
+	fmt.Fprintf(w, ";totalLayer:%v\n", n)
+	fmt.Fprintf(w, ";END_OF_HEADER\n")
+	fmt.Fprintf(w, "G21;\nG90;\nM106 S0;\nG28 Z0;\n")
+	for i := 0; i < n; i++ {
+		z := float64(i+1) * p.LayerHeight
+		fmt.Fprintf(w, "\n;LAYER_START:%v\n", i)
+		fmt.Fprintf(w, ";currPos:%.3f\n", z)
+		fmt.Fprintf(w, "M6054 \"%v.png\";show Image\n", i+1)
+		if p.LiftHeight > 0 && i > 0 {
+			fmt.Fprintf(w, "G0 Z%.3f F%v;\n", z+p.LiftHeight, p.LiftSpeed)
+		}
+		fmt.Fprintf(w, "G0 Z%.3f F%v;\n", z, p.RetractSpeed)
+		fmt.Fprintf(w, "G4 P0;\n")
+		fmt.Fprintf(w, "M106 S255;light on\n")
+		fmt.Fprintf(w, "G4 P%.0f;\n", 1000*s.exposure(i))
+		fmt.Fprintf(w, "M106 S0;light off\n")
+		fmt.Fprintf(w, ";LAYER_END\n")
+	}
+	fmt.Fprintf(w, "\nG0 Z%.3f F%v;\nM18;\n", float64(n)*p.LayerHeight+p.LiftHeight, p.LiftSpeed)
+}
+
+// exposure returns the exposure time (in seconds) of layer i.
+func (s *Slicer) exposure(i int) float64 {
+	if i < s.Profile.BottomLayers {
+		return s.Profile.BottomExposureTime
+	}
+	return s.Profile.ExposureTime
+}
+
+// printTime estimates the total print time in seconds.
+func (s *Slicer) printTime() float64 {
+	p := s.Profile
+	var t float64
+	for i := 0; i < s.NumLayers(); i++ {
+		t += s.exposure(i)
+		if p.LiftSpeed > 0 && p.RetractSpeed > 0 {
+			t += 60 * p.LiftHeight * (1/p.LiftSpeed + 1/p.RetractSpeed)
+		}
+	}
+	return t
+}
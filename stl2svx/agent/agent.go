@@ -8,15 +8,16 @@ import (
 	"image/draw"
 	"image/png"
 	"log"
+	"runtime"
 	"sync"
 	"time"
 
-	gl "github.com/fogleman/fauxgl"
 	"github.com/gmlewis/stldice/v4/binvox"
 	pb "github.com/gmlewis/stldice/v4/stl2svx/proto"
-	"github.com/gmlewis/stldice/v4/stl2svx/stl"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -25,11 +26,21 @@ const (
 )
 
 // Agent implements the pb.AgentServer interface.
-type agent struct{}
+type agent struct {
+	address  string
+	conn     *grpc.ClientConn // connection to the master
+	master   pb.MasterClient
+	capacity *pb.Capacity
+	slots    chan struct{} // limits the number of concurrent slices
+	meshes   *meshCache    // STL files uploaded with PutMesh
+	jobs     *jobCache     // jobs prepared for slicing
+}
 
 // New creates and returns a new agent after registering itself
-// with the master.
-func New(ctx context.Context, address, masterAddress string) (*agent, error) {
+// with the master. maxSlices is the number of slices the agent runs
+// concurrently (the number of CPUs if 0). The agent then sends heartbeats
+// to the master until ctx is done.
+func New(ctx context.Context, address, masterAddress string, maxSlices int) (*agent, error) {
 	// Register with the master.
 	var conn *grpc.ClientConn
 	for {
@@ -42,104 +53,132 @@ func New(ctx context.Context, address, masterAddress string) (*agent, error) {
 		}
 		break
 	}
-	defer conn.Close()
-	c := pb.NewMasterClient(conn)
-	req := &pb.RegisterAgentRequest{Address: address}
-	_, err := c.RegisterAgent(ctx, req)
+	if maxSlices <= 0 {
+		maxSlices = runtime.NumCPU()
+	}
+	a := &agent{
+		address:  address,
+		conn:     conn,
+		master:   pb.NewMasterClient(conn),
+		capacity: &pb.Capacity{NumCpu: int32(runtime.NumCPU()), MemoryBytes: memoryBytes(), MaxSlices: int32(maxSlices)},
+		slots:    make(chan struct{}, maxSlices),
+		meshes:   newMeshCache(meshCacheBytes),
+		jobs:     newJobCache(),
+	}
+	interval, err := a.register(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("unable to register with master: %v", err)
+		conn.Close()
+		return nil, err
 	}
 
 	log.Printf("Successfully registered agent %v with master %v", address, masterAddress)
-	return &agent{}, nil
+	go a.heartbeats(ctx, interval)
+	return a, nil
 }
 
-func (m *agent) SliceJob(ctx context.Context, in *pb.SliceJobRequest) (resp *pb.SliceJobResponse, imErr error) {
-	log.Printf("Agent processing slice z=%v...", in.GetZ())
-
-	ch, err := generateMR(in)
+// SliceJob slices the job at a single Z height.
+func (m *agent) SliceJob(ctx context.Context, in *pb.SliceJobRequest) (*pb.SliceJobResponse, error) {
+	var resp *pb.SliceJobResponse
+	err := m.sliceRange(ctx, in, 1, func(r *pb.SliceJobResponse) error {
+		resp = r
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return resp, nil
+}
 
-	vxCh := make(chan voxelInfo, 1000)
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		resp, imErr = imager(in, vxCh)
-		wg.Done()
-	}()
+// SliceRange slices the job at a range of Z heights and streams back one
+// image per slice.
+func (m *agent) SliceRange(in *pb.SliceJobRequest, stream pb.Agent_SliceRangeServer) error {
+	n := int(in.GetZCount())
+	if n <= 0 {
+		n = 1
+	}
+	return m.sliceRange(stream.Context(), in, n, stream.Send)
+}
 
-	for bv := range ch {
-		voxelize(bv, int(in.GetZ()), vxCh)
+// sliceRange slices the job at the n Z heights starting at in.Z and sends
+// each image in order.
+func (m *agent) sliceRange(ctx context.Context, in *pb.SliceJobRequest, n int, send func(*pb.SliceJobResponse) error) error {
+	select {
+	case m.slots <- struct{}{}:
+		defer func() { <-m.slots }()
+	default:
+		return status.Errorf(codes.ResourceExhausted, "agent %v is already running %v slices", m.address, cap(m.slots))
 	}
-	close(vxCh)
-	wg.Wait()
-	if imErr != nil {
-		log.Printf("imager: %v", imErr)
-		return nil, imErr
+	p, err := m.prepareJob(in)
+	if err != nil {
+		return err
 	}
-	log.Printf("Agent done for z=%v.", in.GetZ())
 
-	return resp, nil
+	if z := int(in.GetZ()); z < 0 || z+n > p.dimZ() {
+		return status.Errorf(codes.InvalidArgument, "slices z=%v-%v are outside the model (%v slices)", z, z+n-1, p.dimZ())
+	}
+	for z := int(in.GetZ()); z < int(in.GetZ())+n; z++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		log.Printf("Agent processing slice z=%v...", z)
+		resp, err := p.slice(z)
+		if err != nil {
+			return err
+		}
+		resp.Z = int64(z)
+		if err := send(resp); err != nil {
+			return err
+		}
+		log.Printf("Agent done for z=%v.", z)
+	}
+	return nil
 }
 
 type bvInfo struct {
 	dx, dy, dz int
 	bv         *binvox.BinVOX
 	base       bool
-	mesh       *gl.Mesh
+	index      *meshIndex
 }
 
-// generateMR controls how the MapReduce will run by defining the jobs to
-// be performed by each mapper. It also sends all the data each mapper needs
-// through the provided channel.
-// dimZ is the number of agents needed to output the images.
-func generateMR(in *pb.SliceJobRequest) (ch chan *bvInfo, err error) {
-	ch = make(chan *bvInfo)
+// slice creates the image of the job at the given z height.
+func (p *preparedJob) slice(z int) (*pb.SliceJobResponse, error) {
+	vxCh := make(chan voxelInfo, 1000)
+	var (
+		wg    sync.WaitGroup
+		resp  *pb.SliceJobResponse
+		imErr error
+	)
+	wg.Add(1)
 	go func() {
-		job := in.GetNewJobRequest()
-		var base *stl.STL
-		for i, stlFile := range job.GetStlFiles() {
-			stlMesh, err := stl.New(stlFile, job.GetDim(), job.GetNX(), job.GetNY(), job.GetNZ())
-			if err != nil {
-				log.Fatalf("stl.New: %v", err)
-			}
-			if i == 0 {
-				base = stlMesh
-			}
-			log.Printf("generateMR: i=%v, %v triangles", i, len(stlMesh.Mesh.Triangles))
-
-			// Now dice it up.
-			for zi := 0; zi < int(job.GetNZ()); zi++ {
-				z1 := base.MBB.Min.Z + float64(zi*base.DimZ)*base.MMPV
-				for yi := 0; yi < int(job.GetNY()); yi++ {
-					y1 := base.MBB.Min.Y + float64(yi*base.DimY)*base.MMPV
-					for xi := 0; xi < int(job.GetNX()); xi++ {
-						x1 := base.MBB.Min.X + float64(xi*base.DimX)*base.MMPV
-
-						mapIn := &bvInfo{
-							dx: xi * base.DimX,
-							dy: yi * base.DimY,
-							dz: zi * base.DimZ,
-							bv: &binvox.BinVOX{
-								NX: base.DimX, NY: base.DimY, NZ: base.DimZ,
-								TX: x1, TY: y1, TZ: z1,
-								Scale: base.SubregionScale,
-							},
-							base: i == 0,
-							mesh: stlMesh.Mesh,
-						}
-
-						log.Printf("generateMR: sending STL #%v to mapper", i)
-						ch <- mapIn
-					}
-				}
-			}
-		}
-		close(ch)
+		resp, imErr = imager(p.job, z, vxCh)
+		wg.Done()
 	}()
-	return ch, nil
+
+	var err error
+	for _, region := range p.regions {
+		// Each slice voxelizes its own copy of the region.
+		bvi := *region
+		bv := *region.bv
+		bvi.bv = &bv
+		if err = voxelize(&bvi, z, vxCh); err != nil {
+			break
+		}
+	}
+	close(vxCh)
+	wg.Wait()
+	if err != nil {
+		log.Printf("slice(%v): %v", z, err)
+		if status.Code(err) == codes.InvalidArgument {
+			return nil, err // retrying the job will not help
+		}
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	if imErr != nil {
+		log.Printf("imager: %v", imErr)
+		return nil, status.Errorf(codes.Internal, "%v", imErr)
+	}
+	return resp, nil
 }
 
 type voxelInfo struct {
@@ -149,11 +188,12 @@ type voxelInfo struct {
 
 // voxelize takes an STL file and dices it into voxels
 // then sends them to the imager.
-func voxelize(bvi *bvInfo, zi int, ch chan<- voxelInfo) {
+func voxelize(bvi *bvInfo, zi int, ch chan<- voxelInfo) error {
 	bv := bvi.bv
-	if err := bv.VoxelizeZ(bvi.mesh, zi); err != nil {
-		log.Printf("voxelize(%v): %v", zi, err)
-		return
+	// Only the triangles near the Z plane of the slice can cross it.
+	mesh := bvi.index.at(bv.TZ + (0.5+float64(zi))/bv.VoxelsPerMM())
+	if err := bv.VoxelizeZ(mesh, zi); err != nil {
+		return fmt.Errorf("voxelize(%v): %v", zi, err)
 	}
 
 	vType := "cut"
@@ -162,24 +202,30 @@ func voxelize(bvi *bvInfo, zi int, ch chan<- voxelInfo) {
 	}
 	log.Printf("voxelize(%v): sending %v %v voxels to imager(%v)...", zi, len(bv.WhiteVoxels), vType, zi)
 
-	keyFunc := func(k binvox.Key) {
+	keyFunc := func(k binvox.Key) error {
 		k.X += bvi.dx
 		k.Y += bvi.dy
 		k.Z += bvi.dz
 		if zi != k.Z {
-			log.Fatalf("voxelize(%v): k{%v,%v,%v} does not match zi=%v", zi, k.X, k.Y, k.Z, zi)
+			return status.Errorf(codes.InvalidArgument, "voxelize(%v): k{%v,%v,%v} does not match zi=%v", zi, k.X, k.Y, k.Z, zi)
 		}
 		if k.X < 0 || k.Y < 0 || k.Z < 0 {
-			return // common for a cut to extend beyond the bounds of the base.
+			return nil // common for a cut to extend beyond the bounds of the base.
 		}
 		ch <- voxelInfo{X: k.X, Y: k.Y, Base: bvi.base}
+		return nil
 	}
 	for k := range bv.WhiteVoxels {
-		keyFunc(k)
+		if err := keyFunc(k); err != nil {
+			return err
+		}
 	}
 	for k := range bv.ColorVoxels {
-		keyFunc(k)
+		if err := keyFunc(k); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 type pixelKey struct {
@@ -189,9 +235,7 @@ type pixelKey struct {
 // imager takes the voxels from voxelize and creates
 // a 2D image at the provided z height.
 // It outputs an image to disk.
-func imager(in *pb.SliceJobRequest, ch <-chan voxelInfo) (*pb.SliceJobResponse, error) {
-	job := in.GetNewJobRequest()
-	z := in.GetZ()
+func imager(job *pb.NewJobRequest, z int, ch <-chan voxelInfo) (*pb.SliceJobResponse, error) {
 	log.Printf("imager: z=%v", z)
 	pixels := make(map[pixelKey]bool)
 	for value := range ch {
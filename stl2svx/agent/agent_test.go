@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"bytes"
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+	pb "github.com/gmlewis/stldice/v4/stl2svx/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sphereJob returns a job for a sphere with a box cut out of it.
+func sphereJob() *pb.NewJobRequest {
+	toSTL := func(mesh *gl.Mesh) *pb.STLFile {
+		stlFile := &pb.STLFile{}
+		for _, t := range mesh.Triangles {
+			stlFile.Triangles = append(stlFile.Triangles, &pb.Triangle{
+				V1: &pb.Vertex{X: t.V1.Position.X, Y: t.V1.Position.Y, Z: t.V1.Position.Z},
+				V2: &pb.Vertex{X: t.V2.Position.X, Y: t.V2.Position.Y, Z: t.V2.Position.Z},
+				V3: &pb.Vertex{X: t.V3.Position.X, Y: t.V3.Position.Y, Z: t.V3.Position.Z},
+			})
+		}
+		return stlFile
+	}
+	sphere := gl.NewSphere(3)
+	cut := gl.NewCube()
+	cut.Transform(gl.Scale(gl.V(1, 1, 3)).Translate(gl.V(0.5, 0, 0)))
+	return &pb.NewJobRequest{StlFiles: []*pb.STLFile{toSTL(sphere), toSTL(cut)}, Dim: 32, NX: 2, NY: 2, NZ: 1}
+}
+
+func newTestAgent(maxSlices int) *agent {
+	return &agent{
+		address: "test",
+		slots:   make(chan struct{}, maxSlices),
+		meshes:  newMeshCache(meshCacheBytes),
+		jobs:    newJobCache(),
+	}
+}
+
+// putMeshes caches the job's STL files in the agent and returns their hashes.
+func putMeshes(t *testing.T, m *agent, job *pb.NewJobRequest) []string {
+	t.Helper()
+	var hashes []string
+	for _, stlFile := range job.GetStlFiles() {
+		hash, _, err := pb.MarshalMesh(stlFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.meshes.put(hash, stlFile)
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+// unindex replaces the index of each region with one that has a single
+// bucket holding every triangle.
+func unindex(p *preparedJob) {
+	unindexed := make(map[*meshIndex]*meshIndex)
+	for _, r := range p.regions {
+		if _, ok := unindexed[r.index]; !ok {
+			seen := make(map[*gl.Triangle]bool)
+			var tris []*gl.Triangle
+			for _, b := range r.index.buckets {
+				for _, t := range b {
+					if !seen[t] {
+						seen[t] = true
+						tris = append(tris, t)
+					}
+				}
+			}
+			unindexed[r.index] = newMeshIndex(gl.NewTriangleMesh(tris), 1e9)
+		}
+		r.index = unindexed[r.index]
+	}
+}
+
+func TestSliceRange(t *testing.T) {
+	job := sphereJob()
+	m := newTestAgent(1)
+	hashes := putMeshes(t, m, job)
+	spec := &pb.NewJobRequest{Dim: job.Dim, NX: job.NX, NY: job.NY, NZ: job.NZ}
+
+	want, err := newPreparedJob(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unindex(want)
+
+	const z0, n = 3, 26
+	var got []*pb.SliceJobResponse
+	in := &pb.SliceJobRequest{NewJobRequest: spec, Z: z0, ZCount: n, MeshHashes: hashes}
+	if err := m.sliceRange(context.Background(), in, n, func(r *pb.SliceJobResponse) error { got = append(got, r); return nil }); err != nil {
+		t.Fatalf("sliceRange: %v", err)
+	}
+	if len(got) != n {
+		t.Fatalf("sliceRange sent %v images, want %v", len(got), n)
+	}
+	sizes := make(map[int]bool)
+	for i, r := range got {
+		z := z0 + i
+		if r.GetZ() != int64(z) {
+			t.Errorf("image #%v has z=%v, want %v", i, r.GetZ(), z)
+		}
+		w, err := want.slice(z)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(r.GetPngFile(), w.GetPngFile()) {
+			t.Errorf("image at z=%v differs from the unindexed slice", z)
+		}
+		sizes[len(r.GetPngFile())] = true
+	}
+	if len(sizes) < 2 {
+		t.Error("all images are the same size; the sphere was not sliced")
+	}
+
+	// The prepared job is cached, so the STL files are no longer needed.
+	m.meshes = newMeshCache(meshCacheBytes)
+	in.ZCount = 1
+	if _, err := m.SliceJob(context.Background(), in); err != nil {
+		t.Errorf("SliceJob with cached job: %v", err)
+	}
+	in.NewJobRequest = &pb.NewJobRequest{Dim: job.Dim * 2, NX: job.NX, NY: job.NY, NZ: job.NZ}
+	if _, err := m.SliceJob(context.Background(), in); err == nil {
+		t.Error("SliceJob of new job with missing meshes = nil error, want error")
+	}
+}
+
+func TestMeshIndex(t *testing.T) {
+	mesh := gl.NewSphere(2)
+	x := newMeshIndex(mesh, 0.1)
+	crossing := func(tris []*gl.Triangle, z float64) (n int) {
+		for _, t := range tris {
+			lo := t.V1.Position.Min(t.V2.Position).Min(t.V3.Position).Z
+			hi := t.V1.Position.Max(t.V2.Position).Max(t.V3.Position).Z
+			if lo <= z && z <= hi {
+				n++
+			}
+		}
+		return n
+	}
+	for z := -1.2; z <= 1.2; z += 0.05 {
+		if got, want := crossing(x.at(z).Triangles, z), crossing(mesh.Triangles, z); got != want {
+			t.Errorf("at(%v) has %v triangles crossing z, want %v", z, got, want)
+		}
+	}
+}
+
+func TestMalformedJob(t *testing.T) {
+	m := newTestAgent(1)
+	bad := sphereJob()
+	bad.StlFiles[1].Triangles[3].V2 = nil
+	badHashes := putMeshes(t, m, bad)
+	in := &pb.SliceJobRequest{NewJobRequest: &pb.NewJobRequest{Dim: bad.Dim, NX: bad.NX, NY: bad.NY, NZ: bad.NZ}, Z: 3, MeshHashes: badHashes}
+	if _, err := m.SliceJob(context.Background(), in); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("SliceJob of malformed job = %v, want InvalidArgument", err)
+	}
+
+	// The agent keeps serving other jobs.
+	job := sphereJob()
+	in.MeshHashes = putMeshes(t, m, job)
+	resp, err := m.SliceJob(context.Background(), in)
+	if err != nil {
+		t.Fatalf("SliceJob after malformed job: %v", err)
+	}
+	if len(resp.GetPngFile()) == 0 {
+		t.Error("SliceJob returned no image")
+	}
+
+	in.Z = 1000
+	if _, err := m.SliceJob(context.Background(), in); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("SliceJob outside the model = %v, want InvalidArgument", err)
+	}
+
+	// The regions above the first layer of regions are voxelized at the
+	// wrong height (producing voxels in the wrong slice), which no agent
+	// can fix.
+	layered := sphereJob()
+	layered.NZ = 2
+	in = &pb.SliceJobRequest{NewJobRequest: &pb.NewJobRequest{Dim: layered.Dim, NX: layered.NX, NY: layered.NY, NZ: layered.NZ}, MeshHashes: putMeshes(t, m, layered)}
+	p, err := m.prepareJob(in)
+	if err != nil {
+		t.Fatalf("prepareJob: %v", err)
+	}
+	in.Z = int64(p.regions[0].bv.NZ / 2)
+	if _, err := m.SliceJob(context.Background(), in); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("SliceJob with several layers of regions = %v, want InvalidArgument", err)
+	}
+}
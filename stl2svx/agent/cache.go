@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"container/list"
+	"log"
+	"sync"
+
+	pb "github.com/gmlewis/stldice/v4/stl2svx/proto"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// meshCacheBytes is the default size of the agent's mesh cache.
+const meshCacheBytes = 2 << 30 // 2GB
+
+// meshCache is a least-recently-used cache of STL files by hash.
+type meshCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	size     int
+	lru      *list.List               // of *meshEntry, most recently used first
+	entries  map[string]*list.Element // by hash
+}
+
+type meshEntry struct {
+	hash    string
+	stlFile *pb.STLFile
+	size    int
+}
+
+func newMeshCache(maxBytes int) *meshCache {
+	return &meshCache{maxBytes: maxBytes, lru: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns the STL file with the given hash, or nil if it is not cached.
+func (c *meshCache) get(hash string) *pb.STLFile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[hash]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*meshEntry).stlFile
+}
+
+// put caches the STL file, evicting the least recently used STL files
+// if the cache is full.
+func (c *meshCache) put(hash string, stlFile *pb.STLFile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[hash]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+	size := proto.Size(stlFile)
+	c.entries[hash] = c.lru.PushFront(&meshEntry{hash: hash, stlFile: stlFile, size: size})
+	c.size += size
+	for c.size > c.maxBytes && c.lru.Len() > 1 {
+		e := c.lru.Back()
+		old := c.lru.Remove(e).(*meshEntry)
+		delete(c.entries, old.hash)
+		c.size -= old.size
+	}
+}
+
+// PutMesh receives an STL file and caches it by its hash.
+func (m *agent) PutMesh(stream pb.Agent_PutMeshServer) error {
+	hash, stlFile, err := pb.ReceiveMesh(stream.Recv)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "PutMesh: %v", err)
+	}
+	m.meshes.put(hash, stlFile)
+	log.Printf("Agent cached mesh %v (%v triangles)", hash, len(stlFile.GetTriangles()))
+	return stream.SendAndClose(&pb.PutMeshResponse{})
+}
+
+// resolveMeshes returns the slice request with its job's STL files filled
+// in from the mesh cache. It returns a NOT_FOUND error if any are missing
+// so that the master uploads them again.
+func (m *agent) resolveMeshes(in *pb.SliceJobRequest) (*pb.SliceJobRequest, error) {
+	if len(in.GetMeshHashes()) == 0 {
+		return in, nil
+	}
+	job := proto.Clone(in.GetNewJobRequest()).(*pb.NewJobRequest)
+	job.StlFiles = nil
+	for _, hash := range in.GetMeshHashes() {
+		stlFile := m.meshes.get(hash)
+		if stlFile == nil {
+			return nil, status.Errorf(codes.NotFound, "agent %v does not have mesh %v", m.address, hash)
+		}
+		job.StlFiles = append(job.StlFiles, stlFile)
+	}
+	return &pb.SliceJobRequest{NewJobRequest: job, Z: in.GetZ()}, nil
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	pb "github.com/gmlewis/stldice/v4/stl2svx/proto"
+	"golang.org/x/net/context"
+)
+
+const defaultHeartbeatInterval = 10 * time.Second
+
+// register registers the agent and its capacity with the master and
+// returns how often the master expects a heartbeat.
+func (m *agent) register(ctx context.Context) (time.Duration, error) {
+	resp, err := m.master.RegisterAgent(ctx, &pb.RegisterAgentRequest{Address: m.address, Capacity: m.capacity})
+	if err != nil {
+		return 0, fmt.Errorf("unable to register with master: %v", err)
+	}
+	if ms := resp.GetHeartbeatIntervalMs(); ms > 0 {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	return defaultHeartbeatInterval, nil
+}
+
+// heartbeats sends a heartbeat to the master every interval until ctx is
+// done, registering again if the master has forgotten the agent.
+func (m *agent) heartbeats(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		req := &pb.HeartbeatRequest{Address: m.address, Capacity: m.capacity}
+		resp, err := m.master.Heartbeat(ctx, req)
+		if err != nil {
+			log.Printf("Agent %v heartbeat: %v", m.address, err)
+			continue
+		}
+		if !resp.GetRegistered() {
+			log.Printf("Agent %v is unknown to the master; registering again", m.address)
+			if interval, err = m.register(ctx); err != nil {
+				log.Printf("Agent %v: %v", m.address, err)
+				interval = defaultHeartbeatInterval
+			}
+		}
+	}
+}
+
+// Close deregisters the agent from the master and closes the connection.
+func (m *agent) Close(ctx context.Context) error {
+	defer m.conn.Close()
+	if _, err := m.master.Deregister(ctx, &pb.DeregisterRequest{Address: m.address}); err != nil {
+		return fmt.Errorf("unable to deregister from master: %v", err)
+	}
+	log.Printf("Agent %v deregistered", m.address)
+	return nil
+}
+
+// memoryBytes returns the total memory of the machine, or 0 if unknown.
+func memoryBytes() int64 {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var kb int64
+		if n, _ := fmt.Sscanf(s.Text(), "MemTotal: %d kB", &kb); n == 1 {
+			return kb << 10
+		}
+	}
+	return 0
+}
@@ -0,0 +1,200 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+	pb "github.com/gmlewis/stldice/v4/stl2svx/proto"
+	"github.com/gmlewis/stldice/v4/stl2svx/stl"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxCachedJobs is the number of prepared jobs an agent keeps.
+const maxCachedJobs = 4
+
+// preparedJob is a job whose meshes have been parsed, indexed and diced
+// into regions. It is cached by the agent across slice requests of the
+// same job.
+type preparedJob struct {
+	job      *pb.NewJobRequest
+	regions  []*bvInfo     // templates; each slice voxelizes a copy
+	ready    chan struct{} // closed once the job is prepared (or failed)
+	err      error
+	lastUsed time.Time
+}
+
+// jobCache is a cache of preparedJobs keyed by their mesh hashes and dimensions.
+type jobCache struct {
+	mu   sync.Mutex
+	jobs map[string]*preparedJob
+}
+
+func newJobCache() *jobCache {
+	return &jobCache{jobs: make(map[string]*preparedJob)}
+}
+
+// jobKey returns the cache key of the slice request's job, or "" if the
+// job's STL files were sent in the request instead of by hash.
+func jobKey(in *pb.SliceJobRequest) string {
+	if len(in.GetMeshHashes()) == 0 {
+		return ""
+	}
+	job := in.GetNewJobRequest()
+	return fmt.Sprintf("%v/%v/%v/%v/%v", strings.Join(in.GetMeshHashes(), ","), job.GetDim(), job.GetNX(), job.GetNY(), job.GetNZ())
+}
+
+// prepareJob returns the prepared job of the slice request, preparing it
+// (only once, even for concurrent requests) if it is not cached.
+func (m *agent) prepareJob(in *pb.SliceJobRequest) (*preparedJob, error) {
+	key := jobKey(in)
+	if key == "" {
+		return newPreparedJob(in.GetNewJobRequest())
+	}
+
+	c := m.jobs
+	c.mu.Lock()
+	p, ok := c.jobs[key]
+	if ok {
+		p.lastUsed = time.Now()
+		c.mu.Unlock()
+		<-p.ready
+		return p, p.err
+	}
+	p = &preparedJob{ready: make(chan struct{}), lastUsed: time.Now()}
+	c.jobs[key] = p
+	c.evict()
+	c.mu.Unlock()
+
+	defer close(p.ready)
+	in, err := m.resolveMeshes(in)
+	if err == nil {
+		var prepared *preparedJob
+		if prepared, err = newPreparedJob(in.GetNewJobRequest()); err == nil {
+			p.job, p.regions = prepared.job, prepared.regions
+		}
+	}
+	if err != nil {
+		p.err = err
+		c.mu.Lock()
+		delete(c.jobs, key) // try again next time
+		c.mu.Unlock()
+	}
+	return p, err
+}
+
+// evict removes the least recently used jobs if the cache is full.
+// c.mu must be held.
+func (c *jobCache) evict() {
+	for len(c.jobs) > maxCachedJobs {
+		var oldest string
+		for key, p := range c.jobs {
+			if oldest == "" || p.lastUsed.Before(c.jobs[oldest].lastUsed) {
+				oldest = key
+			}
+		}
+		delete(c.jobs, oldest)
+	}
+}
+
+// newPreparedJob parses and indexes the job's STL files and dices them
+// into the job's regions.
+func newPreparedJob(job *pb.NewJobRequest) (*preparedJob, error) {
+	p := &preparedJob{job: job, ready: make(chan struct{})}
+	close(p.ready)
+	var base *stl.STL
+	for i, stlFile := range job.GetStlFiles() {
+		stlMesh, err := stl.New(stlFile, job.GetDim(), job.GetNX(), job.GetNY(), job.GetNZ())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "stl.New: %v", err)
+		}
+		if i == 0 {
+			base = stlMesh
+		}
+		log.Printf("prepareJob: i=%v, %v triangles", i, len(stlMesh.Mesh.Triangles))
+		index := newMeshIndex(stlMesh.Mesh, base.MMPV)
+
+		// Now dice it up.
+		for zi := 0; zi < int(job.GetNZ()); zi++ {
+			z1 := base.MBB.Min.Z + float64(zi*base.DimZ)*base.MMPV
+			for yi := 0; yi < int(job.GetNY()); yi++ {
+				y1 := base.MBB.Min.Y + float64(yi*base.DimY)*base.MMPV
+				for xi := 0; xi < int(job.GetNX()); xi++ {
+					x1 := base.MBB.Min.X + float64(xi*base.DimX)*base.MMPV
+					p.regions = append(p.regions, &bvInfo{
+						dx: xi * base.DimX,
+						dy: yi * base.DimY,
+						dz: zi * base.DimZ,
+						bv: &binvox.BinVOX{
+							NX: base.DimX, NY: base.DimY, NZ: base.DimZ,
+							TX: x1, TY: y1, TZ: z1,
+							Scale: base.SubregionScale,
+						},
+						base:  i == 0,
+						index: index,
+					})
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+// dimZ returns the number of slices of the job.
+func (p *preparedJob) dimZ() int {
+	if len(p.regions) == 0 {
+		return 0
+	}
+	last := p.regions[len(p.regions)-1]
+	return last.dz + last.bv.NZ
+}
+
+// meshIndex buckets the triangles of a mesh by Z so that slicing the
+// mesh with a Z plane only considers the triangles that can cross it.
+type meshIndex struct {
+	minZ    float64 // bottom of the mesh
+	height  float64 // height of each bucket
+	buckets [][]*gl.Triangle
+}
+
+// newMeshIndex returns the index of the mesh with buckets of the given height.
+func newMeshIndex(mesh *gl.Mesh, height float64) *meshIndex {
+	mbb := mesh.BoundingBox()
+	n := int(math.Floor((mbb.Max.Z-mbb.Min.Z)/height)) + 1
+	x := &meshIndex{minZ: mbb.Min.Z, height: height, buckets: make([][]*gl.Triangle, n)}
+	for _, t := range mesh.Triangles {
+		lo := x.bucket(math.Min(t.V1.Position.Z, math.Min(t.V2.Position.Z, t.V3.Position.Z)))
+		hi := x.bucket(math.Max(t.V1.Position.Z, math.Max(t.V2.Position.Z, t.V3.Position.Z)))
+		for b := lo; b <= hi; b++ {
+			x.buckets[b] = append(x.buckets[b], t)
+		}
+	}
+	return x
+}
+
+// bucket returns the bucket containing z, clamped to the mesh.
+func (x *meshIndex) bucket(z float64) int {
+	b := int(math.Floor((z - x.minZ) / x.height))
+	if b < 0 {
+		return 0
+	}
+	if b >= len(x.buckets) {
+		return len(x.buckets) - 1
+	}
+	return b
+}
+
+// at returns a mesh of the triangles that may cross the Z plane at z.
+func (x *meshIndex) at(z float64) *gl.Mesh {
+	b := int(math.Floor((z - x.minZ) / x.height))
+	if b < 0 || b >= len(x.buckets) {
+		return gl.NewTriangleMesh(nil)
+	}
+	return gl.NewTriangleMesh(x.buckets[b])
+}
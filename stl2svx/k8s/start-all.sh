@@ -1,5 +1,6 @@
 #!/bin/bash -ex
 # -*- compile-command: "./start-all.sh"; -*-
+kubectl create -f 00-master-data.yaml
 kubectl create -f 01-master-controller.yaml
 kubectl create -f 02-master-service.yaml
 sleep 5
@@ -0,0 +1,366 @@
+package master
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	pb "github.com/gmlewis/stldice/v4/stl2svx/proto"
+	"github.com/gmlewis/stldice/v4/stl2svx/stl"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// jobRetention is how long a finished job (and its SVX file) is kept.
+const jobRetention = 24 * time.Hour
+
+// job is a job tracked by the master.
+type job struct {
+	id     string
+	req    *pb.NewJobRequest
+	spec   *pb.NewJobRequest // req without its STL files
+	hashes []string          // hashes of the STL files, for PutMesh
+	base   *stl.STL
+	cancel context.CancelFunc
+	done   chan struct{} // closed when the job finishes
+	dir    string        // where the job is persisted ("" if it is not)
+
+	mu       sync.Mutex // protects the fields below
+	state    pb.JobState
+	slices   []*pb.SliceStatus
+	pngs     map[int][]byte // completed slices (if the job is not persisted)
+	svx      []byte         // the SVX file (if the job is not persisted)
+	err      error
+	canceled bool
+}
+
+// newJobID returns a random job ID.
+func newJobID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("unable to generate job ID: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// newJob returns a running job with all of its slices pending.
+func newJob(id string, in *pb.NewJobRequest, base *stl.STL, dir string) (*job, error) {
+	j := &job{
+		id:     id,
+		req:    in,
+		spec:   proto.Clone(in).(*pb.NewJobRequest),
+		base:   base,
+		done:   make(chan struct{}),
+		dir:    dir,
+		slices: make([]*pb.SliceStatus, base.DimZ),
+		pngs:   make(map[int][]byte),
+	}
+	for z := range j.slices {
+		j.slices[z] = &pb.SliceStatus{Z: int64(z)}
+	}
+	j.spec.StlFiles = nil
+	for _, stlFile := range in.GetStlFiles() {
+		hash, _, err := pb.MarshalMesh(stlFile)
+		if err != nil {
+			return nil, err
+		}
+		j.hashes = append(j.hashes, hash)
+	}
+	return j, nil
+}
+
+// parseJob validates the job and returns its base STL.
+func parseJob(in *pb.NewJobRequest) (*stl.STL, error) {
+	if len(in.GetStlFiles()) == 0 || len(in.GetStlFiles()[0].GetTriangles()) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Must pass at least one STL file to NewJob")
+	}
+	var base *stl.STL
+	for i, stlFile := range in.GetStlFiles() {
+		s, err := stl.New(stlFile, in.GetDim(), in.GetNX(), in.GetNY(), in.GetNZ())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "STL file %v: %v", i, err)
+		}
+		if i == 0 {
+			base = s
+		}
+	}
+	return base, nil
+}
+
+// submitJob validates the job and starts running it in the background.
+// The job keeps running after the caller returns; use cancelJob to stop it.
+func (m *master) submitJob(in *pb.NewJobRequest) (*job, error) {
+	base, err := parseJob(in)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	var dir string
+	if m.dir != "" {
+		dir = filepath.Join(m.dir, id)
+	}
+	j, err := newJob(id, in, base, dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := j.saveRequest(); err != nil {
+		return nil, fmt.Errorf("unable to save job %v: %v", id, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+
+	m.jmu.Lock()
+	m.jobs[id] = j
+	m.jmu.Unlock()
+	log.Printf("Job %v: %v slices", id, base.DimZ)
+
+	go m.runJob(ctx, j)
+	return j, nil
+}
+
+// runJob runs the job's slices and builds its SVX file, which is written
+// straight to the job's directory if the job is persisted.
+func (m *master) runJob(ctx context.Context, j *job) {
+	defer j.cancel()
+	err := m.runSlices(ctx, j)
+	var svx []byte
+	switch {
+	case err != nil:
+	case j.dir != "":
+		write := func(w io.Writer) error { return m.writeSVX(j, w) }
+		if err = createFile(filepath.Join(j.dir, resultFile), write); err != nil {
+			err = fmt.Errorf("unable to save result: %v", err)
+		}
+	default:
+		var buf bytes.Buffer
+		if err = m.writeSVX(j, &buf); err == nil {
+			svx = buf.Bytes()
+		}
+	}
+	j.finish(svx, err)
+	m.retainJob(j.id, time.Now())
+}
+
+// retainJob removes the job once jobRetention has passed since it finished.
+func (m *master) retainJob(id string, finished time.Time) {
+	time.AfterFunc(time.Until(finished.Add(jobRetention)), func() { m.removeJob(id) })
+}
+
+// getJob returns the job with the given ID.
+func (m *master) getJob(id string) (*job, error) {
+	m.jmu.Lock()
+	defer m.jmu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown job %q", id)
+	}
+	return j, nil
+}
+
+// removeJob forgets the job and deletes its persisted state.
+func (m *master) removeJob(id string) {
+	m.jmu.Lock()
+	j, ok := m.jobs[id]
+	delete(m.jobs, id)
+	m.jmu.Unlock()
+	if ok && j.dir != "" {
+		if err := os.RemoveAll(j.dir); err != nil {
+			log.Printf("Job %v: %v", id, err)
+		}
+	}
+}
+
+// cancelJob stops the job if it is still running.
+func (j *job) cancelJob() {
+	j.mu.Lock()
+	if j.state == pb.JobState_JOB_RUNNING {
+		j.canceled = true
+	}
+	j.mu.Unlock()
+	j.cancel()
+}
+
+// pending returns the slices that have not been completed.
+func (j *job) pending() []int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var result []int
+	for z, s := range j.slices {
+		if s.State != pb.SliceState_SLICE_DONE {
+			result = append(result, z)
+		}
+	}
+	return result
+}
+
+// sliceStarted records that slice z was assigned to the agent.
+func (j *job) sliceStarted(z int, address string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.slices[z].State = pb.SliceState_SLICE_RUNNING
+	j.slices[z].Agent = address
+}
+
+// sliceFailed records a failed attempt of slice z.
+func (j *job) sliceFailed(z int, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.slices[z].State = pb.SliceState_SLICE_PENDING
+	j.slices[z].Attempts++
+	j.slices[z].Error = err.Error()
+}
+
+// sliceDone records (and persists) the PNG of the completed slice z.
+func (j *job) sliceDone(z int, pngFile []byte) error {
+	if j.dir != "" {
+		if err := writeFile(filepath.Join(j.dir, fmt.Sprintf(sliceFile, z)), pngFile); err != nil {
+			return err
+		}
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.slices[z].State = pb.SliceState_SLICE_DONE
+	if j.dir == "" {
+		j.pngs[z] = pngFile
+	}
+	return nil
+}
+
+// slicePNG returns the PNG of the completed slice z.
+func (j *job) slicePNG(z int) ([]byte, error) {
+	if j.dir != "" {
+		return ioutil.ReadFile(filepath.Join(j.dir, fmt.Sprintf(sliceFile, z)))
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.pngs[z], nil
+}
+
+// finish records (and persists) the outcome of the job, along with its
+// SVX file if the job is not persisted. The outcome is persisted before
+// it is published, so a finished job's result is always available. A job
+// whose result cannot be persisted fails.
+func (j *job) finish(svx []byte, err error) {
+	defer close(j.done)
+	j.mu.Lock()
+	state := pb.JobState_JOB_DONE
+	switch {
+	case j.canceled:
+		state = pb.JobState_JOB_CANCELED
+		err = fmt.Errorf("job %v canceled", j.id)
+	case err != nil:
+		state = pb.JobState_JOB_FAILED
+		err = fmt.Errorf("job %v failed: %v", j.id, err)
+	}
+	j.mu.Unlock()
+	if state != pb.JobState_JOB_DONE {
+		svx = nil
+	}
+
+	s := j.status()
+	s.State = state
+	if err != nil {
+		s.Error = err.Error()
+	}
+	if serr := j.saveResult(s); serr != nil {
+		log.Printf("Job %v: unable to save result: %v", j.id, serr)
+		if state == pb.JobState_JOB_DONE {
+			state, svx = pb.JobState_JOB_FAILED, nil
+			err = fmt.Errorf("job %v failed: unable to save result: %v", j.id, serr)
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state, j.err = state, err
+	if j.dir == "" {
+		j.svx = svx
+	}
+	j.pngs = nil
+	log.Printf("Job %v: %v", j.id, j.state)
+}
+
+// openResult opens the SVX file of a finished job and returns its size.
+// The caller must close it.
+func (j *job) openResult() (io.ReadCloser, int64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch j.state {
+	case pb.JobState_JOB_RUNNING:
+		return nil, 0, status.Errorf(codes.FailedPrecondition, "job %v is still running", j.id)
+	case pb.JobState_JOB_DONE:
+		if j.dir == "" {
+			return ioutil.NopCloser(bytes.NewReader(j.svx)), int64(len(j.svx)), nil
+		}
+		f, err := os.Open(filepath.Join(j.dir, resultFile))
+		if err != nil {
+			return nil, 0, err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, fi.Size(), nil
+	case pb.JobState_JOB_CANCELED:
+		return nil, 0, status.Errorf(codes.Canceled, "%v", j.err)
+	}
+	return nil, 0, status.Errorf(codes.Aborted, "%v", j.err)
+}
+
+// result returns the SVX file of a finished job.
+func (j *job) result() ([]byte, error) {
+	r, _, err := j.openResult()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// sendResult streams the SVX file of a finished job in chunks without
+// reading all of it into memory.
+func (j *job) sendResult(send func(*pb.Chunk) error) error {
+	r, size, err := j.openResult()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return pb.SendChunksFrom(r, size, send)
+}
+
+// status returns the progress of the job.
+func (j *job) status() *pb.JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	in := j.req
+	s := &pb.JobStatus{
+		JobId:       j.id,
+		State:       j.state,
+		TotalSlices: int64(len(j.slices)),
+		SvxName:     fmt.Sprintf("%v-%v-%v-%v-%v.svx", in.GetOutPrefix(), in.GetDim(), in.GetNX(), in.GetNY(), in.GetNZ()),
+	}
+	if j.err != nil {
+		s.Error = j.err.Error()
+	}
+	for _, slice := range j.slices {
+		if slice.State == pb.SliceState_SLICE_DONE {
+			s.CompletedSlices++
+		}
+		s.Slices = append(s.Slices, proto.Clone(slice).(*pb.SliceStatus))
+	}
+	return s
+}
@@ -3,149 +3,222 @@ package master
 
 import (
 	"archive/zip"
-	"bytes"
 	"fmt"
 	"image"
 	"image/draw"
 	"image/png"
+	"io"
 	"log"
 	"sync"
 	"time"
 
-	"google.golang.org/grpc"
+	"github.com/golang/protobuf/proto"
 
 	pb "github.com/gmlewis/stldice/v4/stl2svx/proto"
 	"github.com/gmlewis/stldice/v4/stl2svx/stl"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	idleWaitTime = 100 * time.Millisecond
-	maxSize      = 1 << 30 // 1GB
+	maxSize = 1 << 30 // 1GB
 )
 
 // Master implements the pb.MasterServer interface.
 type master struct {
-	amu        sync.Mutex          // protects agents and idleAgents
-	idleAgents map[string]struct{} // just a map of idle agents
-
-	zmu     sync.Mutex  // protects zipFile
-	zipFile *zip.Writer // resulting SVX file
+	amu           sync.Mutex            // protects agents and agentsChanged
+	agents        map[string]*agentInfo // registered agents by address
+	agentsChanged chan struct{}         // closed when an agent is added or released
+
+	dial             func(address string) (pb.AgentClient, io.Closer, error)
+	maxRetries       int           // retries per slice before the job fails
+	retryBackoff     time.Duration // delay before the first retry of a slice
+	sliceTimeout     time.Duration // deadline per slice of a SliceRange call
+	sliceBatch       int           // slices per SliceRange request
+	maxAgentFailures int           // consecutive failures before an agent is unhealthy
+	agentProbation   time.Duration // how long an unhealthy agent is skipped
+	agentTimeout     time.Duration // silence before an agent is evicted
+
+	jmu  sync.Mutex      // protects jobs
+	jobs map[string]*job // jobs by ID
+	dir  string          // where jobs are persisted ("" to keep them in memory)
 }
 
-func New() *master {
-	return &master{idleAgents: make(map[string]struct{})}
+// New returns a new master that persists its jobs in dir (or only keeps
+// them in memory if dir is ""). Unfinished jobs found in dir are resumed.
+func New(dir string) (*master, error) {
+	m := &master{
+		agents:           make(map[string]*agentInfo),
+		agentsChanged:    make(chan struct{}),
+		dial:             dialAgent,
+		maxRetries:       defaultMaxRetries,
+		retryBackoff:     defaultRetryBackoff,
+		sliceTimeout:     defaultSliceTimeout,
+		sliceBatch:       defaultSliceBatch,
+		maxAgentFailures: defaultMaxAgentFailures,
+		agentProbation:   defaultAgentProbation,
+		agentTimeout:     defaultAgentTimeout,
+		jobs:             make(map[string]*job),
+		dir:              dir,
+	}
+	if err := m.loadJobs(); err != nil {
+		return nil, fmt.Errorf("unable to load jobs from %v: %v", dir, err)
+	}
+	return m, nil
 }
 
 func (m *master) NewJob(ctx context.Context, in *pb.NewJobRequest) (*pb.NewJobResponse, error) {
-	if len(in.GetStlFiles()) == 0 || len(in.GetStlFiles()[0].GetTriangles()) == 0 {
-		return nil, fmt.Errorf("Must pass at least one STL file to NewJob")
-	}
-	base, err := stl.New(in.GetStlFiles()[0], in.GetDim(), in.GetNX(), in.GetNY(), in.GetNZ())
+	var svx []byte
+	err := m.waitJob(ctx, in, func(j *job) (err error) {
+		svx, err = j.result()
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	return &pb.NewJobResponse{SvxFile: svx}, nil
+}
 
-	var zbuf bytes.Buffer
-	m.zipFile = zip.NewWriter(&zbuf)
+// NewJobStream is NewJob with the request and the SVX file streamed in chunks.
+func (m *master) NewJobStream(stream pb.Master_NewJobStreamServer) error {
+	in, err := receiveJob(stream.Recv)
+	if err != nil {
+		return status.Errorf(status.Code(err), "NewJobStream: %v", status.Convert(err).Message())
+	}
+	return m.waitJob(stream.Context(), in, func(j *job) error { return j.sendResult(stream.Send) })
+}
+
+// waitJob runs the job and calls send to return the resulting SVX file
+// before the job is removed. The job is canceled if ctx is done first.
+func (m *master) waitJob(ctx context.Context, in *pb.NewJobRequest, send func(j *job) error) error {
+	j, err := m.submitJob(in)
+	if err != nil {
+		return err
+	}
+	defer m.removeJob(j.id)
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		j.cancelJob()
+		return ctx.Err()
+	}
+	log.Print("Sending SVX file back to client...")
+	return send(j)
+}
 
-	m.writeManifest(in, base)
-	m.writeBlankImages(in, base)
+// receiveJob receives a serialized NewJobRequest in chunks.
+func receiveJob(recv func() (*pb.Chunk, error)) (*pb.NewJobRequest, error) {
+	buf, err := pb.ReceiveChunks(recv)
+	if err != nil {
+		return nil, err
+	}
+	in := &pb.NewJobRequest{}
+	if err := proto.Unmarshal(buf, in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "bad request: %v", err)
+	}
+	return in, nil
+}
 
-	var wg sync.WaitGroup
-	for z := 0; z < base.DimZ; z++ {
-		wg.Add(1)
-		go func(z int) {
-			m.runAgent(ctx, in, z)
-			wg.Done()
-		}(z)
+// SubmitJob starts a job in the background and returns its ID.
+func (m *master) SubmitJob(stream pb.Master_SubmitJobServer) error {
+	in, err := receiveJob(stream.Recv)
+	if err != nil {
+		return status.Errorf(status.Code(err), "SubmitJob: %v", status.Convert(err).Message())
 	}
-	wg.Wait()
+	j, err := m.submitJob(in)
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(&pb.SubmitJobResponse{JobId: j.id})
+}
 
-	if err := m.zipFile.Close(); err != nil {
+func (m *master) GetJobStatus(ctx context.Context, in *pb.GetJobStatusRequest) (*pb.JobStatus, error) {
+	j, err := m.getJob(in.GetJobId())
+	if err != nil {
 		return nil, err
 	}
+	return j.status(), nil
+}
 
-	log.Print("Sending SVX file back to client...")
-	return &pb.NewJobResponse{SvxFile: zbuf.Bytes()}, nil
+func (m *master) CancelJob(ctx context.Context, in *pb.CancelJobRequest) (*pb.CancelJobResponse, error) {
+	j, err := m.getJob(in.GetJobId())
+	if err != nil {
+		return nil, err
+	}
+	j.cancelJob()
+	log.Printf("Job %v: cancel requested", j.id)
+	return &pb.CancelJobResponse{}, nil
+}
+
+// FetchResult streams the SVX file of a completed job in chunks.
+func (m *master) FetchResult(in *pb.FetchResultRequest, stream pb.Master_FetchResultServer) error {
+	j, err := m.getJob(in.GetJobId())
+	if err != nil {
+		return err
+	}
+	return j.sendResult(stream.Send)
 }
 
 func (m *master) RegisterAgent(ctx context.Context, in *pb.RegisterAgentRequest) (*pb.RegisterAgentResponse, error) {
 	address := in.GetAddress()
-	m.amu.Lock()
-	m.idleAgents[address] = struct{}{}
-	m.amu.Unlock()
-	log.Printf("Successfully registered agent %v", address)
-	return &pb.RegisterAgentResponse{}, nil
-}
-
-// runAgent runs the agent on the given z slice.
-func (m *master) runAgent(ctx context.Context, in *pb.NewJobRequest, z int) {
-	req := &pb.SliceJobRequest{NewJobRequest: in, Z: int64(z)}
-	var conn *grpc.ClientConn
-	var address string
-	for {
-		address = m.getIdleAgent()
-		log.Printf("Assigning agent %v to slice z=%v", address, z)
-		var err error
-		conn, err = grpc.Dial(address, grpc.WithInsecure(), grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxSize)), grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(maxSize)))
-		if err != nil {
-			log.Printf("Unable to communicate with agent %v, finding another...", address)
-			continue
-		}
-		break
+	if err := m.addAgent(address, in.GetCapacity()); err != nil {
+		return nil, err
 	}
-	defer conn.Close()
-	c := pb.NewAgentClient(conn)
-	resp, err := c.SliceJob(ctx, req)
-	if err != nil {
-		log.Printf("Error running SliceJob with agent %v: %v", address, err)
-		return
+	log.Printf("Successfully registered agent %v (%v CPUs, %v bytes, %v slices)", address, in.GetCapacity().GetNumCpu(), in.GetCapacity().GetMemoryBytes(), in.GetCapacity().GetMaxSlices())
+	return &pb.RegisterAgentResponse{HeartbeatIntervalMs: int64(heartbeatInterval / time.Millisecond)}, nil
+}
+
+func (m *master) Heartbeat(ctx context.Context, in *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	registered := m.heartbeat(in.GetAddress(), in.GetCapacity())
+	if !registered {
+		log.Printf("Heartbeat from unknown agent %v; asking it to register", in.GetAddress())
 	}
+	return &pb.HeartbeatResponse{Registered: registered}, nil
+}
+
+func (m *master) Deregister(ctx context.Context, in *pb.DeregisterRequest) (*pb.DeregisterResponse, error) {
 	m.amu.Lock()
-	m.idleAgents[address] = struct{}{} // Mark agent as idle
+	m.removeAgent(in.GetAddress())
 	m.amu.Unlock()
+	log.Printf("Deregistered agent %v", in.GetAddress())
+	return &pb.DeregisterResponse{}, nil
+}
 
-	// Write the PNG to the zip file.
-	outFile := fmt.Sprintf("%v/out-%v-%v-%v-%v-%04d.png", in.GetDim(), in.GetDim(), in.GetNX(), in.GetNY(), in.GetNZ(), z+1)
+// writeSVX writes the SVX file of a job whose slices are all complete
+// to w, one slice at a time.
+func (m *master) writeSVX(j *job, w io.Writer) error {
+	in := j.req
+	zw := zip.NewWriter(w)
+	if err := writeManifest(zw, in, j.base); err != nil {
+		return err
+	}
+	if err := writeBlankImages(zw, in, j.base); err != nil {
+		return err
+	}
 
-	log.Printf("runAgent(%v): writing %v to zip file...", z, outFile)
-	m.zmu.Lock()
-	defer m.zmu.Unlock()
-	f, err := m.zipFile.Create(outFile)
-	if err != nil {
-		log.Printf("runAgent(%v) error: %v", z, err)
-		return
-	}
-	if _, err := f.Write(resp.GetPngFile()); err != nil {
-		log.Printf("runAgent(%v) error: %v", z, err)
-	}
-}
-
-// getIdleAgent sits in a loop waiting for an agent to become idle, then marks it busy and returns its address.
-func (m *master) getIdleAgent() string {
-	for {
-		m.amu.Lock()
-		var address string
-		if len(m.idleAgents) > 0 {
-			for address = range m.idleAgents {
-				break // get any address from the map
-			}
-			delete(m.idleAgents, address)
-			m.amu.Unlock()
-			return address
+	for z := range j.slices {
+		pngFile, err := j.slicePNG(z)
+		if err != nil {
+			return fmt.Errorf("writeSVX(%v): %v", z, err)
+		}
+		outFile := fmt.Sprintf("%v/out-%v-%v-%v-%v-%04d.png", in.GetDim(), in.GetDim(), in.GetNX(), in.GetNY(), in.GetNZ(), z+1)
+		f, err := zw.Create(outFile)
+		if err != nil {
+			return fmt.Errorf("writeSVX(%v): %v", z, err)
+		}
+		if _, err := f.Write(pngFile); err != nil {
+			return fmt.Errorf("writeSVX(%v): %v", z, err)
 		}
-		m.amu.Unlock()
-		time.Sleep(idleWaitTime)
 	}
+
+	return zw.Close()
 }
 
-func (m *master) writeManifest(in *pb.NewJobRequest, base *stl.STL) error {
+func writeManifest(zw *zip.Writer, in *pb.NewJobRequest, base *stl.STL) error {
 	const outFile = "manifest.xml"
-	log.Printf("Writing file %v ... need %v agents for job...", outFile, base.DimZ)
-	m.zmu.Lock()
-	defer m.zmu.Unlock()
-	mf, err := m.zipFile.Create(outFile)
+	log.Printf("Writing file %v ...", outFile)
+	mf, err := zw.Create(outFile)
 	if err != nil {
 		return err
 	}
@@ -155,27 +228,23 @@ func (m *master) writeManifest(in *pb.NewJobRequest, base *stl.STL) error {
     <channel type="DENSITY" bits="8" slices="%v/%v-%v-%v-%v-%v-%%04d.png" />
   </channels>
 </grid>
-`, base.ModelDimX+2, base.ModelDimZ+2, base.ModelDimY+2, base.MMPV*1e-3, base.MBB.Min.X, base.MBB.Min.Z, base.MBB.Min.Y, in.GetDim(), in.GetOutPrefix(), in.GetDim(), in.GetNX(), in.GetNY(), in.GetNZ())
+`, base.ModelDimX+2, base.ModelDimZ+2, base.ModelDimY+2, base.MMPV*1e-3, base.MBB.Min.X*1e-3, base.MBB.Min.Z*1e-3, base.MBB.Min.Y*1e-3, in.GetDim(), in.GetOutPrefix(), in.GetDim(), in.GetNX(), in.GetNY(), in.GetNZ())
 	return nil
 }
 
-func (m *master) writeBlankImages(in *pb.NewJobRequest, base *stl.STL) {
+func writeBlankImages(zw *zip.Writer, in *pb.NewJobRequest, base *stl.STL) error {
 	// For Shapeways, create a black base and a black top.
 	rect := image.Rect(0, 0, int(in.GetDim()+2), int(in.GetDim()+2))
 	img := image.NewGray(rect)
 	draw.Draw(img, rect, image.Black, image.ZP, draw.Over)
-	m.zmu.Lock()
-	defer m.zmu.Unlock()
-	outf := func(fn string) {
-		f, err := m.zipFile.Create(fn)
+	for _, z := range []int{0, base.DimZ + 1} {
+		f, err := zw.Create(fmt.Sprintf("%v/out-%v-%v-%v-%v-%04d.png", in.GetDim(), in.GetDim(), in.GetNX(), in.GetNY(), in.GetNZ(), z))
 		if err != nil {
-			log.Printf("error: %v", err)
-			return
+			return err
 		}
 		if err := png.Encode(f, img); err != nil {
-			log.Printf("error: %v", err)
+			return err
 		}
 	}
-	outf(fmt.Sprintf("%v/out-%v-%v-%v-%v-%04d.png", in.GetDim(), in.GetDim(), in.GetNX(), in.GetNY(), in.GetNZ(), 0))
-	outf(fmt.Sprintf("%v/out-%v-%v-%v-%v-%04d.png", in.GetDim(), in.GetDim(), in.GetNX(), in.GetNY(), in.GetNZ(), base.DimZ+1))
+	return nil
 }
@@ -0,0 +1,780 @@
+package master
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gl "github.com/fogleman/fauxgl"
+	pb "github.com/gmlewis/stldice/v4/stl2svx/proto"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeAgent is an in-memory pb.AgentServer whose slices fail when fail returns true.
+type fakeAgent struct {
+	mu        sync.Mutex
+	calls     int // slices rendered (or attempted)
+	ranges    int // SliceRange calls
+	active    int // calls currently running
+	maxActive int // maximum concurrent calls seen
+	fail      func(call int) bool
+	failErr   error           // the error of failing calls (if not "agent crashed")
+	hang      bool            // failing calls wait for their deadline instead of returning
+	delay     time.Duration   // how long each call takes
+	slots     int             // concurrent slices reported at registration
+	gate      chan struct{}   // if not nil, calls wait until it is closed
+	meshes    map[string]bool // hashes of uploaded STL files
+	puts      int             // number of PutMesh calls
+}
+
+func (f *fakeAgent) SliceJob(ctx context.Context, in *pb.SliceJobRequest) (*pb.SliceJobResponse, error) {
+	if err := f.checkMeshes(in); err != nil {
+		return nil, err
+	}
+	pngFile, err := f.slice(ctx, in.GetZ())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SliceJobResponse{PngFile: pngFile}, nil
+}
+
+func (f *fakeAgent) SliceRange(in *pb.SliceJobRequest, stream pb.Agent_SliceRangeServer) error {
+	if err := f.checkMeshes(in); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.ranges++
+	f.mu.Unlock()
+	for z := in.GetZ(); z < in.GetZ()+in.GetZCount(); z++ {
+		pngFile, err := f.slice(stream.Context(), z)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&pb.SliceJobResponse{PngFile: pngFile, Z: z}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkMeshes returns a NotFound error if any of the request's STL files
+// have not been uploaded.
+func (f *fakeAgent) checkMeshes(in *pb.SliceJobRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, hash := range in.GetMeshHashes() {
+		if !f.meshes[hash] {
+			return status.Errorf(codes.NotFound, "unknown mesh %v", hash)
+		}
+	}
+	return nil
+}
+
+// slice renders slice z, counting it as a call.
+func (f *fakeAgent) slice(ctx context.Context, z int64) ([]byte, error) {
+	f.mu.Lock()
+	f.calls++
+	f.active++
+	if f.active > f.maxActive {
+		f.maxActive = f.active
+	}
+	fail := f.fail != nil && f.fail(f.calls)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.active--
+		f.mu.Unlock()
+	}()
+	time.Sleep(f.delay)
+	if f.gate != nil {
+		<-f.gate
+	}
+	if fail && f.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if fail && f.failErr != nil {
+		return nil, f.failErr
+	}
+	if fail {
+		return nil, fmt.Errorf("agent crashed")
+	}
+	return []byte(fmt.Sprintf("slice %v", z)), nil
+}
+
+func (f *fakeAgent) PutMesh(stream pb.Agent_PutMeshServer) error {
+	hash, _, err := pb.ReceiveMesh(stream.Recv)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	if f.meshes == nil {
+		f.meshes = make(map[string]bool)
+	}
+	f.meshes[hash] = true
+	f.puts++
+	f.mu.Unlock()
+	return stream.SendAndClose(&pb.PutMeshResponse{})
+}
+
+// numPuts returns the number of STL files uploaded to the agent.
+func (f *fakeAgent) numPuts() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.puts
+}
+
+// forgetMeshes simulates an agent restart that loses its mesh cache.
+func (f *fakeAgent) forgetMeshes() {
+	f.mu.Lock()
+	f.meshes = nil
+	f.mu.Unlock()
+}
+
+func (f *fakeAgent) numCalls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func (f *fakeAgent) numRanges() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ranges
+}
+
+func (f *fakeAgent) maxConcurrent() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.maxActive
+}
+
+// newTestMaster returns a master with the given fake agents registered.
+func newTestMaster(t *testing.T, agents ...*fakeAgent) *master {
+	t.Helper()
+	m, err := New("")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	m.retryBackoff = time.Millisecond
+	m.agentProbation = 10 * time.Millisecond
+	m.sliceBatch = 1
+	addAgents(t, m, agents...)
+	return m
+}
+
+// addAgents serves the fake agents over in-memory connections and
+// registers them with the master as agent0, agent1, etc.
+func addAgents(t *testing.T, m *master, agents ...*fakeAgent) {
+	t.Helper()
+	var listeners []*bufconn.Listener
+	for _, a := range agents {
+		lis := bufconn.Listen(1 << 20)
+		gs := grpc.NewServer()
+		pb.RegisterAgentServer(gs, a)
+		go gs.Serve(lis)
+		t.Cleanup(gs.Stop)
+		listeners = append(listeners, lis)
+	}
+	m.dial = func(address string) (pb.AgentClient, io.Closer, error) {
+		var i int
+		fmt.Sscanf(address, "agent%d", &i)
+		dialer := func(ctx context.Context, _ string) (net.Conn, error) { return listeners[i].DialContext(ctx) }
+		conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(dialer), grpc.WithInsecure())
+		if err != nil {
+			return nil, nil, err
+		}
+		t.Cleanup(func() { conn.Close() })
+		return pb.NewAgentClient(conn), conn, nil
+	}
+	for i, a := range agents {
+		req := &pb.RegisterAgentRequest{Address: fmt.Sprintf("agent%v", i), Capacity: &pb.Capacity{MaxSlices: int32(a.slots)}}
+		if _, err := m.RegisterAgent(context.Background(), req); err != nil {
+			t.Fatalf("RegisterAgent: %v", err)
+		}
+	}
+}
+
+// cubeJob returns a job for an 8x8x8 voxel cube.
+func cubeJob() *pb.NewJobRequest {
+	mesh := gl.NewCube()
+	mesh.Transform(gl.Translate(gl.V(1, 1, 1)).Scale(gl.V(4, 4, 4)))
+	stlFile := &pb.STLFile{}
+	for _, t := range mesh.Triangles {
+		stlFile.Triangles = append(stlFile.Triangles, &pb.Triangle{
+			V1: &pb.Vertex{X: t.V1.Position.X, Y: t.V1.Position.Y, Z: t.V1.Position.Z},
+			V2: &pb.Vertex{X: t.V2.Position.X, Y: t.V2.Position.Y, Z: t.V2.Position.Z},
+			V3: &pb.Vertex{X: t.V3.Position.X, Y: t.V3.Position.Y, Z: t.V3.Position.Z},
+		})
+	}
+	return &pb.NewJobRequest{StlFiles: []*pb.STLFile{stlFile}, Dim: 8, NX: 1, NY: 1, NZ: 1, OutPrefix: "out"}
+}
+
+// checkSVX verifies that the SVX file contains every slice of cubeJob.
+func checkSVX(t *testing.T, svx []byte) {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(svx), int64(len(svx)))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	files := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(buf)
+	}
+	// The manifest, 8 slices, and a blank image at each end.
+	if got, want := len(files), 11; got != want {
+		t.Errorf("SVX file has %v files, want %v", got, want)
+	}
+	for z := 0; z < 8; z++ {
+		fn := fmt.Sprintf("8/out-8-1-1-1-%04d.png", z+1)
+		if got, want := files[fn], fmt.Sprintf("slice %v", z); got != want {
+			t.Errorf("%v = %q, want %q", fn, got, want)
+		}
+	}
+}
+
+func TestIntermittentFailures(t *testing.T) {
+	flaky := &fakeAgent{fail: func(call int) bool { return call%2 == 0 }}
+	dead := &fakeAgent{fail: func(int) bool { return true }}
+	good := &fakeAgent{}
+	m := newTestMaster(t, flaky, dead, good)
+	m.agentProbation = time.Minute // the dead agent stays unhealthy
+
+	resp, err := m.NewJob(context.Background(), cubeJob())
+	if err != nil {
+		t.Fatalf("NewJob: %v", err)
+	}
+	checkSVX(t, resp.GetSvxFile())
+	if got := good.numCalls(); got == 0 {
+		t.Error("healthy agent was never used")
+	}
+}
+
+func TestSliceExhaustsRetries(t *testing.T) {
+	dead := &fakeAgent{fail: func(int) bool { return true }}
+	m := newTestMaster(t, dead)
+	m.maxAgentFailures = 100
+
+	if _, err := m.NewJob(context.Background(), cubeJob()); err == nil {
+		t.Fatal("NewJob = nil error, want error")
+	}
+	// Each of the 8 slices is tried at most maxRetries+1 times. (A call
+	// that was in flight when the job failed may still reach the agent.)
+	time.Sleep(20 * time.Millisecond)
+	calls := dead.numCalls()
+	if min, max := m.maxRetries+1, 8*(m.maxRetries+1); calls < min || calls > max {
+		t.Errorf("agent was called %v times, want %v to %v", calls, min, max)
+	}
+	// No slices are dispatched once the job has failed.
+	time.Sleep(20 * time.Millisecond)
+	if got := dead.numCalls(); got != calls {
+		t.Errorf("agent was called %v times after the job failed", got-calls)
+	}
+}
+
+func TestSliceDeadline(t *testing.T) {
+	// The first call hangs until its deadline, then the slice is retried.
+	slow := &fakeAgent{fail: func(call int) bool { return call == 1 }, hang: true}
+	m := newTestMaster(t, slow)
+	m.sliceTimeout = 10 * time.Millisecond
+
+	resp, err := m.NewJob(context.Background(), cubeJob())
+	if err != nil {
+		t.Fatalf("NewJob: %v", err)
+	}
+	checkSVX(t, resp.GetSvxFile())
+}
+
+func TestReRegistration(t *testing.T) {
+	a := &fakeAgent{fail: func(call int) bool { return call <= 2 }}
+	m := newTestMaster(t, a)
+	m.agentProbation = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		_, err := m.NewJob(ctx, cubeJob())
+		done <- err
+	}()
+
+	// After two failures, the agent is unhealthy until it re-registers.
+	for a.numCalls() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if got := a.numCalls(); got != 2 {
+		t.Fatalf("unhealthy agent was called %v times, want 2", got)
+	}
+	if _, err := m.RegisterAgent(ctx, &pb.RegisterAgentRequest{Address: "agent0"}); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("NewJob: %v", err)
+	}
+}
+
+func TestCanceledJob(t *testing.T) {
+	m := newTestMaster(t) // no agents
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := m.NewJob(ctx, cubeJob()); err == nil {
+		t.Fatal("NewJob with no agents = nil error, want error")
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	big := &fakeAgent{slots: 4, delay: 20 * time.Millisecond}
+	small := &fakeAgent{slots: 1, delay: 20 * time.Millisecond}
+	m := newTestMaster(t, big, small)
+
+	resp, err := m.NewJob(context.Background(), cubeJob())
+	if err != nil {
+		t.Fatalf("NewJob: %v", err)
+	}
+	checkSVX(t, resp.GetSvxFile())
+	if got, want := big.maxConcurrent(), 4; got != want {
+		t.Errorf("high-capacity agent ran at most %v slices at once, want %v", got, want)
+	}
+	if got, want := small.maxConcurrent(), 1; got != want {
+		t.Errorf("low-capacity agent ran at most %v slices at once, want %v", got, want)
+	}
+	if big.numCalls() <= small.numCalls() {
+		t.Errorf("high-capacity agent ran %v slices, low-capacity agent ran %v", big.numCalls(), small.numCalls())
+	}
+}
+
+func TestHeartbeatEviction(t *testing.T) {
+	m := newTestMaster(t, &fakeAgent{})
+	m.agentTimeout = 20 * time.Millisecond
+	ctx := context.Background()
+
+	resp, err := m.Heartbeat(ctx, &pb.HeartbeatRequest{Address: "agent0", Capacity: &pb.Capacity{MaxSlices: 2}})
+	if err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+	if !resp.GetRegistered() {
+		t.Fatal("Heartbeat from registered agent: registered = false")
+	}
+	if got := m.agents["agent0"].slots(); got != 2 {
+		t.Errorf("slots after heartbeat = %v, want 2", got)
+	}
+
+	// A silent agent is evicted and must register again.
+	time.Sleep(2 * m.agentTimeout)
+	if resp, err = m.Heartbeat(ctx, &pb.HeartbeatRequest{Address: "agent0"}); err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+	if resp.GetRegistered() {
+		t.Error("Heartbeat from evicted agent: registered = true")
+	}
+	if resp, err = m.Heartbeat(ctx, &pb.HeartbeatRequest{Address: "unknown"}); err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+	if resp.GetRegistered() {
+		t.Error("Heartbeat from unknown agent: registered = true")
+	}
+}
+
+func TestDeregister(t *testing.T) {
+	gone := &fakeAgent{}
+	stays := &fakeAgent{}
+	m := newTestMaster(t, gone, stays)
+	if _, err := m.Deregister(context.Background(), &pb.DeregisterRequest{Address: "agent0"}); err != nil {
+		t.Fatalf("Deregister: %v", err)
+	}
+
+	resp, err := m.NewJob(context.Background(), cubeJob())
+	if err != nil {
+		t.Fatalf("NewJob: %v", err)
+	}
+	checkSVX(t, resp.GetSvxFile())
+	if got := gone.numCalls(); got != 0 {
+		t.Errorf("deregistered agent ran %v slices, want 0", got)
+	}
+}
+
+// startMaster serves the master over an in-memory connection and returns a client for it.
+func startMaster(t *testing.T, m *master) pb.MasterClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	pb.RegisterMasterServer(gs, m)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(dialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc.DialContext: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewMasterClient(conn)
+}
+
+// submitJob submits cubeJob and returns its ID.
+func submitJob(t *testing.T, c pb.MasterClient) string {
+	t.Helper()
+	buf, err := proto.Marshal(cubeJob())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := c.SubmitJob(context.Background())
+	if err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+	if err := pb.SendChunks(buf, stream.Send); err != nil {
+		t.Fatalf("SendChunks: %v", err)
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+	return resp.GetJobId()
+}
+
+// waitForState polls the job until it reaches the state.
+func waitForState(t *testing.T, c pb.MasterClient, id string, state pb.JobState) *pb.JobStatus {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(5 * time.Millisecond) {
+		status, err := c.GetJobStatus(context.Background(), &pb.GetJobStatusRequest{JobId: id})
+		if err != nil {
+			t.Fatalf("GetJobStatus: %v", err)
+		}
+		if status.GetState() == state {
+			return status
+		}
+	}
+	t.Fatalf("job %v never reached state %v", id, state)
+	return nil
+}
+
+func fetchResult(c pb.MasterClient, id string) ([]byte, error) {
+	stream, err := c.FetchResult(context.Background(), &pb.FetchResultRequest{JobId: id})
+	if err != nil {
+		return nil, err
+	}
+	return pb.ReceiveChunks(stream.Recv)
+}
+
+func TestAsyncJob(t *testing.T) {
+	// The agent is held until the job has been seen running.
+	a := &fakeAgent{slots: 8, fail: func(call int) bool { return call == 3 }, gate: make(chan struct{})}
+	m := newTestMaster(t, a)
+	c := startMaster(t, m)
+
+	id := submitJob(t, c)
+	status := waitForState(t, c, id, pb.JobState_JOB_RUNNING)
+	if got, want := status.GetTotalSlices(), int64(8); got != want {
+		t.Errorf("total slices = %v, want %v", got, want)
+	}
+	if _, err := fetchResult(c, id); err == nil {
+		t.Error("FetchResult of running job = nil error, want error")
+	}
+	close(a.gate)
+
+	status = waitForState(t, c, id, pb.JobState_JOB_DONE)
+	if got, want := status.GetCompletedSlices(), int64(8); got != want {
+		t.Errorf("completed slices = %v, want %v", got, want)
+	}
+	var attempts int32
+	for _, s := range status.GetSlices() {
+		if s.GetState() != pb.SliceState_SLICE_DONE || s.GetAgent() != "agent0" {
+			t.Errorf("slice %v: state %v on agent %q, want SLICE_DONE on agent0", s.GetZ(), s.GetState(), s.GetAgent())
+		}
+		attempts += s.GetAttempts()
+	}
+	if attempts != 1 {
+		t.Errorf("failed attempts = %v, want 1", attempts)
+	}
+	if got, want := status.GetSvxName(), "out-8-1-1-1.svx"; got != want {
+		t.Errorf("SVX name = %q, want %q", got, want)
+	}
+	svx, err := fetchResult(c, id)
+	if err != nil {
+		t.Fatalf("FetchResult: %v", err)
+	}
+	checkSVX(t, svx)
+}
+
+func TestCancelJob(t *testing.T) {
+	// The agent never finishes a slice.
+	a := &fakeAgent{fail: func(int) bool { return true }, hang: true}
+	m := newTestMaster(t, a)
+	c := startMaster(t, m)
+	ctx := context.Background()
+
+	id := submitJob(t, c)
+	if _, err := c.CancelJob(ctx, &pb.CancelJobRequest{JobId: id}); err != nil {
+		t.Fatalf("CancelJob: %v", err)
+	}
+	waitForState(t, c, id, pb.JobState_JOB_CANCELED)
+	if _, err := fetchResult(c, id); err == nil {
+		t.Error("FetchResult of canceled job = nil error, want error")
+	}
+
+	if _, err := c.GetJobStatus(ctx, &pb.GetJobStatusRequest{JobId: "bogus"}); err == nil {
+		t.Error("GetJobStatus of unknown job = nil error, want error")
+	}
+	if _, err := c.CancelJob(ctx, &pb.CancelJobRequest{JobId: "bogus"}); err == nil {
+		t.Error("CancelJob of unknown job = nil error, want error")
+	}
+}
+
+// copyDir copies the files in src to a new directory dst.
+func copyDir(t *testing.T, src, dst string) {
+	t.Helper()
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(src, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range files {
+		buf, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dst, filepath.Base(fn)), buf, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResumeJob(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	m1, err := New(dir1)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	// The first agent completes 3 slices, then hangs.
+	addAgents(t, m1, &fakeAgent{fail: func(call int) bool { return call > 3 }, hang: true})
+	j, err := m1.submitJob(cubeJob())
+	if err != nil {
+		t.Fatalf("submitJob: %v", err)
+	}
+	for j.status().GetCompletedSlices() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Restart the master from a copy of its directory as it was when it crashed.
+	copyDir(t, filepath.Join(dir1, j.id), filepath.Join(dir2, j.id))
+	j.cancelJob()
+	<-j.done
+	if err := os.MkdirAll(filepath.Join(dir2, "junk"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	m2, err := New(dir2)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	a := &fakeAgent{}
+	addAgents(t, m2, a)
+	c := startMaster(t, m2)
+	waitForState(t, c, j.id, pb.JobState_JOB_DONE)
+	if got, want := a.numCalls(), 5; got != want {
+		t.Errorf("resumed job ran %v slices, want %v", got, want)
+	}
+	svx, err := fetchResult(c, j.id)
+	if err != nil {
+		t.Fatalf("FetchResult: %v", err)
+	}
+	checkSVX(t, svx)
+
+	// A completed job is still available after another restart.
+	m3, err := New(dir2)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	c = startMaster(t, m3)
+	status := waitForState(t, c, j.id, pb.JobState_JOB_DONE)
+	if got, want := status.GetCompletedSlices(), int64(8); got != want {
+		t.Errorf("completed slices = %v, want %v", got, want)
+	}
+	svx, err = fetchResult(c, j.id)
+	if err != nil {
+		t.Fatalf("FetchResult: %v", err)
+	}
+	checkSVX(t, svx)
+}
+
+func TestSaveResultFails(t *testing.T) {
+	m, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	a := &fakeAgent{gate: make(chan struct{})}
+	addAgents(t, m, a)
+	c := startMaster(t, m)
+
+	// A directory in the way of the SVX file makes persisting the result fail.
+	id := submitJob(t, c)
+	j, err := m.getJob(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(j.dir, resultFile, "junk"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	close(a.gate)
+	status := waitForState(t, c, id, pb.JobState_JOB_FAILED)
+	if !strings.Contains(status.GetError(), "unable to save result") {
+		t.Errorf("error = %q, want unable to save result", status.GetError())
+	}
+	if _, err := fetchResult(c, id); err == nil {
+		t.Error("FetchResult of failed job = nil error, want error")
+	}
+}
+
+func TestRetainReloadedJob(t *testing.T) {
+	dir := t.TempDir()
+	m1, err := New(dir)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	addAgents(t, m1, &fakeAgent{})
+	j, err := m1.submitJob(cubeJob())
+	if err != nil {
+		t.Fatalf("submitJob: %v", err)
+	}
+	<-j.done
+
+	// A reloaded job that finished longer than jobRetention ago is removed.
+	old := time.Now().Add(-jobRetention - time.Hour)
+	if err := os.Chtimes(filepath.Join(j.dir, statusFile), old, old); err != nil {
+		t.Fatal(err)
+	}
+	m2, err := New(dir)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for start := time.Now(); ; time.Sleep(5 * time.Millisecond) {
+		if _, err := os.Stat(j.dir); os.IsNotExist(err) {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatalf("job %v was never removed", j.id)
+		}
+	}
+	if _, err := m2.getJob(j.id); err == nil {
+		t.Errorf("getJob(%v) = nil error, want error", j.id)
+	}
+}
+
+func TestPutMesh(t *testing.T) {
+	a, b := &fakeAgent{slots: 2}, &fakeAgent{slots: 2}
+	m := newTestMaster(t, a, b)
+
+	// Each agent receives the mesh once, not once per slice.
+	resp, err := m.NewJob(context.Background(), cubeJob())
+	if err != nil {
+		t.Fatalf("NewJob: %v", err)
+	}
+	checkSVX(t, resp.GetSvxFile())
+	if a.numPuts() != 1 || b.numPuts() != 1 {
+		t.Errorf("agents received %v and %v meshes, want 1 each", a.numPuts(), b.numPuts())
+	}
+
+	// An agent that loses its cache gets the mesh again.
+	a.forgetMeshes()
+	b.forgetMeshes()
+	resp, err = m.NewJob(context.Background(), cubeJob())
+	if err != nil {
+		t.Fatalf("NewJob: %v", err)
+	}
+	checkSVX(t, resp.GetSvxFile())
+	if got := a.numPuts() + b.numPuts(); got < 3 {
+		t.Errorf("agents received %v meshes in total, want at least 3", got)
+	}
+	// Uploading the mesh again does not use up a retry.
+	if got := a.numCalls() + b.numCalls(); got != 16 {
+		t.Errorf("agents ran %v slices, want 16", got)
+	}
+}
+
+func TestSliceBatch(t *testing.T) {
+	// The second slice of the first range fails, so only the rest of that
+	// range is retried.
+	a := &fakeAgent{fail: func(call int) bool { return call == 2 }}
+	m := newTestMaster(t, a)
+	m.sliceBatch = 3
+
+	resp, err := m.NewJob(context.Background(), cubeJob())
+	if err != nil {
+		t.Fatalf("NewJob: %v", err)
+	}
+	checkSVX(t, resp.GetSvxFile())
+	// Ranges z=0-2, 3-5 and 6-7, plus the retry of z=1-2.
+	if got, want := a.numRanges(), 4; got != want {
+		t.Errorf("agent received %v ranges, want %v", got, want)
+	}
+	if got, want := a.numCalls(), 9; got != want {
+		t.Errorf("agent ran %v slices, want %v", got, want)
+	}
+}
+
+func TestMalformedJob(t *testing.T) {
+	a := &fakeAgent{}
+	m := newTestMaster(t, a)
+
+	in := cubeJob()
+	in.StlFiles[0].Triangles[5].V1 = nil
+	if _, err := m.NewJob(context.Background(), in); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("NewJob with missing vertex = %v, want InvalidArgument", err)
+	}
+	in = cubeJob()
+	in.NX = 0
+	if _, err := m.NewJob(context.Background(), in); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("NewJob with nx=0 = %v, want InvalidArgument", err)
+	}
+	if got := a.numCalls(); got != 0 {
+		t.Errorf("agent ran %v slices of malformed jobs", got)
+	}
+	if _, err := m.GetJobStatus(context.Background(), &pb.GetJobStatusRequest{JobId: "unknown"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetJobStatus of unknown job = %v, want NotFound", err)
+	}
+}
+
+func TestAgentRejectsJob(t *testing.T) {
+	// An agent that rejects the job fails it without retries and stays healthy.
+	a := &fakeAgent{fail: func(call int) bool { return call == 1 }, failErr: status.Error(codes.InvalidArgument, "bad mesh")}
+	m := newTestMaster(t, a)
+	m.maxAgentFailures = 1
+	m.agentProbation = time.Minute
+
+	_, err := m.NewJob(context.Background(), cubeJob())
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("NewJob = %v, want Aborted", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := m.NewJob(ctx, cubeJob())
+	if err != nil {
+		t.Fatalf("NewJob after rejected job: %v", err)
+	}
+	checkSVX(t, resp.GetSvxFile())
+}
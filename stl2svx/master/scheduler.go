@@ -0,0 +1,436 @@
+package master
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	pb "github.com/gmlewis/stldice/v4/stl2svx/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultMaxRetries       = 3                     // retries per slice before the job fails
+	defaultRetryBackoff     = time.Second           // delay before the first retry of a slice
+	defaultSliceTimeout     = 10 * time.Minute      // deadline per slice of a SliceRange call
+	defaultSliceBatch       = 4                     // slices per SliceRange call
+	defaultMaxAgentFailures = 2                     // consecutive failures before an agent is unhealthy
+	defaultAgentProbation   = 30 * time.Second      // how long an unhealthy agent is skipped
+	heartbeatInterval       = 10 * time.Second      // how often agents send a Heartbeat
+	defaultAgentTimeout     = 3 * heartbeatInterval // silence before an agent is evicted
+)
+
+// agentInfo tracks the capacity and health of a registered agent.
+type agentInfo struct {
+	address  string
+	client   pb.AgentClient
+	conn     io.Closer
+	capacity *pb.Capacity
+	active   int       // slices currently running on the agent
+	failures int       // consecutive failed slices
+	retryAt  time.Time // unhealthy until this time (zero if healthy)
+	lastSeen time.Time // time of the last registration or heartbeat
+
+	mmu    sync.Mutex      // protects meshes (and serializes uploads)
+	meshes map[string]bool // hashes of the STL files uploaded to the agent
+}
+
+// slots returns the number of slices the agent may run concurrently.
+func (a *agentInfo) slots() int {
+	if n := int(a.capacity.GetMaxSlices()); n > 0 {
+		return n
+	}
+	return 1
+}
+
+// sliceTask is a range of slices of a job waiting in the work queue.
+type sliceTask struct {
+	z, n     int // the slices z through z+n-1
+	next     int // the next slice whose image is expected
+	attempts int
+}
+
+// sliceResult is the image of the next slice of a sliceTask or, if done
+// is set, the outcome of running the sliceTask on an agent.
+type sliceResult struct {
+	task    *sliceTask
+	pngFile []byte
+	done    bool
+	err     error
+}
+
+// dialAgent connects to the agent at address.
+func dialAgent(address string) (pb.AgentClient, io.Closer, error) {
+	conn, err := grpc.Dial(address, grpc.WithInsecure(), grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxSize)), grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(maxSize)))
+	if err != nil {
+		return nil, nil, err
+	}
+	return pb.NewAgentClient(conn), conn, nil
+}
+
+// addAgent adds a newly-registered agent or, if it is already known,
+// updates its capacity and marks it healthy again.
+func (m *master) addAgent(address string, capacity *pb.Capacity) error {
+	m.amu.Lock()
+	defer m.amu.Unlock()
+	if a, ok := m.agents[address]; ok {
+		a.capacity = capacity
+		a.failures = 0
+		a.retryAt = time.Time{}
+		a.lastSeen = time.Now()
+		m.notifyAgents()
+		return nil
+	}
+	client, conn, err := m.dial(address)
+	if err != nil {
+		return fmt.Errorf("unable to dial agent %v: %v", address, err)
+	}
+	m.agents[address] = &agentInfo{address: address, client: client, conn: conn, capacity: capacity, lastSeen: time.Now(), meshes: make(map[string]bool)}
+	m.notifyAgents()
+	return nil
+}
+
+// heartbeat records that the agent is alive and updates its capacity.
+// It returns false if the agent is not registered.
+func (m *master) heartbeat(address string, capacity *pb.Capacity) bool {
+	m.amu.Lock()
+	defer m.amu.Unlock()
+	m.evictSilentAgents()
+	a, ok := m.agents[address]
+	if !ok {
+		return false
+	}
+	a.lastSeen = time.Now()
+	if capacity != nil {
+		a.capacity = capacity
+		m.notifyAgents() // the agent may have more free slots
+	}
+	return true
+}
+
+// removeAgent forgets the agent. Slices running on it are unaffected
+// (and are retried elsewhere if they fail).
+// m.amu must be held.
+func (m *master) removeAgent(address string) {
+	a, ok := m.agents[address]
+	if !ok {
+		return
+	}
+	delete(m.agents, address)
+	if err := a.conn.Close(); err != nil {
+		log.Printf("Closing connection to agent %v: %v", address, err)
+	}
+	m.notifyAgents()
+}
+
+// evictSilentAgents removes agents that have not sent a heartbeat recently.
+// m.amu must be held.
+func (m *master) evictSilentAgents() {
+	now := time.Now()
+	for address, a := range m.agents {
+		if now.Sub(a.lastSeen) > m.agentTimeout {
+			log.Printf("Evicting agent %v: no heartbeat for %v", address, now.Sub(a.lastSeen))
+			m.removeAgent(address)
+		}
+	}
+}
+
+// notifyAgents wakes up everyone waiting in acquireAgent.
+// m.amu must be held.
+func (m *master) notifyAgents() {
+	close(m.agentsChanged)
+	m.agentsChanged = make(chan struct{})
+}
+
+// acquireAgent waits until a healthy agent has a free slot, reserves it
+// and returns the agent. The agent with the most free slots is chosen so
+// that high-capacity agents receive more slices.
+func (m *master) acquireAgent(ctx context.Context) (*agentInfo, error) {
+	for {
+		m.amu.Lock()
+		m.evictSilentAgents()
+		now := time.Now()
+		var best *agentInfo
+		var next time.Time // earliest time an unhealthy agent is available
+		for _, a := range m.agents {
+			if a.active >= a.slots() {
+				continue
+			}
+			if a.retryAt.After(now) {
+				if next.IsZero() || a.retryAt.Before(next) {
+					next = a.retryAt
+				}
+				continue
+			}
+			if best == nil || a.slots()-a.active > best.slots()-best.active {
+				best = a
+			}
+		}
+		if best != nil {
+			best.active++
+			m.amu.Unlock()
+			return best, nil
+		}
+		changed := m.agentsChanged
+		m.amu.Unlock()
+
+		var probation *time.Timer
+		var expired <-chan time.Time
+		if !next.IsZero() {
+			probation = time.NewTimer(next.Sub(now))
+			expired = probation.C
+		}
+		select {
+		case <-changed:
+		case <-expired:
+		case <-ctx.Done():
+		}
+		if probation != nil {
+			probation.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// releaseAgent frees the agent's slot and records whether its slice succeeded.
+// An agent that fails too many slices in a row is skipped until it
+// re-registers or its probation expires. Slices of bad or canceled jobs
+// are not held against the agent.
+func (m *master) releaseAgent(a *agentInfo, err error) {
+	m.amu.Lock()
+	defer m.amu.Unlock()
+	defer m.notifyAgents()
+	a.active--
+	switch status.Code(err) {
+	case codes.OK:
+		a.failures = 0
+		a.retryAt = time.Time{}
+	case codes.InvalidArgument, codes.Canceled:
+	default:
+		a.failures++
+		if a.failures >= m.maxAgentFailures {
+			log.Printf("Agent %v failed %v slices in a row; marking it unhealthy for %v", a.address, a.failures, m.agentProbation)
+			a.retryAt = time.Now().Add(m.agentProbation)
+		}
+	}
+}
+
+// sliceTasks groups the slices into ranges of consecutive slices with
+// at most batch slices each.
+func sliceTasks(slices []int, batch int) []*sliceTask {
+	var tasks []*sliceTask
+	for _, z := range slices {
+		if n := len(tasks); n > 0 {
+			t := tasks[n-1]
+			if t.z+t.n == z && t.n < batch {
+				t.n++
+				continue
+			}
+		}
+		tasks = append(tasks, &sliceTask{z: z, n: 1, next: z})
+	}
+	return tasks
+}
+
+// runSlices puts the pending slices of the job into a work queue (as
+// ranges of up to m.sliceBatch slices) and dispatches them to the agents,
+// retrying the unfinished slices of failed ranges with exponential
+// backoff. The job records the progress of each slice.
+// runSlices returns an error if any slice exhausts its retries or an agent
+// rejects the job as malformed.
+func (m *master) runSlices(ctx context.Context, j *job) error {
+	// Cancel any outstanding work and wait for it to finish before returning.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Every task is in exactly one place (the queue, a timer, or an agent),
+	// so the queue never blocks.
+	pending := j.pending()
+	queue := make(chan *sliceTask, len(pending))
+	for _, t := range sliceTasks(pending, m.sliceBatch) {
+		queue <- t
+	}
+	results := make(chan sliceResult)
+	send := func(r sliceResult) bool {
+		select {
+		case results <- r:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			var task *sliceTask
+			select {
+			case task = <-queue:
+			case <-ctx.Done():
+				return
+			}
+			a, err := m.acquireAgent(ctx)
+			if err != nil {
+				return
+			}
+			log.Printf("Job %v: assigning agent %v to slices z=%v-%v", j.id, a.address, task.z, task.z+task.n-1)
+			for z := task.z; z < task.z+task.n; z++ {
+				j.sliceStarted(z, a.address)
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := m.runRange(ctx, a, j, task, func(pngFile []byte) bool {
+					return send(sliceResult{task: task, pngFile: pngFile})
+				})
+				m.releaseAgent(a, err)
+				send(sliceResult{task: task, done: true, err: err})
+			}()
+		}
+	}()
+
+	for remaining := len(pending); remaining > 0; {
+		var r sliceResult
+		select {
+		case r = <-results:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		t := r.task
+		if !r.done {
+			if err := j.sliceDone(t.next, r.pngFile); err != nil {
+				return err
+			}
+			t.next++
+			remaining--
+			continue
+		}
+		end := t.z + t.n
+		if r.err == nil || t.next == end {
+			continue
+		}
+
+		for z := t.next; z < end; z++ {
+			j.sliceFailed(z, r.err)
+		}
+		if status.Code(r.err) == codes.InvalidArgument {
+			return fmt.Errorf("slices z=%v-%v: %v", t.next, end-1, r.err) // retrying will not help
+		}
+		t.attempts++
+		if t.attempts > m.maxRetries {
+			return fmt.Errorf("slices z=%v-%v failed after %v attempts: %v", t.next, end-1, t.attempts, r.err)
+		}
+		backoff := m.retryBackoff << uint(t.attempts-1)
+		log.Printf("Job %v: slices z=%v-%v failed (attempt %v): %v; retrying in %v", j.id, t.next, end-1, t.attempts, r.err, backoff)
+		retry := &sliceTask{z: t.next, n: end - t.next, next: t.next, attempts: t.attempts}
+		time.AfterFunc(backoff, func() { queue <- retry })
+	}
+	return nil
+}
+
+// runRange runs SliceRange for the task's slices on the agent with a
+// deadline, first uploading any of the job's STL files that the agent
+// lacks. send is called with each image, in order; it returns false if
+// the job is canceled.
+func (m *master) runRange(ctx context.Context, a *agentInfo, j *job, t *sliceTask, send func(pngFile []byte) bool) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(t.n)*m.sliceTimeout)
+	defer cancel()
+	next := t.z
+	recv := func(resp *pb.SliceJobResponse) error {
+		switch {
+		case resp.GetError() != "":
+			return fmt.Errorf("%v", resp.GetError())
+		case resp.GetZ() != int64(next):
+			return fmt.Errorf("got image of slice z=%v, want z=%v", resp.GetZ(), next)
+		case len(resp.GetPngFile()) == 0:
+			return fmt.Errorf("no image for slice z=%v", next)
+		case !send(resp.GetPngFile()):
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		next++
+		return nil
+	}
+	err := a.sliceRange(ctx, j, t, recv)
+	if status.Code(err) == codes.NotFound && next == t.z {
+		// The agent lost its cache (e.g. it restarted), so upload the meshes again.
+		a.forgetMeshes()
+		err = a.sliceRange(ctx, j, t, recv)
+	}
+	if err == nil && next < t.z+t.n {
+		err = fmt.Errorf("returned %v of %v slices", next-t.z, t.n)
+	}
+	if err != nil {
+		return status.Errorf(status.Code(err), "agent %v: %v", a.address, status.Convert(err).Message())
+	}
+	return nil
+}
+
+// sliceRange uploads the job's STL files that the agent lacks, runs
+// SliceRange for the task's slices and calls recv with each response.
+func (a *agentInfo) sliceRange(ctx context.Context, j *job, t *sliceTask, recv func(*pb.SliceJobResponse) error) error {
+	if err := a.putMeshes(ctx, j); err != nil {
+		return err
+	}
+	req := &pb.SliceJobRequest{NewJobRequest: j.spec, Z: int64(t.z), ZCount: int64(t.n), MeshHashes: j.hashes}
+	stream, err := a.client.SliceRange(ctx, req)
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := recv(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// putMeshes uploads the job's STL files that the agent does not have.
+func (a *agentInfo) putMeshes(ctx context.Context, j *job) error {
+	a.mmu.Lock()
+	defer a.mmu.Unlock()
+	for i, hash := range j.hashes {
+		if a.meshes[hash] {
+			continue
+		}
+		_, buf, err := pb.MarshalMesh(j.req.GetStlFiles()[i])
+		if err != nil {
+			return err
+		}
+		stream, err := a.client.PutMesh(ctx)
+		if err != nil {
+			return fmt.Errorf("PutMesh: %v", err)
+		}
+		if err := pb.SendMesh(hash, buf, stream.Send); err != nil {
+			return fmt.Errorf("PutMesh: %v", err)
+		}
+		if _, err := stream.CloseAndRecv(); err != nil {
+			return fmt.Errorf("PutMesh: %v", err)
+		}
+		log.Printf("Uploaded mesh %v to agent %v", hash, a.address)
+		a.meshes[hash] = true
+	}
+	return nil
+}
+
+// forgetMeshes records that the agent no longer has any STL files.
+func (a *agentInfo) forgetMeshes() {
+	a.mmu.Lock()
+	a.meshes = make(map[string]bool)
+	a.mmu.Unlock()
+}
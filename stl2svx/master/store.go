@@ -0,0 +1,172 @@
+package master
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	pb "github.com/gmlewis/stldice/v4/stl2svx/proto"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+)
+
+// Each job persisted in the master's directory has a subdirectory
+// (named by its ID) holding these files.
+const (
+	requestFile = "request.pb" // the serialized NewJobRequest
+	statusFile  = "status.pb"  // the serialized JobStatus, once the job finishes
+	resultFile  = "result.svx" // the SVX file of a completed job
+	sliceFile   = "slice-%04d.png"
+)
+
+// writeFile atomically writes data to the file.
+func writeFile(filename string, data []byte) error {
+	return createFile(filename, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// createFile atomically creates the file with the contents written by write.
+func createFile(filename string, write func(w io.Writer) error) error {
+	f, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return fmt.Errorf("unable to create temporary file: %v", err)
+	}
+	tmpFilename := f.Name()
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmpFilename)
+		return fmt.Errorf("unable to write %q: %v", tmpFilename, err)
+	}
+	if err := os.Rename(tmpFilename, filename); err != nil {
+		os.Remove(tmpFilename)
+		return fmt.Errorf("unable to rename %q: %v", tmpFilename, err)
+	}
+	return nil
+}
+
+// saveRequest creates the job's directory and writes its request.
+func (j *job) saveRequest() error {
+	if j.dir == "" {
+		return nil
+	}
+	buf, err := proto.Marshal(j.req)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(j.dir, 0755); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(j.dir, requestFile), buf)
+}
+
+// saveResult writes the status of a finished job. (The SVX file of a
+// completed job has already been written by runJob.)
+func (j *job) saveResult(status *pb.JobStatus) error {
+	if j.dir == "" {
+		return nil
+	}
+	status.Slices = nil // recovered from the slice files
+	buf, err := proto.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(j.dir, statusFile), buf)
+}
+
+// loadJobs reloads the jobs persisted in the master's directory and
+// resumes the unfinished ones, dispatching only their missing slices.
+// Finished jobs are removed once jobRetention has passed since their
+// status was written.
+func (m *master) loadJobs() error {
+	if m.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return err
+	}
+	infos, err := ioutil.ReadDir(m.dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		j, err := m.loadJob(info.Name())
+		if err != nil {
+			log.Printf("Skipping job %v: %v", info.Name(), err)
+			continue
+		}
+		m.jmu.Lock()
+		m.jobs[j.id] = j
+		m.jmu.Unlock()
+		if j.state == pb.JobState_JOB_RUNNING {
+			log.Printf("Job %v: resuming with %v of %v slices pending", j.id, len(j.pending()), len(j.slices))
+			ctx, cancel := context.WithCancel(context.Background())
+			j.cancel = cancel
+			go m.runJob(ctx, j)
+		} else {
+			close(j.done)
+			finished := time.Now()
+			if fi, err := os.Stat(filepath.Join(j.dir, statusFile)); err == nil {
+				finished = fi.ModTime()
+			}
+			m.retainJob(j.id, finished)
+		}
+	}
+	return nil
+}
+
+// loadJob reads the persisted job with the given ID.
+func (m *master) loadJob(id string) (*job, error) {
+	dir := filepath.Join(m.dir, id)
+	buf, err := ioutil.ReadFile(filepath.Join(dir, requestFile))
+	if err != nil {
+		return nil, err
+	}
+	in := &pb.NewJobRequest{}
+	if err := proto.Unmarshal(buf, in); err != nil {
+		return nil, fmt.Errorf("bad request: %v", err)
+	}
+	base, err := parseJob(in)
+	if err != nil {
+		return nil, err
+	}
+	j, err := newJob(id, in, base, dir)
+	if err != nil {
+		return nil, err
+	}
+	j.cancel = func() {}
+
+	for z, s := range j.slices {
+		if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf(sliceFile, z))); err == nil {
+			s.State = pb.SliceState_SLICE_DONE
+		}
+	}
+
+	buf, err = ioutil.ReadFile(filepath.Join(dir, statusFile))
+	if os.IsNotExist(err) {
+		return j, nil // still running
+	}
+	if err != nil {
+		return nil, err
+	}
+	status := &pb.JobStatus{}
+	if err := proto.Unmarshal(buf, status); err != nil {
+		return nil, fmt.Errorf("bad status: %v", err)
+	}
+	j.state = status.GetState()
+	if status.GetError() != "" {
+		j.err = fmt.Errorf("%v", status.GetError())
+	}
+	return j, nil
+}
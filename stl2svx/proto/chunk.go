@@ -0,0 +1,49 @@
+package stl2svx
+
+import (
+	"io"
+
+	"github.com/gmlewis/stldice/v4/chunk"
+)
+
+// ChunkSize is the maximum number of bytes of data in each Chunk.
+const ChunkSize = chunk.Size
+
+// SendChunks splits data into Chunks (with checksums) and sends them
+// in order with send. Empty data is sent as a single empty Chunk.
+func SendChunks(data []byte, send func(*Chunk) error) error {
+	return chunk.Send(data, func(offset, size int64, data []byte, crc uint32) error {
+		return send(&Chunk{Offset: offset, Size: size, Data: data, Crc32: crc})
+	})
+}
+
+// SendChunksFrom is SendChunks for the size bytes read from r
+// (see chunk.SendFrom).
+func SendChunksFrom(r io.Reader, size int64, send func(*Chunk) error) error {
+	return chunk.SendFrom(r, size, func(offset, size int64, data []byte, crc uint32) error {
+		return send(&Chunk{Offset: offset, Size: size, Data: data, Crc32: crc})
+	})
+}
+
+// ReceiveChunks receives Chunks with recv until it returns io.EOF and
+// returns the reassembled data (see chunk.Receive).
+func ReceiveChunks(recv func() (*Chunk, error)) ([]byte, error) {
+	return chunk.Receive(message(recv))
+}
+
+// ReceiveChunksTo is ReceiveChunks that writes the data to w as it
+// arrives and returns its size (see chunk.ReceiveTo).
+func ReceiveChunksTo(w io.Writer, recv func() (*Chunk, error)) (int64, error) {
+	return chunk.ReceiveTo(w, message(recv))
+}
+
+// message adapts recv to return chunk.Messages.
+func message(recv func() (*Chunk, error)) func() (chunk.Message, error) {
+	return func() (chunk.Message, error) {
+		c, err := recv()
+		if c == nil {
+			return nil, err // not a nil *Chunk in a non-nil chunk.Message
+		}
+		return c, err
+	}
+}
@@ -0,0 +1,63 @@
+package stl2svx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// MarshalMesh serializes the STL file for PutMesh and returns it with its hash.
+func MarshalMesh(stlFile *STLFile) (hash string, buf []byte, err error) {
+	if buf, err = proto.Marshal(stlFile); err != nil {
+		return "", nil, err
+	}
+	return MeshHash(buf), buf, nil
+}
+
+// MeshHash returns the hash (the hex SHA-256 digest) of a serialized STL file.
+func MeshHash(buf []byte) string {
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])
+}
+
+// SendMesh sends the serialized STL file with its hash as MeshChunks with send.
+func SendMesh(hash string, buf []byte, send func(*MeshChunk) error) error {
+	first := true
+	return SendChunks(buf, func(c *Chunk) error {
+		m := &MeshChunk{Chunk: c}
+		if first {
+			m.Hash = hash
+			first = false
+		}
+		return send(m)
+	})
+}
+
+// ReceiveMesh receives MeshChunks with recv until it returns io.EOF and
+// returns the STL file. It returns an error if the STL file does not
+// match its hash.
+func ReceiveMesh(recv func() (*MeshChunk, error)) (hash string, stlFile *STLFile, err error) {
+	buf, err := ReceiveChunks(func() (*Chunk, error) {
+		m, err := recv()
+		if err != nil {
+			return nil, err
+		}
+		if hash == "" {
+			hash = m.GetHash()
+		}
+		return m.GetChunk(), nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if got := MeshHash(buf); got != hash {
+		return "", nil, fmt.Errorf("mesh has hash %v, want %q", got, hash)
+	}
+	stlFile = &STLFile{}
+	if err := proto.Unmarshal(buf, stlFile); err != nil {
+		return "", nil, fmt.Errorf("bad mesh: %v", err)
+	}
+	return hash, stlFile, nil
+}
@@ -0,0 +1,45 @@
+package stl2svx
+
+import (
+	"io"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestMesh(t *testing.T) {
+	want := &STLFile{Triangles: []*Triangle{{V1: &Vertex{X: 1}, V2: &Vertex{Y: 2}, V3: &Vertex{Z: 3}}}}
+	hash, buf, err := MarshalMesh(want)
+	if err != nil {
+		t.Fatalf("MarshalMesh: %v", err)
+	}
+
+	var chunks []*MeshChunk
+	if err := SendMesh(hash, buf, func(c *MeshChunk) error { chunks = append(chunks, c); return nil }); err != nil {
+		t.Fatalf("SendMesh: %v", err)
+	}
+	recv := func(chunks []*MeshChunk) func() (*MeshChunk, error) {
+		return func() (*MeshChunk, error) {
+			if len(chunks) == 0 {
+				return nil, io.EOF
+			}
+			c := chunks[0]
+			chunks = chunks[1:]
+			return c, nil
+		}
+	}
+	gotHash, got, err := ReceiveMesh(recv(chunks))
+	if err != nil {
+		t.Fatalf("ReceiveMesh: %v", err)
+	}
+	if gotHash != hash || !proto.Equal(got, want) {
+		t.Errorf("ReceiveMesh = (%v, %v), want (%v, %v)", gotHash, got, hash, want)
+	}
+
+	// The mesh must match its hash.
+	bad := *chunks[0]
+	bad.Hash = MeshHash([]byte("something else"))
+	if _, _, err := ReceiveMesh(recv([]*MeshChunk{&bad})); err == nil {
+		t.Error("ReceiveMesh with wrong hash = nil error, want error")
+	}
+}
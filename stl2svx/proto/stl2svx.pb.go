@@ -1,6 +1,5 @@
-// Code generated by protoc-gen-go.
+// Code generated by protoc-gen-go. DO NOT EDIT.
 // source: stl2svx.proto
-// DO NOT EDIT!
 
 /*
 Package stl2svx is a generated protocol buffer package.
@@ -15,8 +14,23 @@ It has these top-level messages:
 	NewJobResponse
 	RegisterAgentRequest
 	RegisterAgentResponse
+	Capacity
+	HeartbeatRequest
+	HeartbeatResponse
+	DeregisterRequest
+	DeregisterResponse
 	SliceJobRequest
 	SliceJobResponse
+	SubmitJobResponse
+	GetJobStatusRequest
+	JobStatus
+	SliceStatus
+	CancelJobRequest
+	CancelJobResponse
+	FetchResultRequest
+	MeshChunk
+	PutMeshResponse
+	Chunk
 	STLFile
 	Triangle
 	Vertex
@@ -43,6 +57,59 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+// JobState is the state of a job.
+type JobState int32
+
+const (
+	JobState_JOB_RUNNING  JobState = 0
+	JobState_JOB_DONE     JobState = 1
+	JobState_JOB_FAILED   JobState = 2
+	JobState_JOB_CANCELED JobState = 3
+)
+
+var JobState_name = map[int32]string{
+	0: "JOB_RUNNING",
+	1: "JOB_DONE",
+	2: "JOB_FAILED",
+	3: "JOB_CANCELED",
+}
+var JobState_value = map[string]int32{
+	"JOB_RUNNING":  0,
+	"JOB_DONE":     1,
+	"JOB_FAILED":   2,
+	"JOB_CANCELED": 3,
+}
+
+func (x JobState) String() string {
+	return proto.EnumName(JobState_name, int32(x))
+}
+func (JobState) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+// SliceState is the state of a single slice of a job.
+type SliceState int32
+
+const (
+	SliceState_SLICE_PENDING SliceState = 0
+	SliceState_SLICE_RUNNING SliceState = 1
+	SliceState_SLICE_DONE    SliceState = 2
+)
+
+var SliceState_name = map[int32]string{
+	0: "SLICE_PENDING",
+	1: "SLICE_RUNNING",
+	2: "SLICE_DONE",
+}
+var SliceState_value = map[string]int32{
+	"SLICE_PENDING": 0,
+	"SLICE_RUNNING": 1,
+	"SLICE_DONE":    2,
+}
+
+func (x SliceState) String() string {
+	return proto.EnumName(SliceState_name, int32(x))
+}
+func (SliceState) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
 // NewJobRequest is the request to start a new job.
 type NewJobRequest struct {
 	StlFiles []*STLFile `protobuf:"bytes,1,rep,name=stl_files,json=stlFiles" json:"stl_files,omitempty"`
@@ -131,7 +198,8 @@ func (m *NewJobResponse) GetError() string {
 
 // RegisterAgentRequest is sent from each agent to the master.
 type RegisterAgentRequest struct {
-	Address string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Address  string    `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Capacity *Capacity `protobuf:"bytes,2,opt,name=capacity" json:"capacity,omitempty"`
 }
 
 func (m *RegisterAgentRequest) Reset()                    { *m = RegisterAgentRequest{} }
@@ -146,8 +214,17 @@ func (m *RegisterAgentRequest) GetAddress() string {
 	return ""
 }
 
+func (m *RegisterAgentRequest) GetCapacity() *Capacity {
+	if m != nil {
+		return m.Capacity
+	}
+	return nil
+}
+
 // RegisterAgentResponse is the reply from the master to the agent.
 type RegisterAgentResponse struct {
+	// How often the agent must send a Heartbeat to avoid eviction.
+	HeartbeatIntervalMs int64 `protobuf:"varint,1,opt,name=heartbeat_interval_ms,json=heartbeatIntervalMs" json:"heartbeat_interval_ms,omitempty"`
 }
 
 func (m *RegisterAgentResponse) Reset()                    { *m = RegisterAgentResponse{} }
@@ -155,16 +232,133 @@ func (m *RegisterAgentResponse) String() string            { return proto.Compac
 func (*RegisterAgentResponse) ProtoMessage()               {}
 func (*RegisterAgentResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
+func (m *RegisterAgentResponse) GetHeartbeatIntervalMs() int64 {
+	if m != nil {
+		return m.HeartbeatIntervalMs
+	}
+	return 0
+}
+
+// Capacity describes the resources of an agent.
+type Capacity struct {
+	NumCpu      int32 `protobuf:"varint,1,opt,name=num_cpu,json=numCpu" json:"num_cpu,omitempty"`
+	MemoryBytes int64 `protobuf:"varint,2,opt,name=memory_bytes,json=memoryBytes" json:"memory_bytes,omitempty"`
+	MaxSlices   int32 `protobuf:"varint,3,opt,name=max_slices,json=maxSlices" json:"max_slices,omitempty"`
+}
+
+func (m *Capacity) Reset()                    { *m = Capacity{} }
+func (m *Capacity) String() string            { return proto.CompactTextString(m) }
+func (*Capacity) ProtoMessage()               {}
+func (*Capacity) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *Capacity) GetNumCpu() int32 {
+	if m != nil {
+		return m.NumCpu
+	}
+	return 0
+}
+
+func (m *Capacity) GetMemoryBytes() int64 {
+	if m != nil {
+		return m.MemoryBytes
+	}
+	return 0
+}
+
+func (m *Capacity) GetMaxSlices() int32 {
+	if m != nil {
+		return m.MaxSlices
+	}
+	return 0
+}
+
+// HeartbeatRequest is sent periodically from each agent to the master.
+type HeartbeatRequest struct {
+	Address  string    `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Capacity *Capacity `protobuf:"bytes,2,opt,name=capacity" json:"capacity,omitempty"`
+}
+
+func (m *HeartbeatRequest) Reset()                    { *m = HeartbeatRequest{} }
+func (m *HeartbeatRequest) String() string            { return proto.CompactTextString(m) }
+func (*HeartbeatRequest) ProtoMessage()               {}
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *HeartbeatRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *HeartbeatRequest) GetCapacity() *Capacity {
+	if m != nil {
+		return m.Capacity
+	}
+	return nil
+}
+
+// HeartbeatResponse is the reply from the master to the agent.
+type HeartbeatResponse struct {
+	// False if the master does not know the agent (for example, because the
+	// master restarted or evicted it), in which case the agent must
+	// RegisterAgent again.
+	Registered bool `protobuf:"varint,1,opt,name=registered" json:"registered,omitempty"`
+}
+
+func (m *HeartbeatResponse) Reset()                    { *m = HeartbeatResponse{} }
+func (m *HeartbeatResponse) String() string            { return proto.CompactTextString(m) }
+func (*HeartbeatResponse) ProtoMessage()               {}
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *HeartbeatResponse) GetRegistered() bool {
+	if m != nil {
+		return m.Registered
+	}
+	return false
+}
+
+// DeregisterRequest is sent from an agent to the master when it shuts down.
+type DeregisterRequest struct {
+	Address string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+}
+
+func (m *DeregisterRequest) Reset()                    { *m = DeregisterRequest{} }
+func (m *DeregisterRequest) String() string            { return proto.CompactTextString(m) }
+func (*DeregisterRequest) ProtoMessage()               {}
+func (*DeregisterRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *DeregisterRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+// DeregisterResponse is the reply from the master to the agent.
+type DeregisterResponse struct {
+}
+
+func (m *DeregisterResponse) Reset()                    { *m = DeregisterResponse{} }
+func (m *DeregisterResponse) String() string            { return proto.CompactTextString(m) }
+func (*DeregisterResponse) ProtoMessage()               {}
+func (*DeregisterResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
 // SliceJobRequests represents an entire job slice to be performed.
 type SliceJobRequest struct {
+	// The job. If mesh_hashes is set, stl_files is empty and the agent uses
+	// the STL files previously uploaded with PutMesh instead.
 	NewJobRequest *NewJobRequest `protobuf:"bytes,1,opt,name=new_job_request,json=newJobRequest" json:"new_job_request,omitempty"`
 	Z             int64          `protobuf:"varint,2,opt,name=z" json:"z,omitempty"`
+	// The hashes of the job's STL files, in order. The agent returns a
+	// NOT_FOUND error if it does not have one of them.
+	MeshHashes []string `protobuf:"bytes,3,rep,name=mesh_hashes,json=meshHashes" json:"mesh_hashes,omitempty"`
+	ZCount     int64    `protobuf:"varint,4,opt,name=z_count,json=zCount" json:"z_count,omitempty"`
 }
 
 func (m *SliceJobRequest) Reset()                    { *m = SliceJobRequest{} }
 func (m *SliceJobRequest) String() string            { return proto.CompactTextString(m) }
 func (*SliceJobRequest) ProtoMessage()               {}
-func (*SliceJobRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*SliceJobRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
 
 func (m *SliceJobRequest) GetNewJobRequest() *NewJobRequest {
 	if m != nil {
@@ -180,16 +374,31 @@ func (m *SliceJobRequest) GetZ() int64 {
 	return 0
 }
 
+func (m *SliceJobRequest) GetMeshHashes() []string {
+	if m != nil {
+		return m.MeshHashes
+	}
+	return nil
+}
+
+func (m *SliceJobRequest) GetZCount() int64 {
+	if m != nil {
+		return m.ZCount
+	}
+	return 0
+}
+
 // SliceJobResponse is the resulting PNG file from a job slice.
 type SliceJobResponse struct {
 	PngFile []byte `protobuf:"bytes,1,opt,name=png_file,json=pngFile,proto3" json:"png_file,omitempty"`
 	Error   string `protobuf:"bytes,2,opt,name=error" json:"error,omitempty"`
+	Z       int64  `protobuf:"varint,3,opt,name=z" json:"z,omitempty"`
 }
 
 func (m *SliceJobResponse) Reset()                    { *m = SliceJobResponse{} }
 func (m *SliceJobResponse) String() string            { return proto.CompactTextString(m) }
 func (*SliceJobResponse) ProtoMessage()               {}
-func (*SliceJobResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*SliceJobResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
 
 func (m *SliceJobResponse) GetPngFile() []byte {
 	if m != nil {
@@ -205,6 +414,279 @@ func (m *SliceJobResponse) GetError() string {
 	return ""
 }
 
+func (m *SliceJobResponse) GetZ() int64 {
+	if m != nil {
+		return m.Z
+	}
+	return 0
+}
+
+// SubmitJobResponse is the reply from the master to SubmitJob.
+type SubmitJobResponse struct {
+	JobId string `protobuf:"bytes,1,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
+}
+
+func (m *SubmitJobResponse) Reset()                    { *m = SubmitJobResponse{} }
+func (m *SubmitJobResponse) String() string            { return proto.CompactTextString(m) }
+func (*SubmitJobResponse) ProtoMessage()               {}
+func (*SubmitJobResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *SubmitJobResponse) GetJobId() string {
+	if m != nil {
+		return m.JobId
+	}
+	return ""
+}
+
+// GetJobStatusRequest asks for the status of a job.
+type GetJobStatusRequest struct {
+	JobId string `protobuf:"bytes,1,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
+}
+
+func (m *GetJobStatusRequest) Reset()                    { *m = GetJobStatusRequest{} }
+func (m *GetJobStatusRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetJobStatusRequest) ProtoMessage()               {}
+func (*GetJobStatusRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *GetJobStatusRequest) GetJobId() string {
+	if m != nil {
+		return m.JobId
+	}
+	return ""
+}
+
+// JobStatus is the progress of a job.
+type JobStatus struct {
+	JobId           string         `protobuf:"bytes,1,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
+	State           JobState       `protobuf:"varint,2,opt,name=state,enum=stl2svx.JobState" json:"state,omitempty"`
+	Error           string         `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+	TotalSlices     int64          `protobuf:"varint,4,opt,name=total_slices,json=totalSlices" json:"total_slices,omitempty"`
+	CompletedSlices int64          `protobuf:"varint,5,opt,name=completed_slices,json=completedSlices" json:"completed_slices,omitempty"`
+	Slices          []*SliceStatus `protobuf:"bytes,6,rep,name=slices" json:"slices,omitempty"`
+	SvxName         string         `protobuf:"bytes,7,opt,name=svx_name,json=svxName" json:"svx_name,omitempty"`
+}
+
+func (m *JobStatus) Reset()                    { *m = JobStatus{} }
+func (m *JobStatus) String() string            { return proto.CompactTextString(m) }
+func (*JobStatus) ProtoMessage()               {}
+func (*JobStatus) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *JobStatus) GetJobId() string {
+	if m != nil {
+		return m.JobId
+	}
+	return ""
+}
+
+func (m *JobStatus) GetState() JobState {
+	if m != nil {
+		return m.State
+	}
+	return JobState_JOB_RUNNING
+}
+
+func (m *JobStatus) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *JobStatus) GetTotalSlices() int64 {
+	if m != nil {
+		return m.TotalSlices
+	}
+	return 0
+}
+
+func (m *JobStatus) GetCompletedSlices() int64 {
+	if m != nil {
+		return m.CompletedSlices
+	}
+	return 0
+}
+
+func (m *JobStatus) GetSlices() []*SliceStatus {
+	if m != nil {
+		return m.Slices
+	}
+	return nil
+}
+
+func (m *JobStatus) GetSvxName() string {
+	if m != nil {
+		return m.SvxName
+	}
+	return ""
+}
+
+// SliceStatus is the progress of a single slice of a job.
+type SliceStatus struct {
+	Z        int64      `protobuf:"varint,1,opt,name=z" json:"z,omitempty"`
+	State    SliceState `protobuf:"varint,2,opt,name=state,enum=stl2svx.SliceState" json:"state,omitempty"`
+	Attempts int32      `protobuf:"varint,3,opt,name=attempts" json:"attempts,omitempty"`
+	Agent    string     `protobuf:"bytes,4,opt,name=agent" json:"agent,omitempty"`
+	Error    string     `protobuf:"bytes,5,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *SliceStatus) Reset()                    { *m = SliceStatus{} }
+func (m *SliceStatus) String() string            { return proto.CompactTextString(m) }
+func (*SliceStatus) ProtoMessage()               {}
+func (*SliceStatus) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *SliceStatus) GetZ() int64 {
+	if m != nil {
+		return m.Z
+	}
+	return 0
+}
+
+func (m *SliceStatus) GetState() SliceState {
+	if m != nil {
+		return m.State
+	}
+	return SliceState_SLICE_PENDING
+}
+
+func (m *SliceStatus) GetAttempts() int32 {
+	if m != nil {
+		return m.Attempts
+	}
+	return 0
+}
+
+func (m *SliceStatus) GetAgent() string {
+	if m != nil {
+		return m.Agent
+	}
+	return ""
+}
+
+func (m *SliceStatus) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+// CancelJobRequest asks the master to stop a job.
+type CancelJobRequest struct {
+	JobId string `protobuf:"bytes,1,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
+}
+
+func (m *CancelJobRequest) Reset()                    { *m = CancelJobRequest{} }
+func (m *CancelJobRequest) String() string            { return proto.CompactTextString(m) }
+func (*CancelJobRequest) ProtoMessage()               {}
+func (*CancelJobRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *CancelJobRequest) GetJobId() string {
+	if m != nil {
+		return m.JobId
+	}
+	return ""
+}
+
+// CancelJobResponse is the reply from the master to CancelJob.
+type CancelJobResponse struct {
+}
+
+func (m *CancelJobResponse) Reset()                    { *m = CancelJobResponse{} }
+func (m *CancelJobResponse) String() string            { return proto.CompactTextString(m) }
+func (*CancelJobResponse) ProtoMessage()               {}
+func (*CancelJobResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+// FetchResultRequest asks for the SVX file of a completed job.
+type FetchResultRequest struct {
+	JobId string `protobuf:"bytes,1,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
+}
+
+func (m *FetchResultRequest) Reset()                    { *m = FetchResultRequest{} }
+func (m *FetchResultRequest) String() string            { return proto.CompactTextString(m) }
+func (*FetchResultRequest) ProtoMessage()               {}
+func (*FetchResultRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *FetchResultRequest) GetJobId() string {
+	if m != nil {
+		return m.JobId
+	}
+	return ""
+}
+
+// MeshChunk is a piece of an STL file uploaded with PutMesh.
+type MeshChunk struct {
+	Hash  string `protobuf:"bytes,1,opt,name=hash" json:"hash,omitempty"`
+	Chunk *Chunk `protobuf:"bytes,2,opt,name=chunk" json:"chunk,omitempty"`
+}
+
+func (m *MeshChunk) Reset()                    { *m = MeshChunk{} }
+func (m *MeshChunk) String() string            { return proto.CompactTextString(m) }
+func (*MeshChunk) ProtoMessage()               {}
+func (*MeshChunk) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *MeshChunk) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+func (m *MeshChunk) GetChunk() *Chunk {
+	if m != nil {
+		return m.Chunk
+	}
+	return nil
+}
+
+// PutMeshResponse is the reply from the agent to PutMesh.
+type PutMeshResponse struct {
+}
+
+func (m *PutMeshResponse) Reset()                    { *m = PutMeshResponse{} }
+func (m *PutMeshResponse) String() string            { return proto.CompactTextString(m) }
+func (*PutMeshResponse) ProtoMessage()               {}
+func (*PutMeshResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+// Chunk is a piece of a file (or serialized message) sent over a stream.
+type Chunk struct {
+	Offset int64  `protobuf:"varint,1,opt,name=offset" json:"offset,omitempty"`
+	Size   int64  `protobuf:"varint,2,opt,name=size" json:"size,omitempty"`
+	Data   []byte `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Crc32  uint32 `protobuf:"varint,4,opt,name=crc32" json:"crc32,omitempty"`
+}
+
+func (m *Chunk) Reset()                    { *m = Chunk{} }
+func (m *Chunk) String() string            { return proto.CompactTextString(m) }
+func (*Chunk) ProtoMessage()               {}
+func (*Chunk) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *Chunk) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *Chunk) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *Chunk) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *Chunk) GetCrc32() uint32 {
+	if m != nil {
+		return m.Crc32
+	}
+	return 0
+}
+
 // STLFile contains the data in an STL file.
 type STLFile struct {
 	Triangles []*Triangle `protobuf:"bytes,1,rep,name=triangles" json:"triangles,omitempty"`
@@ -213,7 +695,7 @@ type STLFile struct {
 func (m *STLFile) Reset()                    { *m = STLFile{} }
 func (m *STLFile) String() string            { return proto.CompactTextString(m) }
 func (*STLFile) ProtoMessage()               {}
-func (*STLFile) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*STLFile) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
 
 func (m *STLFile) GetTriangles() []*Triangle {
 	if m != nil {
@@ -232,7 +714,7 @@ type Triangle struct {
 func (m *Triangle) Reset()                    { *m = Triangle{} }
 func (m *Triangle) String() string            { return proto.CompactTextString(m) }
 func (*Triangle) ProtoMessage()               {}
-func (*Triangle) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+func (*Triangle) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
 
 func (m *Triangle) GetV1() *Vertex {
 	if m != nil {
@@ -265,7 +747,7 @@ type Vertex struct {
 func (m *Vertex) Reset()                    { *m = Vertex{} }
 func (m *Vertex) String() string            { return proto.CompactTextString(m) }
 func (*Vertex) ProtoMessage()               {}
-func (*Vertex) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+func (*Vertex) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
 
 func (m *Vertex) GetX() float64 {
 	if m != nil {
@@ -293,11 +775,28 @@ func init() {
 	proto.RegisterType((*NewJobResponse)(nil), "stl2svx.NewJobResponse")
 	proto.RegisterType((*RegisterAgentRequest)(nil), "stl2svx.RegisterAgentRequest")
 	proto.RegisterType((*RegisterAgentResponse)(nil), "stl2svx.RegisterAgentResponse")
+	proto.RegisterType((*Capacity)(nil), "stl2svx.Capacity")
+	proto.RegisterType((*HeartbeatRequest)(nil), "stl2svx.HeartbeatRequest")
+	proto.RegisterType((*HeartbeatResponse)(nil), "stl2svx.HeartbeatResponse")
+	proto.RegisterType((*DeregisterRequest)(nil), "stl2svx.DeregisterRequest")
+	proto.RegisterType((*DeregisterResponse)(nil), "stl2svx.DeregisterResponse")
 	proto.RegisterType((*SliceJobRequest)(nil), "stl2svx.SliceJobRequest")
 	proto.RegisterType((*SliceJobResponse)(nil), "stl2svx.SliceJobResponse")
+	proto.RegisterType((*SubmitJobResponse)(nil), "stl2svx.SubmitJobResponse")
+	proto.RegisterType((*GetJobStatusRequest)(nil), "stl2svx.GetJobStatusRequest")
+	proto.RegisterType((*JobStatus)(nil), "stl2svx.JobStatus")
+	proto.RegisterType((*SliceStatus)(nil), "stl2svx.SliceStatus")
+	proto.RegisterType((*CancelJobRequest)(nil), "stl2svx.CancelJobRequest")
+	proto.RegisterType((*CancelJobResponse)(nil), "stl2svx.CancelJobResponse")
+	proto.RegisterType((*FetchResultRequest)(nil), "stl2svx.FetchResultRequest")
+	proto.RegisterType((*MeshChunk)(nil), "stl2svx.MeshChunk")
+	proto.RegisterType((*PutMeshResponse)(nil), "stl2svx.PutMeshResponse")
+	proto.RegisterType((*Chunk)(nil), "stl2svx.Chunk")
 	proto.RegisterType((*STLFile)(nil), "stl2svx.STLFile")
 	proto.RegisterType((*Triangle)(nil), "stl2svx.Triangle")
 	proto.RegisterType((*Vertex)(nil), "stl2svx.Vertex")
+	proto.RegisterEnum("stl2svx.JobState", JobState_name, JobState_value)
+	proto.RegisterEnum("stl2svx.SliceState", SliceState_name, SliceState_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -315,6 +814,28 @@ type MasterClient interface {
 	NewJob(ctx context.Context, in *NewJobRequest, opts ...grpc.CallOption) (*NewJobResponse, error)
 	// RegisterAgent notifies the master that a new agent is ready.
 	RegisterAgent(ctx context.Context, in *RegisterAgentRequest, opts ...grpc.CallOption) (*RegisterAgentResponse, error)
+	// NewJobStream is NewJob without the limit of the maximum gRPC message
+	// size: the client streams the serialized NewJobRequest in chunks and
+	// the master streams the resulting SVX file back in chunks. The request
+	// is held in memory (it is parsed as a whole), but the SVX file is
+	// written to and sent from the master's job directory (if any) without
+	// holding it in memory.
+	NewJobStream(ctx context.Context, opts ...grpc.CallOption) (Master_NewJobStreamClient, error)
+	// Heartbeat is sent periodically by each registered agent with its
+	// current capacity. Agents that stop sending heartbeats are evicted.
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+	// Deregister notifies the master that an agent is shutting down.
+	Deregister(ctx context.Context, in *DeregisterRequest, opts ...grpc.CallOption) (*DeregisterResponse, error)
+	// SubmitJob starts a new job in the background and returns its ID.
+	// The client streams the serialized NewJobRequest in chunks.
+	SubmitJob(ctx context.Context, opts ...grpc.CallOption) (Master_SubmitJobClient, error)
+	// GetJobStatus returns the progress of a job.
+	GetJobStatus(ctx context.Context, in *GetJobStatusRequest, opts ...grpc.CallOption) (*JobStatus, error)
+	// CancelJob stops a running job.
+	CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*CancelJobResponse, error)
+	// FetchResult streams the SVX file of a completed job in chunks, reading
+	// it from the master's job directory (if any) as it is sent.
+	FetchResult(ctx context.Context, in *FetchResultRequest, opts ...grpc.CallOption) (Master_FetchResultClient, error)
 }
 
 type masterClient struct {
@@ -343,6 +864,139 @@ func (c *masterClient) RegisterAgent(ctx context.Context, in *RegisterAgentReque
 	return out, nil
 }
 
+func (c *masterClient) NewJobStream(ctx context.Context, opts ...grpc.CallOption) (Master_NewJobStreamClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Master_serviceDesc.Streams[0], c.cc, "/stl2svx.Master/NewJobStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &masterNewJobStreamClient{stream}
+	return x, nil
+}
+
+type Master_NewJobStreamClient interface {
+	Send(*Chunk) error
+	Recv() (*Chunk, error)
+	grpc.ClientStream
+}
+
+type masterNewJobStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *masterNewJobStreamClient) Send(m *Chunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *masterNewJobStreamClient) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *masterClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	out := new(HeartbeatResponse)
+	err := grpc.Invoke(ctx, "/stl2svx.Master/Heartbeat", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) Deregister(ctx context.Context, in *DeregisterRequest, opts ...grpc.CallOption) (*DeregisterResponse, error) {
+	out := new(DeregisterResponse)
+	err := grpc.Invoke(ctx, "/stl2svx.Master/Deregister", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) SubmitJob(ctx context.Context, opts ...grpc.CallOption) (Master_SubmitJobClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Master_serviceDesc.Streams[1], c.cc, "/stl2svx.Master/SubmitJob", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &masterSubmitJobClient{stream}
+	return x, nil
+}
+
+type Master_SubmitJobClient interface {
+	Send(*Chunk) error
+	CloseAndRecv() (*SubmitJobResponse, error)
+	grpc.ClientStream
+}
+
+type masterSubmitJobClient struct {
+	grpc.ClientStream
+}
+
+func (x *masterSubmitJobClient) Send(m *Chunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *masterSubmitJobClient) CloseAndRecv() (*SubmitJobResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(SubmitJobResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *masterClient) GetJobStatus(ctx context.Context, in *GetJobStatusRequest, opts ...grpc.CallOption) (*JobStatus, error) {
+	out := new(JobStatus)
+	err := grpc.Invoke(ctx, "/stl2svx.Master/GetJobStatus", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*CancelJobResponse, error) {
+	out := new(CancelJobResponse)
+	err := grpc.Invoke(ctx, "/stl2svx.Master/CancelJob", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) FetchResult(ctx context.Context, in *FetchResultRequest, opts ...grpc.CallOption) (Master_FetchResultClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Master_serviceDesc.Streams[2], c.cc, "/stl2svx.Master/FetchResult", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &masterFetchResultClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Master_FetchResultClient interface {
+	Recv() (*Chunk, error)
+	grpc.ClientStream
+}
+
+type masterFetchResultClient struct {
+	grpc.ClientStream
+}
+
+func (x *masterFetchResultClient) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Server API for Master service
 
 type MasterServer interface {
@@ -350,6 +1004,28 @@ type MasterServer interface {
 	NewJob(context.Context, *NewJobRequest) (*NewJobResponse, error)
 	// RegisterAgent notifies the master that a new agent is ready.
 	RegisterAgent(context.Context, *RegisterAgentRequest) (*RegisterAgentResponse, error)
+	// NewJobStream is NewJob without the limit of the maximum gRPC message
+	// size: the client streams the serialized NewJobRequest in chunks and
+	// the master streams the resulting SVX file back in chunks. The request
+	// is held in memory (it is parsed as a whole), but the SVX file is
+	// written to and sent from the master's job directory (if any) without
+	// holding it in memory.
+	NewJobStream(Master_NewJobStreamServer) error
+	// Heartbeat is sent periodically by each registered agent with its
+	// current capacity. Agents that stop sending heartbeats are evicted.
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
+	// Deregister notifies the master that an agent is shutting down.
+	Deregister(context.Context, *DeregisterRequest) (*DeregisterResponse, error)
+	// SubmitJob starts a new job in the background and returns its ID.
+	// The client streams the serialized NewJobRequest in chunks.
+	SubmitJob(Master_SubmitJobServer) error
+	// GetJobStatus returns the progress of a job.
+	GetJobStatus(context.Context, *GetJobStatusRequest) (*JobStatus, error)
+	// CancelJob stops a running job.
+	CancelJob(context.Context, *CancelJobRequest) (*CancelJobResponse, error)
+	// FetchResult streams the SVX file of a completed job in chunks, reading
+	// it from the master's job directory (if any) as it is sent.
+	FetchResult(*FetchResultRequest, Master_FetchResultServer) error
 }
 
 func RegisterMasterServer(s *grpc.Server, srv MasterServer) {
@@ -392,6 +1068,151 @@ func _Master_RegisterAgent_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Master_NewJobStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MasterServer).NewJobStream(&masterNewJobStreamServer{stream})
+}
+
+type Master_NewJobStreamServer interface {
+	Send(*Chunk) error
+	Recv() (*Chunk, error)
+	grpc.ServerStream
+}
+
+type masterNewJobStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *masterNewJobStreamServer) Send(m *Chunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *masterNewJobStreamServer) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Master_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stl2svx.Master/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_Deregister_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeregisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).Deregister(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stl2svx.Master/Deregister",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).Deregister(ctx, req.(*DeregisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_SubmitJob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MasterServer).SubmitJob(&masterSubmitJobServer{stream})
+}
+
+type Master_SubmitJobServer interface {
+	SendAndClose(*SubmitJobResponse) error
+	Recv() (*Chunk, error)
+	grpc.ServerStream
+}
+
+type masterSubmitJobServer struct {
+	grpc.ServerStream
+}
+
+func (x *masterSubmitJobServer) SendAndClose(m *SubmitJobResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *masterSubmitJobServer) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Master_GetJobStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).GetJobStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stl2svx.Master/GetJobStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).GetJobStatus(ctx, req.(*GetJobStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_CancelJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).CancelJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stl2svx.Master/CancelJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).CancelJob(ctx, req.(*CancelJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_FetchResult_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FetchResultRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MasterServer).FetchResult(m, &masterFetchResultServer{stream})
+}
+
+type Master_FetchResultServer interface {
+	Send(*Chunk) error
+	grpc.ServerStream
+}
+
+type masterFetchResultServer struct {
+	grpc.ServerStream
+}
+
+func (x *masterFetchResultServer) Send(m *Chunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _Master_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "stl2svx.Master",
 	HandlerType: (*MasterServer)(nil),
@@ -404,8 +1225,41 @@ var _Master_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RegisterAgent",
 			Handler:    _Master_RegisterAgent_Handler,
 		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _Master_Heartbeat_Handler,
+		},
+		{
+			MethodName: "Deregister",
+			Handler:    _Master_Deregister_Handler,
+		},
+		{
+			MethodName: "GetJobStatus",
+			Handler:    _Master_GetJobStatus_Handler,
+		},
+		{
+			MethodName: "CancelJob",
+			Handler:    _Master_CancelJob_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "NewJobStream",
+			Handler:       _Master_NewJobStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "SubmitJob",
+			Handler:       _Master_SubmitJob_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "FetchResult",
+			Handler:       _Master_FetchResult_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "stl2svx.proto",
 }
 
@@ -414,6 +1268,13 @@ var _Master_serviceDesc = grpc.ServiceDesc{
 type AgentClient interface {
 	// SliceJob slices an entire job at the given Z height and returns the image.
 	SliceJob(ctx context.Context, in *SliceJobRequest, opts ...grpc.CallOption) (*SliceJobResponse, error)
+	// SliceRange slices the job at the z_count Z heights starting at z and
+	// streams back one image per slice, in order.
+	SliceRange(ctx context.Context, in *SliceJobRequest, opts ...grpc.CallOption) (Agent_SliceRangeClient, error)
+	// PutMesh uploads an STL file (a serialized STLFile streamed in chunks)
+	// that the agent caches by its hash. Slice requests then refer to the
+	// STL file by its hash instead of including it.
+	PutMesh(ctx context.Context, opts ...grpc.CallOption) (Agent_PutMeshClient, error)
 }
 
 type agentClient struct {
@@ -433,11 +1294,84 @@ func (c *agentClient) SliceJob(ctx context.Context, in *SliceJobRequest, opts ..
 	return out, nil
 }
 
+func (c *agentClient) SliceRange(ctx context.Context, in *SliceJobRequest, opts ...grpc.CallOption) (Agent_SliceRangeClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Agent_serviceDesc.Streams[0], c.cc, "/stl2svx.Agent/SliceRange", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentSliceRangeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Agent_SliceRangeClient interface {
+	Recv() (*SliceJobResponse, error)
+	grpc.ClientStream
+}
+
+type agentSliceRangeClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentSliceRangeClient) Recv() (*SliceJobResponse, error) {
+	m := new(SliceJobResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentClient) PutMesh(ctx context.Context, opts ...grpc.CallOption) (Agent_PutMeshClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Agent_serviceDesc.Streams[1], c.cc, "/stl2svx.Agent/PutMesh", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentPutMeshClient{stream}
+	return x, nil
+}
+
+type Agent_PutMeshClient interface {
+	Send(*MeshChunk) error
+	CloseAndRecv() (*PutMeshResponse, error)
+	grpc.ClientStream
+}
+
+type agentPutMeshClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentPutMeshClient) Send(m *MeshChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *agentPutMeshClient) CloseAndRecv() (*PutMeshResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PutMeshResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Server API for Agent service
 
 type AgentServer interface {
 	// SliceJob slices an entire job at the given Z height and returns the image.
 	SliceJob(context.Context, *SliceJobRequest) (*SliceJobResponse, error)
+	// SliceRange slices the job at the z_count Z heights starting at z and
+	// streams back one image per slice, in order.
+	SliceRange(*SliceJobRequest, Agent_SliceRangeServer) error
+	// PutMesh uploads an STL file (a serialized STLFile streamed in chunks)
+	// that the agent caches by its hash. Slice requests then refer to the
+	// STL file by its hash instead of including it.
+	PutMesh(Agent_PutMeshServer) error
 }
 
 func RegisterAgentServer(s *grpc.Server, srv AgentServer) {
@@ -462,6 +1396,53 @@ func _Agent_SliceJob_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Agent_SliceRange_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SliceJobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServer).SliceRange(m, &agentSliceRangeServer{stream})
+}
+
+type Agent_SliceRangeServer interface {
+	Send(*SliceJobResponse) error
+	grpc.ServerStream
+}
+
+type agentSliceRangeServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentSliceRangeServer) Send(m *SliceJobResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Agent_PutMesh_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AgentServer).PutMesh(&agentPutMeshServer{stream})
+}
+
+type Agent_PutMeshServer interface {
+	SendAndClose(*PutMeshResponse) error
+	Recv() (*MeshChunk, error)
+	grpc.ServerStream
+}
+
+type agentPutMeshServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentPutMeshServer) SendAndClose(m *PutMeshResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *agentPutMeshServer) Recv() (*MeshChunk, error) {
+	m := new(MeshChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _Agent_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "stl2svx.Agent",
 	HandlerType: (*AgentServer)(nil),
@@ -471,42 +1452,100 @@ var _Agent_serviceDesc = grpc.ServiceDesc{
 			Handler:    _Agent_SliceJob_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SliceRange",
+			Handler:       _Agent_SliceRange_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "PutMesh",
+			Handler:       _Agent_PutMesh_Handler,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "stl2svx.proto",
 }
 
 func init() { proto.RegisterFile("stl2svx.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 476 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x53, 0x41, 0x6f, 0xd3, 0x30,
-	0x14, 0xc6, 0x0d, 0x4d, 0x9b, 0xb7, 0x95, 0x16, 0x6b, 0x30, 0xaf, 0xd2, 0xa0, 0xca, 0xa9, 0x17,
-	0x06, 0x4b, 0x6f, 0x20, 0x21, 0x55, 0x48, 0x1c, 0x26, 0x40, 0x93, 0x37, 0x21, 0xe0, 0x12, 0xb5,
-	0xd4, 0x8b, 0x82, 0x32, 0x27, 0xd8, 0x6e, 0x96, 0xf6, 0x8f, 0x70, 0xe7, 0x97, 0xa2, 0xd8, 0x71,
-	0x43, 0x20, 0xdd, 0xcd, 0xef, 0xf3, 0xf3, 0xf7, 0xde, 0xfb, 0xbe, 0x67, 0x18, 0x48, 0x95, 0x04,
-	0x32, 0x2f, 0xce, 0x32, 0x91, 0xaa, 0x14, 0xf7, 0xaa, 0xd0, 0xff, 0x8d, 0x60, 0xf0, 0x89, 0xdd,
-	0x5d, 0xa4, 0x4b, 0xca, 0x7e, 0xae, 0x99, 0x54, 0xf8, 0x05, 0x78, 0x52, 0x25, 0xe1, 0x4d, 0x9c,
-	0x30, 0x49, 0xd0, 0xc4, 0x99, 0x1e, 0x04, 0xa3, 0x33, 0xfb, 0xfa, 0xea, 0xfa, 0xc3, 0xfb, 0x38,
-	0x61, 0xb4, 0x2f, 0x55, 0x52, 0x1e, 0x24, 0x1e, 0x81, 0xb3, 0x8a, 0x6f, 0x49, 0x67, 0x82, 0xa6,
-	0x0e, 0x2d, 0x8f, 0x78, 0x08, 0x0e, 0x0f, 0x0b, 0xe2, 0x68, 0xa4, 0xc3, 0xbf, 0x18, 0x60, 0x43,
-	0x1e, 0x56, 0xc0, 0x57, 0x03, 0x6c, 0x49, 0xb7, 0x02, 0xbe, 0xe1, 0x53, 0x80, 0x74, 0xad, 0xc2,
-	0x4c, 0xb0, 0x9b, 0xb8, 0x20, 0xee, 0x04, 0x4d, 0x3d, 0xea, 0xa5, 0x6b, 0x75, 0xa9, 0x01, 0x7f,
-	0x0e, 0x8f, 0x6c, 0x8f, 0x32, 0x4b, 0xb9, 0x64, 0xf8, 0x04, 0xfa, 0x32, 0x2f, 0x74, 0x93, 0x04,
-	0x4d, 0xd0, 0xf4, 0x90, 0xf6, 0x64, 0x5e, 0x94, 0x1d, 0xe1, 0x23, 0xe8, 0x32, 0x21, 0x52, 0xa1,
-	0x5b, 0xf2, 0xa8, 0x09, 0xfc, 0x57, 0x70, 0x44, 0x59, 0x14, 0x4b, 0xc5, 0xc4, 0x3c, 0x62, 0x5c,
-	0xd9, 0x69, 0x09, 0xf4, 0x16, 0xab, 0x95, 0x60, 0x52, 0x6a, 0x1e, 0x8f, 0xda, 0xd0, 0x3f, 0x86,
-	0x27, 0xff, 0xbc, 0x30, 0xb5, 0xfd, 0x10, 0x86, 0x57, 0x49, 0xfc, 0x9d, 0xfd, 0xa5, 0xd9, 0x5b,
-	0x18, 0x72, 0x76, 0x17, 0xfe, 0x48, 0x97, 0xa1, 0x30, 0x90, 0x66, 0x3b, 0x08, 0x9e, 0xee, 0x94,
-	0x6b, 0x88, 0x4c, 0x07, 0xbc, 0xa1, 0xf9, 0x21, 0xa0, 0x6d, 0x25, 0x21, 0xda, 0xfa, 0xef, 0x60,
-	0x54, 0x17, 0xa8, 0x07, 0xce, 0x78, 0xd4, 0x18, 0x38, 0xe3, 0xd1, 0x3d, 0x03, 0xbf, 0x86, 0x5e,
-	0x65, 0x16, 0x7e, 0x09, 0x9e, 0x12, 0xf1, 0x82, 0x47, 0xb5, 0xa3, 0x8f, 0x77, 0x7d, 0x5d, 0x57,
-	0x37, 0xb4, 0xce, 0xf1, 0x6f, 0xa1, 0x6f, 0x61, 0xfc, 0x1c, 0x3a, 0xf9, 0x79, 0x35, 0xcd, 0x70,
-	0xf7, 0xea, 0x33, 0x13, 0x8a, 0x15, 0xb4, 0x93, 0x9f, 0xeb, 0x84, 0x40, 0xd7, 0x6e, 0x4d, 0x08,
-	0x74, 0xc2, 0x4c, 0xaf, 0x43, 0x6b, 0xc2, 0xcc, 0x0f, 0xc0, 0x35, 0x51, 0xa9, 0x43, 0xa1, 0x6b,
-	0x21, 0x8a, 0x74, 0xb4, 0xd1, 0xc4, 0x88, 0xa2, 0x8d, 0xd1, 0xc8, 0x31, 0xd1, 0x36, 0xf8, 0x85,
-	0xc0, 0xfd, 0xb8, 0x28, 0xcd, 0xc1, 0x6f, 0xc0, 0x35, 0xe2, 0xe2, 0x3d, 0x6a, 0x8f, 0x8f, 0xff,
-	0xc3, 0x2b, 0x2b, 0x1f, 0xe0, 0x4b, 0x18, 0x34, 0x5c, 0xc6, 0xa7, 0xbb, 0xdc, 0xb6, 0x7d, 0x19,
-	0x3f, 0xdb, 0x77, 0x6d, 0x19, 0x83, 0x0b, 0xe8, 0x1a, 0xa6, 0x39, 0xf4, 0xad, 0x8d, 0x98, 0xd4,
-	0x3f, 0xa8, 0xb9, 0x3a, 0xe3, 0x93, 0x96, 0x1b, 0xcb, 0xb5, 0x74, 0xf5, 0x6f, 0x9d, 0xfd, 0x09,
-	0x00, 0x00, 0xff, 0xff, 0x75, 0x4f, 0xc9, 0x82, 0xbe, 0x03, 0x00, 0x00,
+	// 1222 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x5b, 0x6f, 0x1a, 0x47,
+	0x14, 0xf6, 0x80, 0xb9, 0xec, 0x01, 0x0c, 0x8c, 0x9d, 0x84, 0xd0, 0x26, 0x71, 0x56, 0x95, 0x4a,
+	0xd2, 0x24, 0x4d, 0x70, 0x9f, 0x1a, 0x29, 0x92, 0x8d, 0x49, 0xe2, 0x5c, 0x88, 0x3b, 0x4e, 0xab,
+	0xb6, 0x52, 0xb5, 0x5a, 0x96, 0xb1, 0xd9, 0x94, 0xdd, 0xa5, 0x3b, 0xb3, 0x04, 0xf3, 0x23, 0xfa,
+	0xde, 0xd7, 0xfe, 0xa0, 0xfe, 0x89, 0xfe, 0x89, 0x3e, 0x56, 0x73, 0xd9, 0x8b, 0xb9, 0x28, 0x52,
+	0xd5, 0x37, 0xce, 0x77, 0xbe, 0x3d, 0xb7, 0x39, 0xf3, 0x0d, 0x50, 0x63, 0x7c, 0xd2, 0x65, 0xb3,
+	0xf9, 0xa3, 0x69, 0x18, 0xf0, 0x00, 0x97, 0xb4, 0x69, 0xfe, 0x89, 0xa0, 0x36, 0xa0, 0x1f, 0x5f,
+	0x05, 0x43, 0x42, 0x7f, 0x8b, 0x28, 0xe3, 0xf8, 0x21, 0x18, 0x8c, 0x4f, 0xac, 0x73, 0x77, 0x42,
+	0x59, 0x0b, 0xed, 0xe7, 0x3b, 0x95, 0x6e, 0xe3, 0x51, 0xfc, 0xf5, 0xd9, 0xfb, 0x37, 0xcf, 0xdd,
+	0x09, 0x25, 0x65, 0xc6, 0x27, 0xe2, 0x07, 0xc3, 0x0d, 0xc8, 0x8f, 0x5c, 0xaf, 0x95, 0xdb, 0x47,
+	0x9d, 0x3c, 0x11, 0x3f, 0x71, 0x1d, 0xf2, 0xbe, 0x35, 0x6f, 0xe5, 0x25, 0x92, 0xf3, 0x7f, 0x54,
+	0xc0, 0x65, 0x6b, 0x5b, 0x03, 0x3f, 0x29, 0x60, 0xd1, 0x2a, 0x68, 0xe0, 0x67, 0x7c, 0x0b, 0x20,
+	0x88, 0xb8, 0x35, 0x0d, 0xe9, 0xb9, 0x3b, 0x6f, 0x15, 0xf7, 0x51, 0xc7, 0x20, 0x46, 0x10, 0xf1,
+	0x53, 0x09, 0x98, 0x87, 0xb0, 0x13, 0xd7, 0xc8, 0xa6, 0x81, 0xcf, 0x28, 0xbe, 0x09, 0x65, 0x36,
+	0x9b, 0xcb, 0x22, 0x5b, 0x68, 0x1f, 0x75, 0xaa, 0xa4, 0xc4, 0x66, 0x73, 0x51, 0x11, 0xde, 0x83,
+	0x02, 0x0d, 0xc3, 0x20, 0x94, 0x25, 0x19, 0x44, 0x19, 0xa6, 0x05, 0x7b, 0x84, 0x5e, 0xb8, 0x8c,
+	0xd3, 0xf0, 0xf0, 0x82, 0xfa, 0x3c, 0xee, 0xb6, 0x05, 0x25, 0x7b, 0x34, 0x0a, 0x29, 0x63, 0x32,
+	0x8e, 0x41, 0x62, 0x13, 0x3f, 0x84, 0xb2, 0x63, 0x4f, 0x6d, 0xc7, 0xe5, 0x97, 0x32, 0x54, 0xa5,
+	0xdb, 0x4c, 0xc6, 0xd0, 0xd3, 0x0e, 0x92, 0x50, 0xcc, 0xd7, 0x70, 0x6d, 0x29, 0x81, 0x2e, 0xb5,
+	0x0b, 0xd7, 0xc6, 0xd4, 0x0e, 0xf9, 0x90, 0xda, 0xdc, 0x72, 0x7d, 0x4e, 0xc3, 0x99, 0x3d, 0xb1,
+	0x3c, 0x95, 0x2f, 0x4f, 0x76, 0x13, 0xe7, 0x89, 0xf6, 0xbd, 0x65, 0x26, 0x85, 0x72, 0x9c, 0x02,
+	0xdf, 0x80, 0x92, 0x1f, 0x79, 0x96, 0x33, 0x8d, 0xe4, 0x17, 0x05, 0x52, 0xf4, 0x23, 0xaf, 0x37,
+	0x8d, 0xf0, 0x5d, 0xa8, 0x7a, 0xd4, 0x0b, 0xc2, 0x4b, 0x6b, 0x78, 0xc9, 0x29, 0xd3, 0x47, 0x50,
+	0x51, 0xd8, 0x91, 0x80, 0xc4, 0x5c, 0x3d, 0x7b, 0x6e, 0xb1, 0x89, 0xeb, 0x50, 0x26, 0x4f, 0xa4,
+	0x40, 0x0c, 0xcf, 0x9e, 0x9f, 0x49, 0xc0, 0x9c, 0x42, 0xe3, 0x65, 0x9c, 0xfd, 0xff, 0x1e, 0xc8,
+	0xab, 0xed, 0x72, 0xbe, 0xb1, 0x4d, 0x6a, 0xb6, 0xc3, 0xdd, 0x19, 0xd5, 0x25, 0x98, 0x07, 0xd0,
+	0xcc, 0x64, 0xd4, 0x13, 0xba, 0x0d, 0x10, 0xea, 0xd1, 0xd1, 0x91, 0xcc, 0x5a, 0x26, 0x19, 0xc4,
+	0x7c, 0x08, 0xcd, 0x63, 0x1a, 0xdb, 0x9f, 0xac, 0xd3, 0xdc, 0x03, 0x9c, 0xa5, 0xab, 0x24, 0xe6,
+	0x1f, 0x08, 0xea, 0xb2, 0xed, 0xcc, 0xaa, 0x3f, 0x83, 0xba, 0x4f, 0x3f, 0x5a, 0x1f, 0x82, 0xa1,
+	0x15, 0x2a, 0x48, 0xc6, 0xaa, 0x74, 0xaf, 0x27, 0x8d, 0x5d, 0xb9, 0x1b, 0xa4, 0xe6, 0x67, 0x4d,
+	0x5c, 0x05, 0xb4, 0xd0, 0x63, 0x47, 0x0b, 0x7c, 0x07, 0x2a, 0x1e, 0x65, 0x63, 0x6b, 0x6c, 0xb3,
+	0xb1, 0x9c, 0x76, 0xbe, 0x63, 0x10, 0x10, 0xd0, 0x4b, 0x89, 0x88, 0x93, 0x5c, 0x58, 0x4e, 0x10,
+	0xf9, 0x5c, 0xdf, 0x85, 0xe2, 0xa2, 0x27, 0x2c, 0xf3, 0x3b, 0x68, 0xa4, 0xa5, 0xa5, 0x1b, 0x3e,
+	0xf5, 0x2f, 0xae, 0x6c, 0xf8, 0xd4, 0xbf, 0xd8, 0xbc, 0xe1, 0xaa, 0x98, 0xbc, 0x2e, 0xc6, 0xbc,
+	0x0f, 0xcd, 0xb3, 0x68, 0xe8, 0xb9, 0x3c, 0x1b, 0xf3, 0x1a, 0x14, 0x45, 0xaf, 0xee, 0x48, 0x8f,
+	0xac, 0xf0, 0x21, 0x18, 0x9e, 0x8c, 0xcc, 0x07, 0xb0, 0xfb, 0x82, 0x0a, 0xe2, 0x19, 0xb7, 0x79,
+	0xc4, 0xe2, 0xee, 0x36, 0xb0, 0xff, 0x41, 0x60, 0x24, 0xdc, 0x0d, 0x24, 0xfc, 0x25, 0x14, 0x18,
+	0xb7, 0x39, 0x95, 0x25, 0xee, 0x64, 0x16, 0x45, 0x7f, 0x49, 0x89, 0xf2, 0xa7, 0xbd, 0xe4, 0xb3,
+	0xbd, 0xdc, 0x85, 0x2a, 0x0f, 0xb8, 0x3d, 0x89, 0x37, 0x57, 0x8d, 0xab, 0x22, 0x31, 0xb5, 0xbb,
+	0xf8, 0x1e, 0x34, 0x9c, 0xc0, 0x9b, 0x4e, 0x28, 0xa7, 0xa3, 0x98, 0xa6, 0x04, 0xa5, 0x9e, 0xe0,
+	0x9a, 0xfa, 0x00, 0x8a, 0x9a, 0x50, 0x94, 0x72, 0xb6, 0x97, 0xca, 0x99, 0x80, 0x75, 0xd7, 0x9a,
+	0x13, 0x4b, 0x8b, 0x6f, 0x7b, 0xb4, 0x55, 0x52, 0x9b, 0xc5, 0x66, 0xf3, 0x81, 0xed, 0x51, 0xf3,
+	0x77, 0x04, 0x95, 0xcc, 0x27, 0x6a, 0xe4, 0x28, 0x3e, 0xff, 0x7b, 0x57, 0x7b, 0xde, 0x5d, 0xcd,
+	0x92, 0x74, 0xdd, 0x86, 0xb2, 0xcd, 0x39, 0xf5, 0xa6, 0x3c, 0xbe, 0x95, 0x89, 0x2d, 0x26, 0x62,
+	0x0b, 0x01, 0x91, 0x4d, 0x1b, 0x44, 0x19, 0xe9, 0x9c, 0x0a, 0x59, 0x55, 0xbb, 0x07, 0x8d, 0x9e,
+	0xed, 0x3b, 0x74, 0x92, 0x59, 0xca, 0x0d, 0xc7, 0xb6, 0x0b, 0xcd, 0x0c, 0x55, 0x5f, 0x8a, 0xaf,
+	0x00, 0x3f, 0xa7, 0xdc, 0x19, 0x13, 0xca, 0xa2, 0x09, 0xff, 0x44, 0x84, 0x3e, 0x18, 0x6f, 0x29,
+	0x1b, 0xf7, 0xc6, 0x91, 0xff, 0x2b, 0xc6, 0xb0, 0x2d, 0xf6, 0x5c, 0x33, 0xe4, 0x6f, 0xfc, 0x05,
+	0x14, 0x1c, 0xe1, 0xd4, 0xea, 0xb0, 0x93, 0xaa, 0x83, 0x40, 0x89, 0x72, 0x9a, 0x4d, 0xa8, 0x9f,
+	0x46, 0x5c, 0x44, 0x4a, 0xca, 0xf8, 0x05, 0x0a, 0x2a, 0xea, 0x75, 0x28, 0x06, 0xe7, 0xe7, 0x8c,
+	0x72, 0x3d, 0x55, 0x6d, 0x89, 0x6c, 0xcc, 0x5d, 0x50, 0x7d, 0xd7, 0xe4, 0x6f, 0x81, 0x8d, 0x6c,
+	0x6e, 0xcb, 0xf9, 0x55, 0x89, 0xfc, 0x2d, 0xa6, 0xe4, 0x84, 0xce, 0x41, 0x57, 0xce, 0xae, 0x46,
+	0x94, 0x61, 0x7e, 0x0b, 0x25, 0xfd, 0x6e, 0xe1, 0xaf, 0xc1, 0xe0, 0xa1, 0x6b, 0xfb, 0x17, 0xe9,
+	0xe3, 0x96, 0xee, 0xe6, 0x7b, 0xed, 0x21, 0x29, 0xc7, 0xf4, 0xa0, 0x1c, 0xc3, 0xf8, 0x0e, 0xe4,
+	0x66, 0x4f, 0xb4, 0x42, 0xd4, 0x93, 0xaf, 0x7e, 0xa0, 0x21, 0xa7, 0x73, 0x92, 0x9b, 0x3d, 0x91,
+	0x84, 0x6e, 0x2b, 0xb7, 0x89, 0xd0, 0x95, 0x84, 0x83, 0x56, 0x7e, 0x13, 0xe1, 0xc0, 0xec, 0x42,
+	0x51, 0x59, 0x62, 0xb7, 0xe6, 0x32, 0x17, 0x22, 0x48, 0x5a, 0x4a, 0x74, 0x11, 0x41, 0x97, 0xe9,
+	0x55, 0x47, 0x04, 0x2d, 0xee, 0xbf, 0x86, 0x72, 0x7c, 0xab, 0x70, 0x1d, 0x2a, 0xaf, 0xde, 0x1d,
+	0x59, 0xe4, 0xfb, 0xc1, 0xe0, 0x64, 0xf0, 0xa2, 0xb1, 0x85, 0xab, 0x50, 0x16, 0xc0, 0xf1, 0xbb,
+	0x41, 0xbf, 0x81, 0xf0, 0x0e, 0x80, 0xb0, 0x9e, 0x1f, 0x9e, 0xbc, 0xe9, 0x1f, 0x37, 0x72, 0xb8,
+	0x01, 0x55, 0x61, 0xf7, 0x0e, 0x07, 0xbd, 0xbe, 0x40, 0xf2, 0xf7, 0x8f, 0x00, 0xd2, 0x75, 0xc5,
+	0x4d, 0xa8, 0x9d, 0xbd, 0x39, 0xe9, 0xf5, 0xad, 0xd3, 0xfe, 0xe0, 0x58, 0x05, 0x4c, 0xa0, 0x38,
+	0x87, 0x8c, 0xaa, 0x20, 0x99, 0x25, 0xd7, 0xfd, 0x7b, 0x1b, 0x8a, 0x6f, 0x6d, 0xa1, 0xbe, 0xf8,
+	0x29, 0x14, 0x95, 0x82, 0xe2, 0x0d, 0x92, 0xda, 0xbe, 0xb1, 0x82, 0xeb, 0xa5, 0xd8, 0xc2, 0xa7,
+	0x50, 0xbb, 0xf2, 0xa4, 0xe2, 0x5b, 0x09, 0x77, 0xdd, 0x5b, 0xde, 0xbe, 0xbd, 0xc9, 0x9d, 0x44,
+	0xfc, 0x06, 0xaa, 0x2a, 0xcb, 0x19, 0x0f, 0xa9, 0xed, 0xe1, 0xa5, 0x15, 0x6d, 0x2f, 0xd9, 0xe6,
+	0x56, 0x07, 0x3d, 0x46, 0xf8, 0x18, 0x8c, 0xe4, 0xd1, 0xc2, 0x37, 0x13, 0xca, 0xf2, 0xd3, 0xd9,
+	0x6e, 0xaf, 0x73, 0x25, 0xb9, 0x5f, 0x00, 0xa4, 0xcf, 0x12, 0x4e, 0xb9, 0x2b, 0x4f, 0x5b, 0xfb,
+	0xb3, 0xb5, 0xbe, 0x24, 0xd0, 0x53, 0x30, 0x12, 0x69, 0x5f, 0xe9, 0x20, 0x8d, 0xbb, 0x22, 0xff,
+	0xa2, 0x1b, 0x7c, 0x04, 0xd5, 0xac, 0xd6, 0xe3, 0xcf, 0x13, 0xfe, 0x9a, 0x27, 0xa0, 0x8d, 0x97,
+	0x75, 0x3b, 0x62, 0xe6, 0x96, 0x98, 0x47, 0x22, 0x25, 0x99, 0x79, 0x2c, 0x2b, 0x51, 0xbb, 0xbd,
+	0xce, 0x95, 0xb4, 0xf1, 0x0c, 0x2a, 0x19, 0xed, 0xc1, 0x69, 0xd3, 0xab, 0x8a, 0xb4, 0x7a, 0x2e,
+	0x8f, 0x51, 0xf7, 0x2f, 0x04, 0x05, 0xb5, 0x16, 0x87, 0x50, 0x8e, 0x9f, 0x4f, 0xdc, 0xba, 0xaa,
+	0xba, 0x99, 0x6a, 0x6e, 0xae, 0xf1, 0x24, 0xc5, 0xf4, 0xf5, 0xda, 0x13, 0xdb, 0xbf, 0xa0, 0xff,
+	0x31, 0xc8, 0x63, 0x84, 0x9f, 0x42, 0x49, 0x6b, 0x1b, 0x4e, 0x47, 0x97, 0x88, 0x66, 0x3b, 0x8d,
+	0xbb, 0xac, 0x80, 0x5b, 0x1d, 0x34, 0x2c, 0xca, 0xbf, 0xe6, 0x07, 0xff, 0x0e, 0x00, 0x02, 0xfa,
+	0xa0, 0xd4, 0xab, 0x0b, 0x00, 0x00,
 }
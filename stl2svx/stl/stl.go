@@ -29,12 +29,25 @@ type STL struct {
 // dim represents the number of voxels in the widest dimension.
 // nX, nY, nZ represent the number of subdivisions in each dimension.
 func New(p *pb.STLFile, dim, nX, nY, nZ int64) (*STL, error) {
+	if dim <= 0 || nX <= 0 || nY <= 0 || nZ <= 0 {
+		return nil, fmt.Errorf("invalid dimensions: dim=%v, nx=%v, ny=%v, nz=%v", dim, nX, nY, nZ)
+	}
+	if len(p.GetTriangles()) == 0 {
+		return nil, fmt.Errorf("STL file has no triangles")
+	}
 	var tris []*gl.Triangle
-	for _, t := range p.GetTriangles() {
-		tris = append(tris, gl.NewTriangleForPoints(
-			gl.V(t.V1.X, t.V1.Y, t.V1.Z),
-			gl.V(t.V2.X, t.V2.Y, t.V2.Z),
-			gl.V(t.V3.X, t.V3.Y, t.V3.Z)))
+	for i, t := range p.GetTriangles() {
+		var v [3]gl.Vector
+		for j, vertex := range []*pb.Vertex{t.GetV1(), t.GetV2(), t.GetV3()} {
+			if vertex == nil {
+				return nil, fmt.Errorf("triangle %v is missing vertex %v", i, j+1)
+			}
+			v[j] = gl.V(vertex.X, vertex.Y, vertex.Z)
+			if !finite(v[j]) {
+				return nil, fmt.Errorf("triangle %v has invalid vertex %v: %v", i, j+1, v[j])
+			}
+		}
+		tris = append(tris, gl.NewTriangleForPoints(v[0], v[1], v[2]))
 	}
 	mesh := gl.NewTriangleMesh(tris)
 	mbb := mesh.BoundingBox()
@@ -46,6 +59,9 @@ func New(p *pb.STLFile, dim, nX, nY, nZ int64) (*STL, error) {
 	if dz := mbb.Max.Z - mbb.Min.Z; dz > scale {
 		scale = dz
 	}
+	if scale <= 0 {
+		return nil, fmt.Errorf("STL file has zero size")
+	}
 	vpmm := float64(dim) / scale // voxels per millimeter
 	mmpv := 1.0 / vpmm           // millimeters per voxel
 	modelDimInMM := mbb.Size()
@@ -76,3 +92,13 @@ func New(p *pb.STLFile, dim, nX, nY, nZ int64) (*STL, error) {
 		SubregionScale: subregionScale,
 	}, nil
 }
+
+// finite reports whether every coordinate of v is a finite number.
+func finite(v gl.Vector) bool {
+	for _, f := range []float64{v.X, v.Y, v.Z} {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return false
+		}
+	}
+	return true
+}
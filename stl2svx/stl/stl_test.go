@@ -0,0 +1,69 @@
+package stl
+
+import (
+	"math"
+	"testing"
+
+	pb "github.com/gmlewis/stldice/v4/stl2svx/proto"
+)
+
+// tetrahedron returns an STL file of a tetrahedron with sides of length 10.
+func tetrahedron() *pb.STLFile {
+	a := &pb.Vertex{X: 0, Y: 0, Z: 0}
+	b := &pb.Vertex{X: 10, Y: 0, Z: 0}
+	c := &pb.Vertex{X: 0, Y: 10, Z: 0}
+	d := &pb.Vertex{X: 0, Y: 0, Z: 10}
+	return &pb.STLFile{Triangles: []*pb.Triangle{
+		{V1: a, V2: c, V3: b},
+		{V1: a, V2: b, V3: d},
+		{V1: a, V2: d, V3: c},
+		{V1: b, V2: c, V3: d},
+	}}
+}
+
+func TestNew(t *testing.T) {
+	s, err := New(tetrahedron(), 100, 2, 2, 1)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if s.ModelDimX != 100 || s.ModelDimY != 100 || s.ModelDimZ != 100 {
+		t.Errorf("model dimensions = (%v,%v,%v), want (100,100,100)", s.ModelDimX, s.ModelDimY, s.ModelDimZ)
+	}
+	if s.DimX != 50 || s.DimY != 50 || s.DimZ != 100 {
+		t.Errorf("region dimensions = (%v,%v,%v), want (50,50,100)", s.DimX, s.DimY, s.DimZ)
+	}
+	if got, want := s.MMPV, 0.1; math.Abs(got-want) > 1e-9 {
+		t.Errorf("MMPV = %v, want %v", got, want)
+	}
+}
+
+func TestNewMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *pb.STLFile)
+		dim, n int64
+	}{
+		{name: "no triangles", modify: func(p *pb.STLFile) { p.Triangles = nil }, dim: 100, n: 1},
+		{name: "missing vertex", modify: func(p *pb.STLFile) { p.Triangles[2].V2 = nil }, dim: 100, n: 1},
+		{name: "NaN vertex", modify: func(p *pb.STLFile) { p.Triangles[1].V3 = &pb.Vertex{X: math.NaN()} }, dim: 100, n: 1},
+		{name: "infinite vertex", modify: func(p *pb.STLFile) { p.Triangles[0].V1 = &pb.Vertex{Z: math.Inf(1)} }, dim: 100, n: 1},
+		{name: "zero size", modify: func(p *pb.STLFile) {
+			v := &pb.Vertex{X: 1, Y: 2, Z: 3}
+			p.Triangles = []*pb.Triangle{{V1: v, V2: v, V3: v}}
+		}, dim: 100, n: 1},
+		{name: "zero dim", dim: 0, n: 1},
+		{name: "zero divisions", dim: 100, n: 0},
+		{name: "too many divisions", dim: 100, n: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tetrahedron()
+			if tt.modify != nil {
+				tt.modify(p)
+			}
+			if _, err := New(p, tt.dim, tt.n, tt.n, tt.n); err == nil {
+				t.Error("New = nil error, want error")
+			}
+		})
+	}
+}
@@ -5,14 +5,15 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	gl "github.com/fogleman/fauxgl"
 	pb "github.com/gmlewis/stldice/v4/stl2svx/proto"
+	"github.com/golang/protobuf/proto"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 )
@@ -29,18 +30,22 @@ var (
 	stl           = flag.String("stl", "", "Comma separated list of STL files to process; first is base (e.g. 'base.stl,cut1.stl...')")
 	prefix        = flag.String("prefix", "out", "Prefix for output SVX file")
 	masterAddress = flag.String("master", "", "Address used by agent to contact master")
+	detach        = flag.Bool("detach", false, "Submit the job, print its ID and exit without waiting for the result")
+	wait          = flag.String("wait", "", "ID of a previously submitted job to wait for (instead of submitting a new job)")
+	poll          = flag.Duration("poll", 5*time.Second, "How often to poll the master for the job status")
 )
 
 func main() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", filepath.Base(os.Args[0]))
-		fmt.Fprintf(os.Stderr, "\t%v [options] -stl base.stl[,cut1.stl[,cut2.stl,...]]\n\nOptions:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\t%v [options] -stl base.stl[,cut1.stl[,cut2.stl,...]]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "\t%v [options] -wait jobID\n\nOptions:\n", filepath.Base(os.Args[0]))
 		flag.PrintDefaults()
 	}
 	flag.Parse()
 
-	if *stl == "" {
-		log.Fatal("must specify -stl file(s)")
+	if *stl == "" && *wait == "" {
+		log.Fatal("must specify -stl file(s) or -wait")
 	}
 
 	conn, err := grpc.Dial(*masterAddress, grpc.WithInsecure(), grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxSize)), grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(maxSize)))
@@ -48,34 +53,111 @@ func main() {
 		log.Fatalf("Unable to communicate with master %v: %v", masterAddress, err)
 	}
 	defer conn.Close()
+	c := pb.NewMasterClient(conn)
+	ctx := context.Background()
 
-	req := &pb.NewJobRequest{
-		Dim:       int64(*dim),
-		NX:        int64(*nX),
-		NY:        int64(*nY),
-		NZ:        int64(*nZ),
-		OutPrefix: *prefix,
-	}
-	req.StlFiles, err = loadSTL(strings.Split(*stl, ","))
-	if err != nil {
-		log.Fatalf("loadSTL: %v", err)
+	jobID := *wait
+	if jobID == "" {
+		req := &pb.NewJobRequest{
+			Dim:       int64(*dim),
+			NX:        int64(*nX),
+			NY:        int64(*nY),
+			NZ:        int64(*nZ),
+			OutPrefix: *prefix,
+		}
+		req.StlFiles, err = loadSTL(strings.Split(*stl, ","))
+		if err != nil {
+			log.Fatalf("loadSTL: %v", err)
+		}
+
+		if jobID, err = submitJob(ctx, c, req); err != nil {
+			log.Fatalf("master: %v", err)
+		}
+		log.Printf("Submitted job %v", jobID)
+		if *detach {
+			fmt.Println(jobID)
+			return
+		}
 	}
 
-	c := pb.NewMasterClient(conn)
-	resp, err := c.NewJob(context.Background(), req)
+	status, err := waitForJob(ctx, c, jobID, *poll)
 	if err != nil {
 		log.Fatalf("master: %v", err)
 	}
-
-	outFile := fmt.Sprintf("%v-%v-%v-%v-%v.svx", *prefix, *dim, *nX, *nY, *nZ)
+	outFile := status.GetSvxName()
 	log.Printf("Writing SVX file %v ...", outFile)
-	if err := ioutil.WriteFile(outFile, resp.GetSvxFile(), 0644); err != nil {
-		log.Fatalf("write: %v", err)
+	if err := fetchResult(ctx, c, jobID, outFile); err != nil {
+		log.Fatalf("master: %v", err)
 	}
 
 	log.Print("Done.")
 }
 
+// submitJob streams the job to the master in chunks and returns its ID.
+func submitJob(ctx context.Context, c pb.MasterClient, req *pb.NewJobRequest) (string, error) {
+	buf, err := proto.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	stream, err := c.SubmitJob(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := pb.SendChunks(buf, stream.Send); err != nil {
+		return "", err
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return "", err
+	}
+	return resp.GetJobId(), nil
+}
+
+// waitForJob polls the master every interval until the job is done,
+// logging its progress. It returns an error if the job failed or was canceled.
+func waitForJob(ctx context.Context, c pb.MasterClient, jobID string, interval time.Duration) (*pb.JobStatus, error) {
+	var completed int64 = -1
+	for {
+		status, err := c.GetJobStatus(ctx, &pb.GetJobStatusRequest{JobId: jobID})
+		if err != nil {
+			return nil, err
+		}
+		switch status.GetState() {
+		case pb.JobState_JOB_DONE:
+			return status, nil
+		case pb.JobState_JOB_FAILED, pb.JobState_JOB_CANCELED:
+			return nil, fmt.Errorf("%v: %v", status.GetState(), status.GetError())
+		}
+		if status.GetCompletedSlices() != completed {
+			completed = status.GetCompletedSlices()
+			log.Printf("Job %v: %v of %v slices complete", jobID, completed, status.GetTotalSlices())
+		}
+		time.Sleep(interval)
+	}
+}
+
+// fetchResult writes the SVX file of the completed job to outFile as it
+// arrives. outFile is removed if the download fails.
+func fetchResult(ctx context.Context, c pb.MasterClient, jobID, outFile string) error {
+	stream, err := c.FetchResult(ctx, &pb.FetchResultRequest{JobId: jobID})
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(outFile)
+	if err != nil {
+		return fmt.Errorf("unable to create %q: %v", outFile, err)
+	}
+	_, err = pb.ReceiveChunksTo(f, stream.Recv)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(outFile)
+		return err
+	}
+	return nil
+}
+
 func loadSTL(args []string) (out []*pb.STLFile, err error) {
 	for i, arg := range args {
 		log.Printf("loadSTL: loading file #%v: %v ...", i, arg)
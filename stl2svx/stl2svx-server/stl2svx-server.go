@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/gmlewis/stldice/v4/stl2svx/agent"
 	"github.com/gmlewis/stldice/v4/stl2svx/master"
@@ -23,6 +26,8 @@ const (
 var (
 	port          = flag.String("port", "0.0.0.0:0", "Port to use (0.0.0.0:0 is any available port)")
 	masterAddress = flag.String("master", "", "Address used by agent to contact master")
+	dir           = flag.String("dir", "", "Directory where the master persists jobs so they survive restarts (empty keeps them in memory)")
+	slices        = flag.Int("slices", 0, "Maximum number of slices an agent runs concurrently (0 is the number of CPUs)")
 )
 
 func main() {
@@ -37,27 +42,45 @@ func main() {
 	switch {
 	case *masterAddress == "": // This is the master
 		log.Printf("Master using port: %v", lis.Addr().(*net.TCPAddr).String())
-		pb.RegisterMasterServer(s, master.New())
+		m, err := master.New(*dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pb.RegisterMasterServer(s, m)
 	default: // This is an agent
-		address := getAddress(lis.Addr().(*net.TCPAddr))
+		address, err := getAddress(lis.Addr().(*net.TCPAddr))
+		if err != nil {
+			log.Fatal(err)
+		}
 		log.Printf("Agent using port: %v, master address: %v", address, *masterAddress)
-		agent, err := agent.New(context.Background(), address, *masterAddress)
+		agent, err := agent.New(context.Background(), address, *masterAddress, *slices)
 		if err != nil {
 			log.Fatal(err)
 		}
 		pb.RegisterAgentServer(s, agent)
+
+		// Deregister when shut down so the master stops scheduling slices here.
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigs
+			if err := agent.Close(context.Background()); err != nil {
+				log.Print(err)
+			}
+			s.GracefulStop()
+		}()
 	}
 	s.Serve(lis)
 }
 
-func getAddress(tcpAddr *net.TCPAddr) string {
+func getAddress(tcpAddr *net.TCPAddr) (string, error) {
 	localPort := tcpAddr.String()
 	port := strings.Split(localPort, ":")
 
 	var result string
 	ifaces, err := net.Interfaces()
 	if err != nil {
-		log.Fatalf("net.Interfaces: %v", err)
+		return "", fmt.Errorf("net.Interfaces: %v", err)
 	}
 	for _, i := range ifaces {
 		addrs, err := i.Addrs()
@@ -72,7 +95,7 @@ func getAddress(tcpAddr *net.TCPAddr) string {
 				ip = v.IP
 				result = ip.String()
 				if !strings.HasPrefix(result, "127.") && !strings.Contains(result, ":") {
-					return fmt.Sprintf("%v:%v", result, port[len(port)-1])
+					return fmt.Sprintf("%v:%v", result, port[len(port)-1]), nil
 				}
 			case *net.IPAddr:
 				ip = v.IP
@@ -81,5 +104,5 @@ func getAddress(tcpAddr *net.TCPAddr) string {
 			log.Printf("got address: %v", result)
 		}
 	}
-	return fmt.Sprintf("%v:%v", result, port[len(port)-1])
+	return fmt.Sprintf("%v:%v", result, port[len(port)-1]), nil
 }
@@ -0,0 +1,66 @@
+package stldice
+
+import (
+	"fmt"
+
+	"github.com/gmlewis/stldice/v4/chunk"
+)
+
+// ChunkSize is the maximum number of bytes of data in each Chunk.
+const ChunkSize = chunk.Size
+
+// SendChunks splits data into Chunks (with checksums) and sends them
+// in order with send. Empty data is sent as a single empty Chunk.
+func SendChunks(data []byte, send func(*Chunk) error) error {
+	return chunk.Send(data, func(offset, size int64, data []byte, crc uint32) error {
+		return send(&Chunk{Offset: offset, Size: size, Data: data, Crc32: crc})
+	})
+}
+
+// ReceiveChunks receives Chunks with recv until it returns io.EOF and
+// returns the reassembled data (see chunk.Receive).
+func ReceiveChunks(recv func() (*Chunk, error)) ([]byte, error) {
+	return chunk.Receive(func() (chunk.Message, error) {
+		c, err := recv()
+		if c == nil {
+			return nil, err // not a nil *Chunk in a non-nil chunk.Message
+		}
+		return c, err
+	})
+}
+
+// SendSTLMesh sends the STL file for the region as STLMeshChunks with send.
+func SendSTLMesh(region *VoxelRegion, stlFile []byte, send func(*STLMeshChunk) error) error {
+	first := true
+	return SendChunks(stlFile, func(c *Chunk) error {
+		m := &STLMeshChunk{Chunk: c}
+		if first {
+			m.VoxelRegion = region
+			first = false
+		}
+		return send(m)
+	})
+}
+
+// ReceiveSTLMesh receives STLMeshChunks with recv until it returns io.EOF
+// and returns the VoxelRegion (from the first message) and the STL file.
+func ReceiveSTLMesh(recv func() (*STLMeshChunk, error)) (*VoxelRegion, []byte, error) {
+	var region *VoxelRegion
+	stlFile, err := ReceiveChunks(func() (*Chunk, error) {
+		m, err := recv()
+		if err != nil {
+			return nil, err
+		}
+		if region == nil {
+			region = m.GetVoxelRegion()
+		}
+		return m.GetChunk(), nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if region == nil {
+		return nil, nil, fmt.Errorf("missing voxel region")
+	}
+	return region, stlFile, nil
+}
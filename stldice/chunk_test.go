@@ -0,0 +1,70 @@
+package stldice
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// replay returns a recv function that returns the chunks in order and then io.EOF.
+func replay(chunks []*Chunk) func() (*Chunk, error) {
+	return func() (*Chunk, error) {
+		if len(chunks) == 0 {
+			return nil, io.EOF
+		}
+		c := chunks[0]
+		chunks = chunks[1:]
+		return c, nil
+	}
+}
+
+func TestChunks(t *testing.T) {
+	for _, size := range []int{0, 1, ChunkSize, 2*ChunkSize + 3} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+		var chunks []*Chunk
+		if err := SendChunks(data, func(c *Chunk) error { chunks = append(chunks, c); return nil }); err != nil {
+			t.Fatalf("SendChunks(%v bytes): %v", size, err)
+		}
+		if want := (size + ChunkSize - 1) / ChunkSize; len(chunks) != want && !(size == 0 && len(chunks) == 1) {
+			t.Errorf("SendChunks(%v bytes) sent %v chunks, want %v", size, len(chunks), want)
+		}
+		got, err := ReceiveChunks(replay(chunks))
+		if err != nil {
+			t.Fatalf("ReceiveChunks(%v bytes): %v", size, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("ReceiveChunks(%v bytes) returned different data", size)
+		}
+	}
+}
+
+func TestReceiveChunksErrors(t *testing.T) {
+	var chunks []*Chunk
+	data := make([]byte, ChunkSize+10)
+	if err := SendChunks(data, func(c *Chunk) error { chunks = append(chunks, c); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := *chunks[1]
+	corrupt.Data = append([]byte{1}, corrupt.Data[1:]...)
+
+	tests := []struct {
+		name   string
+		chunks []*Chunk
+	}{
+		{"none", nil},
+		{"truncated", chunks[:1]},
+		{"out of order", []*Chunk{chunks[1], chunks[0]}},
+		{"duplicated", []*Chunk{chunks[0], chunks[0], chunks[1]}},
+		{"corrupt", []*Chunk{chunks[0], &corrupt}},
+		{"too large", []*Chunk{{Size: 1 << 62}}},
+		{"nil", []*Chunk{nil}},
+	}
+	for _, tt := range tests {
+		if _, err := ReceiveChunks(replay(tt.chunks)); err == nil {
+			t.Errorf("ReceiveChunks(%v) = nil error, want error", tt.name)
+		}
+	}
+}
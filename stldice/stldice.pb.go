@@ -1,6 +1,5 @@
-// Code generated by protoc-gen-go.
+// Code generated by protoc-gen-go. DO NOT EDIT.
 // source: stldice.proto
-// DO NOT EDIT!
 
 /*
 Package stldice is a generated protocol buffer package.
@@ -18,6 +17,10 @@ It has these top-level messages:
 	SubSTLMeshReply
 	GetSTLMeshRequest
 	GetSTLMeshReply
+	ResetModelRequest
+	ResetModelReply
+	Chunk
+	STLMeshChunk
 */
 package stldice
 
@@ -193,7 +196,6 @@ func (*SubSTLMeshReply) ProtoMessage()               {}
 func (*SubSTLMeshReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
 
 // Get back the model voxels as STL (possibly a subregion on the slave).
-// This also resets the server by clearing out the model.
 type GetSTLMeshRequest struct {
 	VoxelRegion *VoxelRegion `protobuf:"bytes,1,opt,name=voxel_region,json=voxelRegion" json:"voxel_region,omitempty"`
 }
@@ -227,6 +229,97 @@ func (m *GetSTLMeshReply) GetStlFile() []byte {
 	return nil
 }
 
+// Reset the server by clearing out the model (possibly a subregion on the slave).
+type ResetModelRequest struct {
+	VoxelRegion *VoxelRegion `protobuf:"bytes,1,opt,name=voxel_region,json=voxelRegion" json:"voxel_region,omitempty"`
+}
+
+func (m *ResetModelRequest) Reset()                    { *m = ResetModelRequest{} }
+func (m *ResetModelRequest) String() string            { return proto.CompactTextString(m) }
+func (*ResetModelRequest) ProtoMessage()               {}
+func (*ResetModelRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *ResetModelRequest) GetVoxelRegion() *VoxelRegion {
+	if m != nil {
+		return m.VoxelRegion
+	}
+	return nil
+}
+
+type ResetModelReply struct {
+}
+
+func (m *ResetModelReply) Reset()                    { *m = ResetModelReply{} }
+func (m *ResetModelReply) String() string            { return proto.CompactTextString(m) }
+func (*ResetModelReply) ProtoMessage()               {}
+func (*ResetModelReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+// Chunk is a piece of a file sent over a stream.
+type Chunk struct {
+	Offset int64  `protobuf:"varint,1,opt,name=offset" json:"offset,omitempty"`
+	Size   int64  `protobuf:"varint,2,opt,name=size" json:"size,omitempty"`
+	Data   []byte `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Crc32  uint32 `protobuf:"varint,4,opt,name=crc32" json:"crc32,omitempty"`
+}
+
+func (m *Chunk) Reset()                    { *m = Chunk{} }
+func (m *Chunk) String() string            { return proto.CompactTextString(m) }
+func (*Chunk) ProtoMessage()               {}
+func (*Chunk) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *Chunk) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *Chunk) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *Chunk) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *Chunk) GetCrc32() uint32 {
+	if m != nil {
+		return m.Crc32
+	}
+	return 0
+}
+
+// STLMeshChunk is a piece of an STL file streamed to the server.
+type STLMeshChunk struct {
+	VoxelRegion *VoxelRegion `protobuf:"bytes,1,opt,name=voxel_region,json=voxelRegion" json:"voxel_region,omitempty"`
+	Chunk       *Chunk       `protobuf:"bytes,2,opt,name=chunk" json:"chunk,omitempty"`
+}
+
+func (m *STLMeshChunk) Reset()                    { *m = STLMeshChunk{} }
+func (m *STLMeshChunk) String() string            { return proto.CompactTextString(m) }
+func (*STLMeshChunk) ProtoMessage()               {}
+func (*STLMeshChunk) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *STLMeshChunk) GetVoxelRegion() *VoxelRegion {
+	if m != nil {
+		return m.VoxelRegion
+	}
+	return nil
+}
+
+func (m *STLMeshChunk) GetChunk() *Chunk {
+	if m != nil {
+		return m.Chunk
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*VoxelRegion)(nil), "stldice.VoxelRegion")
 	proto.RegisterType((*AddSTLMeshRequest)(nil), "stldice.AddSTLMeshRequest")
@@ -235,6 +328,10 @@ func init() {
 	proto.RegisterType((*SubSTLMeshReply)(nil), "stldice.SubSTLMeshReply")
 	proto.RegisterType((*GetSTLMeshRequest)(nil), "stldice.GetSTLMeshRequest")
 	proto.RegisterType((*GetSTLMeshReply)(nil), "stldice.GetSTLMeshReply")
+	proto.RegisterType((*ResetModelRequest)(nil), "stldice.ResetModelRequest")
+	proto.RegisterType((*ResetModelReply)(nil), "stldice.ResetModelReply")
+	proto.RegisterType((*Chunk)(nil), "stldice.Chunk")
+	proto.RegisterType((*STLMeshChunk)(nil), "stldice.STLMeshChunk")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -253,8 +350,17 @@ type STLDiceClient interface {
 	// Subtract an STL mesh from the voxels (possibly a subregion on the slave).
 	SubSTLMesh(ctx context.Context, in *SubSTLMeshRequest, opts ...grpc.CallOption) (*SubSTLMeshReply, error)
 	// Get back the model voxels as STL (possibly a subregion on the slave).
-	// This also resets the server by clearing out the model.
+	// The model is left intact so that a lost reply can be retried.
 	GetSTLMesh(ctx context.Context, in *GetSTLMeshRequest, opts ...grpc.CallOption) (*GetSTLMeshReply, error)
+	// Reset the server by clearing out the model (possibly a subregion on the slave).
+	// Resetting a region without a model is not an error.
+	ResetModel(ctx context.Context, in *ResetModelRequest, opts ...grpc.CallOption) (*ResetModelReply, error)
+	// AddSTLMeshStream is AddSTLMesh with the STL file streamed in chunks.
+	AddSTLMeshStream(ctx context.Context, opts ...grpc.CallOption) (STLDice_AddSTLMeshStreamClient, error)
+	// SubSTLMeshStream is SubSTLMesh with the STL file streamed in chunks.
+	SubSTLMeshStream(ctx context.Context, opts ...grpc.CallOption) (STLDice_SubSTLMeshStreamClient, error)
+	// GetSTLMeshStream is GetSTLMesh with the STL file streamed back in chunks.
+	GetSTLMeshStream(ctx context.Context, in *GetSTLMeshRequest, opts ...grpc.CallOption) (STLDice_GetSTLMeshStreamClient, error)
 }
 
 type sTLDiceClient struct {
@@ -292,6 +398,115 @@ func (c *sTLDiceClient) GetSTLMesh(ctx context.Context, in *GetSTLMeshRequest, o
 	return out, nil
 }
 
+func (c *sTLDiceClient) ResetModel(ctx context.Context, in *ResetModelRequest, opts ...grpc.CallOption) (*ResetModelReply, error) {
+	out := new(ResetModelReply)
+	err := grpc.Invoke(ctx, "/stldice.STLDice/ResetModel", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sTLDiceClient) AddSTLMeshStream(ctx context.Context, opts ...grpc.CallOption) (STLDice_AddSTLMeshStreamClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_STLDice_serviceDesc.Streams[0], c.cc, "/stldice.STLDice/AddSTLMeshStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sTLDiceAddSTLMeshStreamClient{stream}
+	return x, nil
+}
+
+type STLDice_AddSTLMeshStreamClient interface {
+	Send(*STLMeshChunk) error
+	CloseAndRecv() (*AddSTLMeshReply, error)
+	grpc.ClientStream
+}
+
+type sTLDiceAddSTLMeshStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *sTLDiceAddSTLMeshStreamClient) Send(m *STLMeshChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *sTLDiceAddSTLMeshStreamClient) CloseAndRecv() (*AddSTLMeshReply, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(AddSTLMeshReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *sTLDiceClient) SubSTLMeshStream(ctx context.Context, opts ...grpc.CallOption) (STLDice_SubSTLMeshStreamClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_STLDice_serviceDesc.Streams[1], c.cc, "/stldice.STLDice/SubSTLMeshStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sTLDiceSubSTLMeshStreamClient{stream}
+	return x, nil
+}
+
+type STLDice_SubSTLMeshStreamClient interface {
+	Send(*STLMeshChunk) error
+	CloseAndRecv() (*SubSTLMeshReply, error)
+	grpc.ClientStream
+}
+
+type sTLDiceSubSTLMeshStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *sTLDiceSubSTLMeshStreamClient) Send(m *STLMeshChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *sTLDiceSubSTLMeshStreamClient) CloseAndRecv() (*SubSTLMeshReply, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(SubSTLMeshReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *sTLDiceClient) GetSTLMeshStream(ctx context.Context, in *GetSTLMeshRequest, opts ...grpc.CallOption) (STLDice_GetSTLMeshStreamClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_STLDice_serviceDesc.Streams[2], c.cc, "/stldice.STLDice/GetSTLMeshStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sTLDiceGetSTLMeshStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type STLDice_GetSTLMeshStreamClient interface {
+	Recv() (*Chunk, error)
+	grpc.ClientStream
+}
+
+type sTLDiceGetSTLMeshStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *sTLDiceGetSTLMeshStreamClient) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Server API for STLDice service
 
 type STLDiceServer interface {
@@ -300,8 +515,17 @@ type STLDiceServer interface {
 	// Subtract an STL mesh from the voxels (possibly a subregion on the slave).
 	SubSTLMesh(context.Context, *SubSTLMeshRequest) (*SubSTLMeshReply, error)
 	// Get back the model voxels as STL (possibly a subregion on the slave).
-	// This also resets the server by clearing out the model.
+	// The model is left intact so that a lost reply can be retried.
 	GetSTLMesh(context.Context, *GetSTLMeshRequest) (*GetSTLMeshReply, error)
+	// Reset the server by clearing out the model (possibly a subregion on the slave).
+	// Resetting a region without a model is not an error.
+	ResetModel(context.Context, *ResetModelRequest) (*ResetModelReply, error)
+	// AddSTLMeshStream is AddSTLMesh with the STL file streamed in chunks.
+	AddSTLMeshStream(STLDice_AddSTLMeshStreamServer) error
+	// SubSTLMeshStream is SubSTLMesh with the STL file streamed in chunks.
+	SubSTLMeshStream(STLDice_SubSTLMeshStreamServer) error
+	// GetSTLMeshStream is GetSTLMesh with the STL file streamed back in chunks.
+	GetSTLMeshStream(*GetSTLMeshRequest, STLDice_GetSTLMeshStreamServer) error
 }
 
 func RegisterSTLDiceServer(s *grpc.Server, srv STLDiceServer) {
@@ -362,6 +586,97 @@ func _STLDice_GetSTLMesh_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _STLDice_ResetModel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetModelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(STLDiceServer).ResetModel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stldice.STLDice/ResetModel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(STLDiceServer).ResetModel(ctx, req.(*ResetModelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _STLDice_AddSTLMeshStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(STLDiceServer).AddSTLMeshStream(&sTLDiceAddSTLMeshStreamServer{stream})
+}
+
+type STLDice_AddSTLMeshStreamServer interface {
+	SendAndClose(*AddSTLMeshReply) error
+	Recv() (*STLMeshChunk, error)
+	grpc.ServerStream
+}
+
+type sTLDiceAddSTLMeshStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *sTLDiceAddSTLMeshStreamServer) SendAndClose(m *AddSTLMeshReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *sTLDiceAddSTLMeshStreamServer) Recv() (*STLMeshChunk, error) {
+	m := new(STLMeshChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _STLDice_SubSTLMeshStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(STLDiceServer).SubSTLMeshStream(&sTLDiceSubSTLMeshStreamServer{stream})
+}
+
+type STLDice_SubSTLMeshStreamServer interface {
+	SendAndClose(*SubSTLMeshReply) error
+	Recv() (*STLMeshChunk, error)
+	grpc.ServerStream
+}
+
+type sTLDiceSubSTLMeshStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *sTLDiceSubSTLMeshStreamServer) SendAndClose(m *SubSTLMeshReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *sTLDiceSubSTLMeshStreamServer) Recv() (*STLMeshChunk, error) {
+	m := new(STLMeshChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _STLDice_GetSTLMeshStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetSTLMeshRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(STLDiceServer).GetSTLMeshStream(m, &sTLDiceGetSTLMeshStreamServer{stream})
+}
+
+type STLDice_GetSTLMeshStreamServer interface {
+	Send(*Chunk) error
+	grpc.ServerStream
+}
+
+type sTLDiceGetSTLMeshStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *sTLDiceGetSTLMeshStreamServer) Send(m *Chunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _STLDice_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "stldice.STLDice",
 	HandlerType: (*STLDiceServer)(nil),
@@ -378,34 +693,63 @@ var _STLDice_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetSTLMesh",
 			Handler:    _STLDice_GetSTLMesh_Handler,
 		},
+		{
+			MethodName: "ResetModel",
+			Handler:    _STLDice_ResetModel_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "AddSTLMeshStream",
+			Handler:       _STLDice_AddSTLMeshStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "SubSTLMeshStream",
+			Handler:       _STLDice_SubSTLMeshStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "GetSTLMeshStream",
+			Handler:       _STLDice_GetSTLMeshStream_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "stldice.proto",
 }
 
 func init() { proto.RegisterFile("stldice.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 322 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x93, 0xbf, 0x4e, 0xc3, 0x30,
-	0x18, 0xc4, 0xeb, 0x16, 0xda, 0xf2, 0xb5, 0x50, 0x62, 0x31, 0x98, 0x4e, 0x55, 0xa6, 0x0e, 0xa8,
-	0x43, 0x19, 0x98, 0x91, 0x2a, 0x58, 0xc2, 0xe2, 0x54, 0xac, 0x95, 0xda, 0x98, 0x12, 0xc9, 0x4a,
-	0x42, 0xfe, 0x54, 0xb6, 0xdf, 0x88, 0x67, 0xe2, 0x65, 0x90, 0x9d, 0x84, 0xb8, 0x0d, 0x23, 0x62,
-	0xcb, 0xfd, 0x22, 0xdd, 0x7d, 0x77, 0x51, 0xe0, 0x32, 0xcb, 0x79, 0x10, 0xee, 0xd8, 0x22, 0x49,
-	0xe3, 0x3c, 0xc6, 0x83, 0x4a, 0xba, 0x9f, 0x08, 0x46, 0xaf, 0xb1, 0x60, 0x9c, 0xb2, 0x7d, 0x18,
-	0x47, 0xf8, 0x1a, 0x7a, 0x9c, 0x0b, 0x82, 0x66, 0x68, 0x8e, 0xa8, 0x7e, 0x2c, 0x89, 0x24, 0xdd,
-	0x9a, 0xc8, 0x92, 0x28, 0xd2, 0xab, 0x89, 0xd2, 0xa4, 0x48, 0x05, 0x39, 0x2b, 0x49, 0x91, 0x8a,
-	0x92, 0x48, 0x72, 0x5e, 0x13, 0x59, 0x12, 0x45, 0xfa, 0x35, 0x51, 0xf8, 0x0a, 0xba, 0x91, 0x20,
-	0x83, 0x19, 0x9a, 0xf7, 0x68, 0x37, 0x12, 0x46, 0x4b, 0x32, 0xac, 0xb4, 0x34, 0x5a, 0x91, 0x8b,
-	0x4a, 0x2b, 0x77, 0x0f, 0xce, 0x63, 0x10, 0xf8, 0x6b, 0xef, 0x85, 0x65, 0xef, 0x94, 0x7d, 0x14,
-	0x2c, 0xcb, 0xf1, 0x03, 0x8c, 0x0f, 0xfa, 0xfe, 0x4d, 0x6a, 0x0a, 0x98, 0xcb, 0x47, 0xcb, 0x9b,
-	0x45, 0xdd, 0xd7, 0x2a, 0x47, 0x47, 0x07, 0xab, 0xe9, 0x2d, 0x0c, 0xb3, 0x9c, 0x6f, 0xde, 0x42,
-	0xce, 0x4c, 0xb9, 0x31, 0xd5, 0xa3, 0x3c, 0x85, 0x9c, 0xb9, 0x0e, 0x4c, 0xec, 0xa0, 0x84, 0x4b,
-	0x9d, 0xed, 0x17, 0xdb, 0xff, 0xc9, 0xb6, 0x83, 0x74, 0xb6, 0x07, 0xce, 0x33, 0xcb, 0xff, 0x28,
-	0xdb, 0xbd, 0x83, 0x89, 0xed, 0x96, 0x70, 0x79, 0x74, 0x0e, 0x3a, 0x3a, 0x67, 0xf9, 0x85, 0x60,
-	0xe0, 0xaf, 0xbd, 0x55, 0xb8, 0x63, 0x78, 0x05, 0xd0, 0xcc, 0x82, 0xa7, 0x3f, 0x51, 0xad, 0x8f,
-	0x32, 0x25, 0xbf, 0xbe, 0xd3, 0x5d, 0x3a, 0xda, 0xa5, 0x29, 0x68, 0xb9, 0xb4, 0xe6, 0xb5, 0x5c,
-	0x4e, 0x17, 0x31, 0x2e, 0x4d, 0x0b, 0xcb, 0xa5, 0x35, 0x94, 0xe5, 0x72, 0x52, 0xdb, 0xed, 0x6c,
-	0xfb, 0xe6, 0x6f, 0xb8, 0xff, 0x0e, 0x00, 0x00, 0xff, 0xff, 0x15, 0x5a, 0x6f, 0xde, 0x1e, 0x03,
-	0x00, 0x00,
+	// 479 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x54, 0x41, 0x6f, 0xd3, 0x30,
+	0x14, 0x9e, 0x9b, 0xb5, 0x1d, 0xaf, 0xdd, 0xda, 0x58, 0x03, 0x99, 0x9c, 0xa6, 0x88, 0x43, 0x0f,
+	0x68, 0x42, 0xdd, 0x81, 0x33, 0x30, 0xb1, 0x4b, 0x77, 0x71, 0x27, 0x6e, 0x68, 0xea, 0x12, 0x77,
+	0x8b, 0xf0, 0x9a, 0x92, 0x38, 0x53, 0x9c, 0x7f, 0xc4, 0x2f, 0xe4, 0x8a, 0x6c, 0x27, 0xb5, 0x47,
+	0xa2, 0x09, 0x01, 0xda, 0xcd, 0xef, 0x7b, 0xca, 0xf7, 0xbd, 0xef, 0xbd, 0x4f, 0x81, 0xc3, 0x5c,
+	0xf0, 0x38, 0x89, 0xd8, 0xe9, 0x36, 0x4b, 0x45, 0x8a, 0x87, 0x75, 0x19, 0xfe, 0x40, 0x30, 0xfa,
+	0x92, 0x96, 0x8c, 0x53, 0x76, 0x9b, 0xa4, 0x1b, 0x3c, 0x05, 0x8f, 0xf3, 0x92, 0xa0, 0x13, 0x34,
+	0x43, 0x54, 0x3d, 0x0d, 0x22, 0x49, 0xaf, 0x41, 0xa4, 0x41, 0x2a, 0xe2, 0x35, 0x48, 0xa5, 0x90,
+	0x22, 0x2b, 0xc9, 0xbe, 0x41, 0x8a, 0xac, 0x34, 0x88, 0x24, 0xfd, 0x06, 0x91, 0x06, 0xa9, 0xc8,
+	0xa0, 0x41, 0x2a, 0x7c, 0x04, 0xbd, 0x4d, 0x49, 0x86, 0x27, 0x68, 0xe6, 0xd1, 0xde, 0xa6, 0xd4,
+	0xb5, 0x24, 0x07, 0x75, 0x2d, 0x75, 0x5d, 0x91, 0x17, 0x75, 0x5d, 0x85, 0xb7, 0xe0, 0x7f, 0x88,
+	0xe3, 0xe5, 0xd5, 0xe2, 0x92, 0xe5, 0x77, 0x94, 0x7d, 0x2f, 0x58, 0x2e, 0xf0, 0x7b, 0x18, 0x3f,
+	0xa8, 0xf9, 0xaf, 0x33, 0x6d, 0x40, 0x4f, 0x3e, 0x9a, 0x1f, 0x9f, 0x36, 0x7e, 0x1d, 0x73, 0x74,
+	0xf4, 0x60, 0x0b, 0xfc, 0x1a, 0x0e, 0x72, 0xc1, 0xaf, 0xd7, 0x09, 0x67, 0xda, 0xdc, 0x98, 0xaa,
+	0xa5, 0x7c, 0x4e, 0x38, 0x0b, 0x7d, 0x98, 0xb8, 0x42, 0x5b, 0x2e, 0x95, 0xf6, 0xb2, 0xb8, 0x79,
+	0x1e, 0x6d, 0x57, 0x48, 0x69, 0x2f, 0xc0, 0xbf, 0x60, 0xe2, 0x3f, 0x69, 0x87, 0x6f, 0x61, 0xe2,
+	0xb2, 0x6d, 0xb9, 0x7c, 0x34, 0x0e, 0x7a, 0x3c, 0xce, 0x02, 0x7c, 0xca, 0x72, 0x26, 0x2e, 0xd3,
+	0x98, 0xf1, 0x7f, 0xd6, 0xf6, 0x61, 0xe2, 0xb2, 0x29, 0x73, 0x5f, 0xa1, 0xff, 0xe9, 0xae, 0xd8,
+	0x7c, 0xc3, 0xaf, 0x60, 0x90, 0xae, 0xd7, 0x39, 0x13, 0x9a, 0xce, 0xa3, 0x75, 0x85, 0x31, 0xec,
+	0xe7, 0x49, 0x65, 0xf6, 0xe4, 0x51, 0xfd, 0x56, 0x58, 0xbc, 0x12, 0x2b, 0x1d, 0xc1, 0x31, 0xd5,
+	0x6f, 0x7c, 0x0c, 0xfd, 0x28, 0x8b, 0xce, 0xe6, 0x3a, 0x85, 0x87, 0xd4, 0x14, 0xe1, 0x3d, 0x8c,
+	0x6b, 0xab, 0x46, 0xe5, 0xaf, 0x4f, 0xf6, 0x06, 0xfa, 0x91, 0x62, 0xd0, 0x73, 0x8c, 0xe6, 0x47,
+	0xbb, 0x2f, 0x34, 0x2f, 0x35, 0xcd, 0xf9, 0x4f, 0x0f, 0x86, 0xcb, 0xab, 0xc5, 0x79, 0x12, 0x31,
+	0x7c, 0x0e, 0x60, 0x53, 0x84, 0x83, 0xdd, 0x07, 0xad, 0x0c, 0x07, 0xa4, 0xb3, 0xa7, 0xb6, 0xb3,
+	0xa7, 0x58, 0x6c, 0x1e, 0x1c, 0x96, 0x56, 0x1a, 0x03, 0xd2, 0xd9, 0xdb, 0xb1, 0xd8, 0xa3, 0x3b,
+	0x2c, 0xad, 0x5c, 0x05, 0xa4, 0xb3, 0xb7, 0x63, 0xb1, 0xe7, 0x73, 0x58, 0x5a, 0x09, 0x09, 0x48,
+	0x67, 0xcf, 0xb0, 0x5c, 0xc0, 0xd4, 0xda, 0x5c, 0x8a, 0x8c, 0xad, 0xee, 0xf1, 0x4b, 0x3b, 0xbb,
+	0x73, 0xad, 0xa7, 0x16, 0x33, 0x43, 0x8a, 0xc8, 0x3a, 0xfd, 0x53, 0xa2, 0xd6, 0x6e, 0x66, 0x08,
+	0x7f, 0x84, 0xa9, 0x35, 0x5b, 0x13, 0x3d, 0xb5, 0xa3, 0xdf, 0x8e, 0x1f, 0xee, 0xbd, 0x43, 0x37,
+	0x03, 0xfd, 0x63, 0x3d, 0xfb, 0x35, 0x00, 0x1b, 0x5a, 0xa6, 0x39, 0x69, 0x05, 0x00, 0x00,
 }
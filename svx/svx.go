@@ -0,0 +1,187 @@
+// Package svx provides functions for reading SVX (Simple Voxels) files.
+// See: https://abfab3d.com/svx-format/ for more information.
+package svx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+// Read reads an SVX file and returns the density channel as a BinVOX.
+//
+// The model is returned with +Z up. SVX files with slicesOrientation="Y"
+// (such as those written by stl2svx, since Shapeways treats +Y as up)
+// are rotated so that their Y axis becomes Z, undoing the rotation made
+// by stl2svx. The origin of the grid (in meters) becomes the minimum
+// corner of the model.
+func Read(filename string) (*binvox.BinVOX, error) {
+	log.Printf("Loading file %q...", filename)
+	var model *binvox.BinVOX
+
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, fmt.Errorf("zip.OpenReader: %v", err)
+	}
+	defer r.Close()
+
+	var sliceNameFmt string
+	var yUp bool
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("unable to open %q: %v", f.Name, err)
+		}
+
+		if f.Name == "manifest.xml" {
+			model, sliceNameFmt, yUp, err = parseManifest(rc)
+			if err != nil {
+				return nil, fmt.Errorf("parseManifest: %v", err)
+			}
+			rc.Close()
+			continue
+		}
+
+		var sliceNum int
+		if n, err := fmt.Sscanf(f.Name, sliceNameFmt, &sliceNum); err != nil || n != 1 {
+			return nil, fmt.Errorf("unable to parse %q using %q", f.Name, sliceNameFmt)
+		}
+
+		img, err := png.Decode(rc)
+		if err != nil {
+			return nil, fmt.Errorf("png.Decode: %v", err)
+		}
+		rc.Close()
+
+		scanImage(img, model, sliceNum, yUp)
+	}
+
+	return model, nil
+}
+
+// scanImage adds the voxels of slice z to the model. If yUp is set, the
+// slice lies in the XZ plane of the SVX grid and its image rows run along
+// -Y in the model (as mirrored by stl2svx).
+func scanImage(img image.Image, model *binvox.BinVOX, z int, yUp bool) {
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			c := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+			if c.Y < 128 {
+				continue
+			}
+			if yUp {
+				model.Add(x, model.NY-1-y, z)
+			} else {
+				model.Add(x, y, z)
+			}
+		}
+	}
+}
+
+// parseManifest returns an empty model sized by the manifest, the format
+// of the density slice filenames, and whether the slices are stacked
+// along the Y axis of the grid.
+func parseManifest(f io.Reader) (*binvox.BinVOX, string, bool, error) {
+	buf, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("ioutil.Reader: %v", err)
+	}
+
+	type Entry struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:"value,attr"`
+	}
+
+	type Metadata struct {
+		Entry []Entry `xml:"entry"`
+	}
+
+	type Material struct {
+		ID  string `xml:"id,attr"`
+		URN string `xml:"urn,attr"`
+	}
+
+	type Materials struct {
+		Material []Material `xml:"material"`
+	}
+
+	type Channel struct {
+		Type   string `xml:"type,attr"`
+		Bits   int    `xml:"bits,attr"`
+		Slices string `xml:"slices,attr"`
+	}
+
+	type Channels struct {
+		Channel []Channel `xml:"channel"`
+	}
+
+	type Grid struct {
+		GridSizeX int       `xml:"gridSizeX,attr"`
+		GridSizeY int       `xml:"gridSizeY,attr"`
+		GridSizeZ int       `xml:"gridSizeZ,attr"`
+		VoxelSize float64   `xml:"voxelSize,attr"`
+		OriginX   float64   `xml:"originX,attr"`
+		OriginY   float64   `xml:"originY,attr"`
+		OriginZ   float64   `xml:"originZ,attr"`
+		Slices    string    `xml:"slicesOrientation,attr"`
+		Channels  Channels  `xml:"channels"`
+		Materials Materials `xml:"materials"`
+		Metadata  Metadata  `xml:"metadata"`
+	}
+
+	type Result struct {
+		Grid
+	}
+
+	v := Result{}
+	if err := xml.Unmarshal(buf, &v); err != nil {
+		return nil, "", false, fmt.Errorf("xml.Unmarshal: %v", err)
+	}
+	if len(v.Grid.Channels.Channel) == 0 {
+		return nil, "", false, fmt.Errorf("expected at least one grid channel")
+	}
+
+	// The SVX grid is measured in meters.
+	model := &binvox.BinVOX{
+		NX: v.Grid.GridSizeX,
+		NY: v.Grid.GridSizeY,
+		NZ: v.Grid.GridSizeZ,
+		TX: v.Grid.OriginX * 1000.0,
+		TY: v.Grid.OriginY * 1000.0,
+		TZ: v.Grid.OriginZ * 1000.0,
+
+		WhiteVoxels: binvox.WhiteVoxelMap{},
+	}
+	var yUp bool
+	switch strings.ToUpper(v.Grid.Slices) {
+	case "", "Z":
+	case "Y":
+		yUp = true
+		model.NY, model.NZ = model.NZ, model.NY
+		model.TY, model.TZ = model.TZ, model.TY
+	default:
+		return nil, "", false, fmt.Errorf("unsupported slicesOrientation %q", v.Grid.Slices)
+	}
+	model.Scale = float64(model.Dim()) * v.Grid.VoxelSize * 1000.0
+
+	var densitySlices string
+	for _, channel := range v.Grid.Channels.Channel {
+		if strings.EqualFold(channel.Type, "DENSITY") {
+			densitySlices = channel.Slices
+		}
+	}
+	if densitySlices == "" {
+		return nil, "", false, fmt.Errorf("could not find DENSITY slices")
+	}
+
+	return model, densitySlices, yUp, nil
+}
@@ -0,0 +1,98 @@
+package svx
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	gl "github.com/fogleman/fauxgl"
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+func TestParseManifest(t *testing.T) {
+	data := `<?xml version="1.0"?>
+
+<grid version="1.0" gridSizeX="660" gridSizeY="150" gridSizeZ="150"
+   voxelSize="0.000200" subvoxelBits="8" slicesOrientation="Z" >
+
+    <channels>
+        <channel type="DENSITY" bits="8" slices="density/slice%04d.png" />
+    </channels>
+
+    <materials>
+        <material id="1" urn="urn:shapeways:materials/1" />
+    </materials>
+
+    <metadata>
+        <entry key="author" value="Glenn M. Lewis" />
+        <entry key="creationDate" value="2020-10-12" />
+    </metadata>
+</grid>`
+
+	xmlFile := bytes.NewBufferString(data)
+	gotModel, gotSliceNameFmt, gotYUp, err := parseManifest(xmlFile)
+	if err != nil {
+		t.Fatalf("parseManifest: %v", err)
+	}
+
+	if want := "density/slice%04d.png"; gotSliceNameFmt != want {
+		t.Errorf("sliceNameFmt = %v, want %v", gotSliceNameFmt, want)
+	}
+	if gotYUp {
+		t.Errorf("yUp = true, want false")
+	}
+
+	want := &binvox.BinVOX{
+		NX:    660,
+		NY:    150,
+		NZ:    150,
+		Scale: 132,
+
+		WhiteVoxels: binvox.WhiteVoxelMap{},
+	}
+	if math.Abs(gotModel.Scale-want.Scale) > 1e-9 {
+		t.Errorf("Scale = %v, want %v", gotModel.Scale, want.Scale)
+	}
+	gotModel.Scale = want.Scale
+	if !reflect.DeepEqual(gotModel, want) {
+		t.Errorf("parseManifest =\n%#v\nwant:\n%#v", *gotModel, *want)
+	}
+	if got, want := gotModel.VoxelsPerMM(), 5.0; math.Abs(got-want) > 1e-9 {
+		t.Errorf("VoxelsPerMM = %v, want %v", got, want)
+	}
+}
+
+func TestParseManifestYUp(t *testing.T) {
+	data := `<?xml version="1.0"?>
+<grid version="1.0" gridSizeX="12" gridSizeY="32" gridSizeZ="22" voxelSize="0.0005" subvoxelBits="8" originX="0.001" originY="0.002" originZ="-0.003" slicesOrientation="Y" >
+  <channels>
+    <channel type="DENSITY" bits="8" slices="density/slice%04d.png" />
+  </channels>
+</grid>`
+
+	got, _, yUp, err := parseManifest(bytes.NewBufferString(data))
+	if err != nil {
+		t.Fatalf("parseManifest: %v", err)
+	}
+	if !yUp {
+		t.Errorf("yUp = false, want true")
+	}
+	if got.NX != 12 || got.NY != 22 || got.NZ != 32 {
+		t.Errorf("dims = (%v,%v,%v), want (12,22,32)", got.NX, got.NY, got.NZ)
+	}
+	want := gl.V(1, -3, 2)
+	if gl.V(got.TX, got.TY, got.TZ).Sub(want).Length() > 1e-9 {
+		t.Errorf("origin = (%v,%v,%v), want %v", got.TX, got.TY, got.TZ, want)
+	}
+	if math.Abs(got.Scale-16) > 1e-9 {
+		t.Errorf("Scale = %v, want 16", got.Scale)
+	}
+}
+
+func TestParseManifestBadOrientation(t *testing.T) {
+	data := `<grid gridSizeX="1" gridSizeY="1" gridSizeZ="1" voxelSize="0.001" slicesOrientation="W"><channels><channel type="DENSITY" slices="s%04d.png" /></channels></grid>`
+	if _, _, _, err := parseManifest(bytes.NewBufferString(data)); err == nil {
+		t.Error("parseManifest = nil error, want error")
+	}
+}
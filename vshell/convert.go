@@ -0,0 +1,46 @@
+package vshell
+
+import (
+	"log"
+	"sort"
+
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+// ToBinVOX returns the solid BinVOX model represented by the shell.
+//
+// Since only the shell voxels are retained, the interior is filled
+// by scanning each row in +X: whenever a shell voxel has a neighbor
+// in +X, all voxels up to the next shell voxel in the row are solid.
+func (vs *VShell) ToBinVOX() *binvox.BinVOX {
+	bv := binvox.New(vs.NX, vs.NY, vs.NZ, vs.TX, vs.TY, vs.TZ, vs.Scale, false)
+
+	type row struct{ Y, Z int }
+	rows := map[row][]int{}
+	for _, v := range vs.Voxels {
+		r := row{v.Y, v.Z}
+		rows[r] = append(rows[r], v.X)
+	}
+	neighbors := make(map[key]NeighborBitMap, len(vs.Voxels))
+	for _, v := range vs.Voxels {
+		neighbors[key{v.X, v.Y, v.Z}] = v.N
+	}
+
+	for r, xs := range rows {
+		sort.Ints(xs)
+		for i, x := range xs {
+			bv.Add(x, r.Y, r.Z)
+			if neighbors[key{x, r.Y, r.Z}]&X1Y0Z0 == 0 {
+				continue
+			}
+			if i+1 >= len(xs) {
+				log.Printf("ToBinVOX: shell voxel (%v,%v,%v) has a +X neighbor but no closing shell voxel", x, r.Y, r.Z)
+				continue
+			}
+			for fx := x + 1; fx < xs[i+1]; fx++ {
+				bv.Add(fx, r.Y, r.Z)
+			}
+		}
+	}
+	return bv
+}
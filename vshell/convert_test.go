@@ -0,0 +1,35 @@
+package vshell
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gmlewis/stldice/v4/binvox"
+)
+
+func TestToBinVOX(t *testing.T) {
+	want := binvox.New(10, 10, 10, 1, 2, 3, 10, false)
+	for z := 0; z < 10; z++ {
+		for y := 0; y < 10; y++ {
+			for x := 0; x < 10; x++ {
+				if x >= 4 && x < 6 && y >= 4 && y < 6 && z >= 4 && z < 6 {
+					continue // hollow center
+				}
+				want.Add(x, y, z)
+			}
+		}
+	}
+
+	vs, err := New(want)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if len(vs.Voxels) == len(want.WhiteVoxels) {
+		t.Fatalf("New retained all %v voxels; want interior elided", len(vs.Voxels))
+	}
+
+	got := vs.ToBinVOX()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToBinVOX = %v, want %v", got, want)
+	}
+}
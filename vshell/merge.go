@@ -127,14 +127,10 @@ func (vs *VShell) Add(bv *binvox.BinVOX) (err error) {
 // update adjusts the locations of the current VShVoxels to accomodate
 // the addition of the BinVOX model.
 func (vs *VShell) update(bv *binvox.BinVOX) (dx, dy, dz int, err error) {
-	vpmm := vs.VoxelsPerMM()
-	if int(vpmm) != int(bv.VoxelsPerMM()) { // Course test for large discrepancies
-		return 0, 0, 0, fmt.Errorf("vshell.VoxelsPerMM (%v) != binvox.VoxelsPerMM (%v)", vpmm, bv.VoxelsPerMM())
+	grid := &binvox.BinVOX{NX: vs.NX, NY: vs.NY, NZ: vs.NZ, TX: vs.TX, TY: vs.TY, TZ: vs.TZ, Scale: vs.Scale}
+	if dx, dy, dz, err = binvox.GridOffset(grid, bv); err != nil {
+		return 0, 0, 0, err
 	}
-
-	dx = int((bv.TX - vs.TX) * vpmm)
-	dy = int((bv.TY - vs.TY) * vpmm)
-	dz = int((bv.TZ - vs.TZ) * vpmm)
 	if bv.TX < vs.TX {
 		vs.TX = bv.TX
 	} else {